@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+const adminUsage = `usage: sprut admin [flags] <command> [args]
+
+commands:
+  peers                                  list connected peers
+  kick <key> [-reason text]              disconnect a peer
+  ban <key|cidr> -for <duration> [-reason t]
+                                         ban a key or address range and disconnect it
+  unban <key|cidr>                       remove a ban
+  bans                                   list active bans
+
+flags:
+`
+
+// runAdmin выполняет команду `sprut admin`.
+// Адрес и токен берутся из флагов, SPRUT_ADMIN_TOKEN или секции admin конфигурации.
+func runAdmin(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file (default: XDG config dir)")
+	addr := fs.String("addr", "", "admin API address, host:port or unix:/path (default: admin.addr from config)")
+	tokenFile := fs.String("token-file", "", "file with admin token (default: $SPRUT_ADMIN_TOKEN or admin.token_file from config)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), adminUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("command is required")
+	}
+
+	token := os.Getenv("SPRUT_ADMIN_TOKEN")
+	if *addr == "" || (token == "" && *tokenFile == "") {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if *addr == "" {
+			*addr = cfg.Admin.Addr
+		}
+		if *tokenFile == "" {
+			*tokenFile = cfg.Admin.TokenFile
+		}
+	}
+	if *addr == "" {
+		return errors.New("admin API address is not configured")
+	}
+	if *tokenFile != "" && os.Getenv("SPRUT_ADMIN_TOKEN") == "" {
+		data, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			return fmt.Errorf("read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return errors.New("admin token is not configured")
+	}
+
+	c := admin.NewClient(*addr, token)
+	ctx := context.Background()
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "peers":
+		peers, err := c.Peers(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tREMOTE\tCONNECTED\tQUEUE\tIN\tOUT")
+		for _, p := range peers {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n",
+				p.Key, p.RemoteAddr, time.Since(p.ConnectedAt).Round(time.Second), p.QueueDepth, p.MessagesIn, p.MessagesOut)
+		}
+		return w.Flush()
+
+	case "kick":
+		key, rest, err := adminKeyArg(cmd, cmdArgs)
+		if err != nil {
+			return err
+		}
+		kfs := flag.NewFlagSet("kick", flag.ContinueOnError)
+		reason := kfs.String("reason", "", "reason sent to the peer")
+		if err := kfs.Parse(rest); err != nil {
+			return err
+		}
+		if err := c.Kick(ctx, key, *reason); err != nil {
+			return err
+		}
+		fmt.Printf("kicked %s\n", key)
+		return nil
+
+	case "ban":
+		key, rest, err := adminKeyArg(cmd, cmdArgs)
+		if err != nil {
+			return err
+		}
+		bfs := flag.NewFlagSet("ban", flag.ContinueOnError)
+		dur := bfs.Duration("for", 0, "ban duration, e.g. 24h")
+		reason := bfs.String("reason", "", "ban reason")
+		if err := bfs.Parse(rest); err != nil {
+			return err
+		}
+		if *dur <= 0 {
+			return errors.New("ban: -for must be a positive duration")
+		}
+		ban, err := c.Ban(ctx, key, *dur, *reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("banned %s until %s\n", ban.Target(), ban.Until.Format(time.RFC3339))
+		return nil
+
+	case "unban":
+		key, _, err := adminKeyArg(cmd, cmdArgs)
+		if err != nil {
+			return err
+		}
+		if err := c.Unban(ctx, key); err != nil {
+			return err
+		}
+		fmt.Printf("unbanned %s\n", key)
+		return nil
+
+	case "bans":
+		bans, err := c.Bans(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TARGET\tUNTIL\tAUTO\tREASON")
+		for _, b := range bans {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", b.Target(), b.Until.Format(time.RFC3339), b.Auto, b.Reason)
+		}
+		return w.Flush()
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// adminKeyArg возвращает ключ клиента или диапазон адресов — первый аргумент команды.
+func adminKeyArg(cmd string, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, fmt.Errorf("%s: target is required", cmd)
+	}
+	return args[0], args[1:], nil
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+	return config.LoadFromAppDir()
+}
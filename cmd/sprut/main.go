@@ -10,16 +10,27 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/udisondev/sprut/internal/appdir"
 	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/listenfd"
 	"github.com/udisondev/sprut/pkg/router"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "sprut admin:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	configPath := flag.String("config", "", "path to config file (default: XDG config dir)")
 	initOnly := flag.Bool("init", false, "initialize app directory and exit")
 	flag.Parse()
@@ -77,15 +88,139 @@ func run(configPath string) error {
 		}()
 	}
 
+	// Сокеты от systemd (socket activation) или от предыдущего процесса при обновлении
+	sockets, err := listenfd.Inherit()
+	if err != nil {
+		return fmt.Errorf("inherit sockets: %w", err)
+	}
+	cfg.Sockets = sockets
+
 	// Создаём контекст с отменой по сигналам
-	ctx, cancel := signal.NotifyContext(context.Background(),
+	ctx, stop := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// После обновления процесс завершается так же, как по SIGTERM
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// SIGHUP перечитывает конфигурацию
+	reload := make(chan *config.Config)
+	cfg.Reload = reload
+	go watchSIGHUP(ctx, configPath, reload)
+
+	// SIGUSR2 запускает новый процесс с теми же сокетами
+	ready := make(chan struct{})
+	cfg.Ready = ready
+	go notifyReady(ctx, ready, sockets)
+	go watchSIGUSR2(ctx, cancel, sockets)
+
 	// Запускаем роутер
 	return router.Run(ctx, cfg)
 }
 
+// watchSIGHUP перечитывает конфигурацию по SIGHUP и передаёт её роутеру.
+// Невалидная конфигурация не применяется.
+func watchSIGHUP(ctx context.Context, configPath string, reload chan<- *config.Config) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		slog.Info("SIGHUP received, reloading configuration")
+
+		var cfg *config.Config
+		var err error
+		if configPath != "" {
+			cfg, err = config.Load(configPath)
+		} else {
+			cfg, err = config.LoadFromAppDir()
+		}
+		if err != nil {
+			slog.Error("reload config, keeping current", "error", err)
+			continue
+		}
+
+		select {
+		case reload <- cfg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// upgradeTimeout — время, за которое новый процесс должен начать принимать соединения.
+const upgradeTimeout = time.Minute
+
+// watchSIGUSR2 обновляет sprut без простоя: по SIGUSR2 запускает бинарник
+// по тому же пути (уже заменённый новой версией) с теми же аргументами и передаёт
+// ему слушающие сокеты. Когда новый процесс готов, текущий перестаёт принимать
+// соединения и завершает работу с клиентами, как по SIGTERM; если новый процесс
+// не запустился, текущий продолжает работу. UDP сокет QUIC не делится между
+// процессами: QUIC клиенты получают GoAway сразу после передачи сокетов
+// и переподключаются к новому процессу, не дожидаясь drain.
+//
+// Под systemd PID процесса меняется и unit остановится вместе с прежним процессом:
+// там для перезапуска без отказов используется socket activation, при которой
+// соединения ждут в очереди сокета systemd.
+func watchSIGUSR2(ctx context.Context, shutdown context.CancelFunc, sockets *listenfd.Set) {
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	// Без обработчика SIGUSR2 завершил бы процесс, не дав закончить drain
+	defer signal.Ignore(syscall.SIGUSR2)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-usr2:
+		}
+
+		slog.Info("SIGUSR2 received, starting new process")
+		if err := upgrade(ctx, sockets); err != nil {
+			slog.Error("upgrade failed, keeping current process", "error", err)
+			continue
+		}
+
+		slog.Info("new process ready, shutting down")
+		shutdown()
+		return
+	}
+}
+
+// upgrade запускает новый процесс sprut и ждёт его готовности.
+func upgrade(ctx context.Context, sockets *listenfd.Set) error {
+	path, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return sockets.Upgrade(ctx, cmd)
+}
+
+// notifyReady сообщает предыдущему процессу, что роутер принимает соединения.
+func notifyReady(ctx context.Context, ready <-chan struct{}, sockets *listenfd.Set) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-ready:
+	}
+	if err := sockets.Ready(); err != nil {
+		slog.Error("notify previous process", "error", err)
+	}
+}
+
 func setupLogging(cfg config.LogConfig) {
 	var output io.Writer = os.Stdout
 
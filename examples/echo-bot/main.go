@@ -2,10 +2,11 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"os/signal"
 	"sync/atomic"
 	"syscall"
@@ -49,40 +50,43 @@ func main() {
 		opts = append(opts, client.WithCACertFile(*caCert))
 	}
 
-	// Создаём канал для отправки
-	send := make(chan client.OutgoingMessage, 100)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Подключаемся к серверу
-	recv, err := client.Connect(*addr, send, opts...)
+	c, err := client.Dial(ctx, *addr, opts...)
 	if err != nil {
 		log.Fatalf("connect: %v", err)
 	}
+	defer c.Close()
 
 	fmt.Println("Echo bot is running. Press Ctrl+C to exit.")
 
-	// Обрабатываем входящие сообщения
-	go func() {
-		for msg := range recv {
-			fmt.Printf("[RECV] From %s: %s\n", msg.From[:16]+"...", string(msg.Payload))
-
-			// Echo back
-			reply := fmt.Sprintf("Echo: %s", string(msg.Payload))
-			msgID := fmt.Sprintf("echo-%d", msgCounter.Add(1))
-
-			send <- client.OutgoingMessage{
-				To:      msg.From,
-				MsgID:   msgID,
-				Payload: []byte(reply),
+	// Обрабатываем входящие сообщения до сигнала или закрытия соединения
+	for {
+		msg, err := c.Receive(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				fmt.Printf("Receive: %v\n", err)
 			}
-			fmt.Printf("[SENT] To %s: %s\n", msg.From[:16]+"...", reply)
+			break
 		}
-	}()
-
-	// Ждём сигнала
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+		fmt.Printf("[RECV] From %s: %s\n", msg.From[:16]+"...", string(msg.Payload))
+
+		// Echo back
+		reply := fmt.Sprintf("Echo: %s", string(msg.Payload))
+		msgID := fmt.Sprintf("echo-%d", msgCounter.Add(1))
+
+		if err := c.Send(ctx, client.OutgoingMessage{
+			To:      msg.From,
+			MsgID:   msgID,
+			Payload: []byte(reply),
+		}); err != nil {
+			fmt.Printf("Send: %v\n", err)
+			continue
+		}
+		fmt.Printf("[SENT] To %s: %s\n", msg.From[:16]+"...", reply)
+	}
 
 	fmt.Println("\nShutting down...")
-	close(send)
 }
@@ -43,6 +43,20 @@ func main() {
 		client.WithOnError(func(err error) {
 			fmt.Printf("Error: %v\n", err)
 		}),
+		client.WithOnAck(func(r client.SendResult) {
+			if err := r.Err(); err != nil {
+				fmt.Printf("[%s] Not sent: %v\n", r.MsgID, err)
+				return
+			}
+			fmt.Printf("[%s] Sent\n", r.MsgID)
+		}),
+		client.WithOnReceipt(func(r client.Receipt) {
+			fmt.Printf("[%s] Delivered\n", r.MsgID)
+		}),
+		client.WithReconnect(),
+		client.WithOnStateChange(func(s client.ConnState) {
+			fmt.Printf("Connection %s\n", s)
+		}),
 	}
 	if *insecure {
 		opts = append(opts, client.WithInsecureSkipVerify())
@@ -49,6 +49,11 @@ func LogFilePath() string {
 	return filepath.Join(LogsDir(), "sprut.log")
 }
 
+// BansPath возвращает путь к файлу блокировок (bans.store: file).
+func BansPath() string {
+	return filepath.Join(Dir(), "bans.json")
+}
+
 // Init инициализирует директорию приложения.
 // Создаёт все необходимые поддиректории, дефолтный конфиг и сертификаты.
 func Init() error {
@@ -0,0 +1,206 @@
+// Package admin описывает HTTP API администрирования роутера
+// и реализует его клиент для CLI `sprut admin`.
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// UnixPrefix — префикс адреса admin API на unix-сокете: "unix:/run/sprut/admin.sock".
+const UnixPrefix = "unix:"
+
+// Пути API.
+const (
+	PathPeers = "/v1/peers"
+	PathBans  = "/v1/bans"
+)
+
+// ErrNotFound — клиент не подключён или блокировка не найдена.
+var ErrNotFound = errors.New("not found")
+
+// Peer — подключённый клиент.
+type Peer struct {
+	Key         string    `json:"key"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	QueueDepth  int       `json:"queue_depth"`  // кадров в очереди записи
+	MessagesIn  uint64    `json:"messages_in"`  // кадров сообщений от клиента
+	MessagesOut uint64    `json:"messages_out"` // сообщений, доставленных клиенту
+}
+
+// KickRequest — тело запроса отключения клиента.
+type KickRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BanRequest — тело запроса блокировки ключа или диапазона адресов.
+// Указывается ровно одно из полей Key и CIDR.
+type BanRequest struct {
+	Key      string `json:"key,omitempty"`
+	CIDR     string `json:"cidr,omitempty"` // диапазон или одиночный адрес
+	Duration string `json:"duration"`       // time.ParseDuration, например "24h"
+	Reason   string `json:"reason"`
+}
+
+// Ban — заблокированный ключ или диапазон адресов.
+type Ban struct {
+	Key    string    `json:"key,omitempty"`
+	CIDR   string    `json:"cidr,omitempty"`
+	Reason string    `json:"reason"`
+	Until  time.Time `json:"until"`
+	Auto   bool      `json:"auto,omitempty"` // выставлена автоматически за злоупотребления
+}
+
+// Target возвращает заблокированный ключ или диапазон.
+func (b Ban) Target() string {
+	if b.CIDR != "" {
+		return b.CIDR
+	}
+	return b.Key
+}
+
+// ParseCIDR разбирает диапазон адресов ("10.0.0.0/8") или одиночный адрес
+// ("192.0.2.1" как /32, IPv6 как /128). Биты адреса за маской обнуляются.
+func ParseCIDR(s string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// ErrorResponse — тело ответа с ошибкой.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Client клиент admin API.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient создаёт клиент admin API по адресу host:port или unix:/path.
+func NewClient(addr, token string) *Client {
+	c := &Client{
+		baseURL: "http://" + addr,
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if path, ok := strings.CutPrefix(addr, UnixPrefix); ok {
+		c.baseURL = "http://sprut-admin"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	}
+
+	return c
+}
+
+// Peers возвращает подключённых к серверу клиентов.
+func (c *Client) Peers(ctx context.Context) ([]Peer, error) {
+	var peers []Peer
+	if err := c.do(ctx, http.MethodGet, PathPeers, nil, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// Kick отключает клиента с ключом key, отправив ему кадр ошибки с reason.
+func (c *Client) Kick(ctx context.Context, key, reason string) error {
+	return c.do(ctx, http.MethodPost, PathPeers+"/"+url.PathEscape(key)+"/kick", KickRequest{Reason: reason}, nil)
+}
+
+// Ban блокирует на duration ключ или диапазон адресов (см. ParseCIDR)
+// и отключает попадающих под блокировку клиентов.
+func (c *Client) Ban(ctx context.Context, target string, duration time.Duration, reason string) (Ban, error) {
+	var ban Ban
+	req := BanRequest{Duration: duration.String(), Reason: reason}
+	if _, ok := ParseCIDR(target); ok {
+		req.CIDR = target
+	} else {
+		req.Key = target
+	}
+	if err := c.do(ctx, http.MethodPost, PathBans, req, &ban); err != nil {
+		return Ban{}, err
+	}
+	return ban, nil
+}
+
+// Unban снимает блокировку ключа или диапазона адресов.
+func (c *Client) Unban(ctx context.Context, target string) error {
+	return c.do(ctx, http.MethodDelete, PathBans+"/"+url.PathEscape(target), nil, nil)
+}
+
+// Bans возвращает действующие блокировки.
+func (c *Client) Bans(ctx context.Context) ([]Ban, error) {
+	var bans []Ban
+	if err := c.do(ctx, http.MethodGet, PathBans, nil, &bans); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// do выполняет запрос и декодирует JSON ответ в out (если out не nil).
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&e) // тело ошибки необязательно
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrNotFound, e.Error)
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, e.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
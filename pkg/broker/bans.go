@@ -0,0 +1,98 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Bans хранит блокировки в NATS KV, общем для всех серверов.
+// Формат значений определяет вызывающий код: Bans передаёт каждое изменение,
+// включая сделанные другими серверами, в функцию apply.
+type Bans struct {
+	kv      jetstream.KeyValue
+	watcher jetstream.KeyWatcher
+}
+
+// NewBans загружает блокировки и подписывается на их изменения.
+// apply вызывается для каждой записи; value == nil означает удаление.
+// Начальные значения передаются в apply до возврата из NewBans.
+// Требует включённого JetStream и заданного bucket блокировок.
+func NewBans(broker *Broker, apply func(id string, value []byte)) (*Bans, error) {
+	if broker.bans == nil {
+		return nil, fmt.Errorf("bans bucket is not configured")
+	}
+
+	watcher, err := broker.bans.WatchAll(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("watch bans: %w", err)
+	}
+
+	// Дожидаемся начальных значений: nil в канале обновлений отмечает их конец
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+	var loaded int
+	for done := false; !done; {
+		select {
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return nil, fmt.Errorf("bans watcher closed")
+			}
+			if entry == nil {
+				done = true
+				continue
+			}
+			applyBanEntry(entry, apply)
+			loaded++
+		case <-ctx.Done():
+			_ = watcher.Stop()
+			return nil, fmt.Errorf("load bans: %w", ctx.Err())
+		}
+	}
+
+	go func() {
+		for entry := range watcher.Updates() {
+			if entry != nil {
+				applyBanEntry(entry, apply)
+			}
+		}
+	}()
+
+	slog.Info("bans: loaded", "entries", loaded)
+
+	return &Bans{kv: broker.bans, watcher: watcher}, nil
+}
+
+// Put сохраняет блокировку id.
+func (b *Bans) Put(ctx context.Context, id string, value []byte) error {
+	if _, err := b.kv.Put(ctx, id, value); err != nil {
+		return fmt.Errorf("save ban %s: %w", id, err)
+	}
+	return nil
+}
+
+// Delete удаляет блокировку id.
+func (b *Bans) Delete(ctx context.Context, id string) error {
+	if err := b.kv.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete ban %s: %w", id, err)
+	}
+	return nil
+}
+
+// Stop останавливает watcher.
+func (b *Bans) Stop() error {
+	if err := b.watcher.Stop(); err != nil {
+		return fmt.Errorf("stop bans watcher: %w", err)
+	}
+	return nil
+}
+
+func applyBanEntry(entry jetstream.KeyValueEntry, apply func(id string, value []byte)) {
+	if entry.Operation() != jetstream.KeyValuePut {
+		apply(entry.Key(), nil)
+		return
+	}
+	apply(entry.Key(), entry.Value())
+}
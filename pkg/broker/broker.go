@@ -2,17 +2,30 @@
 package broker
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 )
 
+// opTimeout — таймаут служебных операций JetStream (публикация, создание consumer).
+const opTimeout = 5 * time.Second
+
 // Broker управляет соединением с NATS.
 type Broker struct {
 	conn *nats.Conn
+
+	// js и stream заданы только в режиме offline-доставки.
+	js     jetstream.JetStream
+	stream jetstream.Stream
+	groups jetstream.KeyValue
+	policy jetstream.KeyValue
+	bans   jetstream.KeyValue // nil, если BansBucket не задан
+	jsCfg  JetStreamConfig
 }
 
 // Config конфигурация NATS.
@@ -20,6 +33,20 @@ type Config struct {
 	URLs          []string
 	ReconnectWait time.Duration
 	MaxReconnects int
+	JetStream     JetStreamConfig
+}
+
+// JetStreamConfig конфигурация хранения сообщений для offline получателей.
+type JetStreamConfig struct {
+	Enabled              bool
+	Stream               string
+	MaxMsgsPerRecipient  int64
+	MaxBytesPerRecipient int64
+	MaxAge               time.Duration
+	Replicas             int
+	GroupsBucket         string
+	PolicyBucket         string
+	BansBucket           string // пустой = блокировки не хранятся в KV
 }
 
 // New создаёт новый брокер.
@@ -56,7 +83,108 @@ func New(cfg Config) (*Broker, error) {
 
 	slog.Debug("broker: connection established", "server_id", conn.ConnectedServerId(), "url", conn.ConnectedUrl())
 
-	return &Broker{conn: conn}, nil
+	b := &Broker{conn: conn, jsCfg: cfg.JetStream}
+
+	if cfg.JetStream.Enabled {
+		if err := b.setupJetStream(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("setup JetStream: %w", err)
+		}
+	}
+
+	return b, nil
+}
+
+// setupJetStream создаёт (или обновляет) stream для хранения сообщений
+// и KV bucket для состава групп.
+//
+// Используется LimitsPolicy: доставленные сообщения отмечаются ack в durable
+// consumer получателя, а из stream удаляются по лимитам (количество, возраст).
+func (b *Broker) setupJetStream() error {
+	js, err := jetstream.New(b.conn)
+	if err != nil {
+		return fmt.Errorf("create JetStream context: %w", err)
+	}
+
+	maxMsgsPerSubject := b.jsCfg.MaxMsgsPerRecipient
+	if maxMsgsPerSubject == 0 {
+		maxMsgsPerSubject = -1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:              b.jsCfg.Stream,
+		Subjects:          []string{subjectPrefix + ">"},
+		Retention:         jetstream.LimitsPolicy,
+		Discard:           jetstream.DiscardOld,
+		MaxMsgsPerSubject: maxMsgsPerSubject,
+		MaxAge:            b.jsCfg.MaxAge,
+		Storage:           jetstream.FileStorage,
+		Replicas:          b.jsCfg.Replicas,
+	})
+	if err != nil {
+		slog.Error("broker: create stream failed", "stream", b.jsCfg.Stream, "error", err)
+		return fmt.Errorf("create stream %s: %w", b.jsCfg.Stream, err)
+	}
+
+	// Состав групп хранится без ограничения по времени
+	groups, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:   b.jsCfg.GroupsBucket,
+		Storage:  jetstream.FileStorage,
+		Replicas: b.jsCfg.Replicas,
+	})
+	if err != nil {
+		slog.Error("broker: create groups bucket failed", "bucket", b.jsCfg.GroupsBucket, "error", err)
+		return fmt.Errorf("create KV bucket %s: %w", b.jsCfg.GroupsBucket, err)
+	}
+
+	// Блок-листы и контакты получателей
+	policy, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:   b.jsCfg.PolicyBucket,
+		Storage:  jetstream.FileStorage,
+		Replicas: b.jsCfg.Replicas,
+	})
+	if err != nil {
+		slog.Error("broker: create policy bucket failed", "bucket", b.jsCfg.PolicyBucket, "error", err)
+		return fmt.Errorf("create KV bucket %s: %w", b.jsCfg.PolicyBucket, err)
+	}
+
+	// Блокировки ключей и адресов, общие для всех серверов
+	if b.jsCfg.BansBucket != "" {
+		b.bans, err = js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+			Bucket:   b.jsCfg.BansBucket,
+			Storage:  jetstream.FileStorage,
+			Replicas: b.jsCfg.Replicas,
+		})
+		if err != nil {
+			slog.Error("broker: create bans bucket failed", "bucket", b.jsCfg.BansBucket, "error", err)
+			return fmt.Errorf("create KV bucket %s: %w", b.jsCfg.BansBucket, err)
+		}
+	}
+
+	b.js = js
+	b.stream = stream
+	b.groups = groups
+	b.policy = policy
+
+	slog.Info("broker: JetStream enabled",
+		"stream", b.jsCfg.Stream,
+		"max_msgs_per_recipient", b.jsCfg.MaxMsgsPerRecipient,
+		"max_bytes_per_recipient", b.jsCfg.MaxBytesPerRecipient,
+		"max_age", b.jsCfg.MaxAge,
+		"groups_bucket", b.jsCfg.GroupsBucket,
+		"policy_bucket", b.jsCfg.PolicyBucket,
+		"bans_bucket", b.jsCfg.BansBucket,
+	)
+
+	return nil
+}
+
+// JetStreamEnabled сообщает, включена ли offline-доставка через JetStream.
+func (b *Broker) JetStreamEnabled() bool {
+	return b.js != nil
 }
 
 // Conn возвращает соединение NATS.
@@ -64,6 +192,12 @@ func (b *Broker) Conn() *nats.Conn {
 	return b.conn
 }
 
+// Connected сообщает, установлено ли соединение с NATS.
+// Во время переподключения возвращает false.
+func (b *Broker) Connected() bool {
+	return b.conn.IsConnected()
+}
+
 // Close закрывает соединение.
 func (b *Broker) Close() error {
 	slog.Debug("broker: closing connection")
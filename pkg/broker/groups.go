@@ -0,0 +1,84 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+var (
+	// ErrGroupNotFound — группа не найдена.
+	ErrGroupNotFound = errors.New("group not found")
+
+	// ErrGroupConflict — группа изменена (или создана) конкурентно.
+	ErrGroupConflict = errors.New("group changed concurrently")
+)
+
+// Groups хранит состав групп в NATS KV.
+// Ключ — id группы, значение — сериализованный message.Group.
+type Groups struct {
+	kv jetstream.KeyValue
+}
+
+// NewGroups создаёт хранилище групп. Требует включённого JetStream.
+func NewGroups(broker *Broker) (*Groups, error) {
+	if broker.groups == nil {
+		return nil, fmt.Errorf("JetStream is not enabled")
+	}
+	return &Groups{kv: broker.groups}, nil
+}
+
+// Get возвращает группу и ревизию записи для последующего Put.
+func (g *Groups) Get(ctx context.Context, id string) (*message.Group, uint64, error) {
+	entry, err := g.kv.Get(ctx, id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, 0, ErrGroupNotFound
+	}
+	if err != nil {
+		slog.Error("groups: get failed", "group", id, "error", err)
+		return nil, 0, fmt.Errorf("get group %s: %w", id, err)
+	}
+
+	group := &message.Group{}
+	if err := proto.Unmarshal(entry.Value(), group); err != nil {
+		return nil, 0, fmt.Errorf("unmarshal group %s: %w", id, err)
+	}
+	return group, entry.Revision(), nil
+}
+
+// Put сохраняет группу, если запись не менялась с ревизии revision.
+// revision = 0 создаёт новую группу.
+func (g *Groups) Put(ctx context.Context, group *message.Group, revision uint64) error {
+	data, err := proto.Marshal(group)
+	if err != nil {
+		return fmt.Errorf("marshal group %s: %w", group.Id, err)
+	}
+
+	if revision == 0 {
+		_, err = g.kv.Create(ctx, group.Id, data)
+	} else {
+		_, err = g.kv.Update(ctx, group.Id, data, revision)
+	}
+	if errors.Is(err, jetstream.ErrKeyExists) {
+		return ErrGroupConflict
+	}
+	if err != nil {
+		// Несовпадение ревизии сервер возвращает как API ошибку
+		var apiErr *jetstream.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode == jetstream.JSErrCodeStreamWrongLastSequence {
+			return ErrGroupConflict
+		}
+		slog.Error("groups: put failed", "group", group.Id, "error", err)
+		return fmt.Errorf("put group %s: %w", group.Id, err)
+	}
+
+	slog.Debug("groups: saved", "group", group.Id, "version", group.Version, "members", len(group.Members))
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// trimFetchBatch — размер пачки при подсчёте объёма backlog.
+const trimFetchBatch = 256
+
+// Mailbox доставляет клиенту сообщения из JetStream: сначала накопленный
+// за время отсутствия backlog, затем новые сообщения.
+// Сообщение удаляется из очереди получателя только после Ack.
+type Mailbox struct {
+	consumer jetstream.Consumer
+	cc       jetstream.ConsumeContext
+	subject  string
+}
+
+// NewMailbox создаёт durable consumer для указанного публичного ключа
+// и начинает доставку сообщений в handler.
+// maxAckPending ограничивает количество сообщений, выданных без подтверждения.
+func NewMailbox(broker *Broker, pubKeyHex string, maxAckPending int, handler jetstream.MessageHandler) (*Mailbox, error) {
+	if broker.js == nil {
+		return nil, fmt.Errorf("JetStream is not enabled")
+	}
+
+	subject := subjectForClient(pubKeyHex)
+	slog.Debug("mailbox: creating", "subject", subject)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	// InactiveThreshold = MaxAge: consumer удаляется не раньше, чем истекут
+	// все доставленные через него сообщения, поэтому пересозданный consumer
+	// с DeliverAll не выдаст их повторно.
+	consumer, err := broker.stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:           consumerName(pubKeyHex),
+		FilterSubject:     subject,
+		DeliverPolicy:     jetstream.DeliverAllPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		MaxAckPending:     maxAckPending,
+		InactiveThreshold: broker.jsCfg.MaxAge,
+	})
+	if err != nil {
+		slog.Error("mailbox: create consumer failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf("create consumer for %s: %w", subject, err)
+	}
+
+	m := &Mailbox{consumer: consumer, subject: subject}
+
+	if limit := broker.jsCfg.MaxBytesPerRecipient; limit > 0 {
+		if err := m.trim(ctx, broker.stream, limit); err != nil {
+			// Не критично: backlog будет доставлен целиком
+			slog.Warn("mailbox: trim backlog failed", "subject", subject, "error", err)
+		}
+	}
+
+	cc, err := consumer.Consume(handler)
+	if err != nil {
+		slog.Error("mailbox: consume failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf("consume %s: %w", subject, err)
+	}
+	m.cc = cc
+
+	slog.Info("mailbox: consuming", "subject", subject)
+
+	return m, nil
+}
+
+// Stop прекращает доставку. Неподтверждённые сообщения будут выданы повторно
+// при следующем подключении клиента.
+func (m *Mailbox) Stop() {
+	slog.Debug("mailbox: stopping", "subject", m.subject)
+	m.cc.Stop()
+}
+
+// trim удаляет самые старые недоставленные сообщения, если суммарный объём
+// backlog получателя превышает maxBytes.
+// JetStream не поддерживает лимит объёма на subject, поэтому лимит
+// применяется при подключении получателя, перед выдачей backlog.
+func (m *Mailbox) trim(ctx context.Context, stream jetstream.Stream, maxBytes int64) error {
+	info, err := m.consumer.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("consumer info: %w", err)
+	}
+	if info.NumPending == 0 {
+		return nil
+	}
+
+	// Читаем только заголовки: размер payload приходит в Nats-Msg-Size
+	oc, err := stream.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{m.subject},
+		DeliverPolicy:  jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:    info.AckFloor.Stream + 1,
+		HeadersOnly:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("create ordered consumer: %w", err)
+	}
+
+	type entry struct {
+		seq  uint64
+		size int64
+	}
+	entries := make([]entry, 0, info.NumPending)
+
+	for done := false; !done; {
+		batch, err := oc.Fetch(trimFetchBatch, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return fmt.Errorf("fetch backlog: %w", err)
+		}
+		received := 0
+		for msg := range batch.Messages() {
+			received++
+			meta, err := msg.Metadata()
+			if err != nil {
+				return fmt.Errorf("message metadata: %w", err)
+			}
+			size, err := strconv.ParseInt(msg.Headers().Get(nats.MsgSize), 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse message size: %w", err)
+			}
+			entries = append(entries, entry{seq: meta.Sequence.Stream, size: size})
+			if meta.NumPending == 0 {
+				done = true
+			}
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return fmt.Errorf("fetch backlog: %w", err)
+		}
+		if received == 0 {
+			break
+		}
+	}
+
+	// Оставляем самые новые сообщения, укладывающиеся в лимит
+	var total int64
+	var keepFrom uint64
+	for i := len(entries) - 1; i >= 0; i-- {
+		total += entries[i].size
+		if total > maxBytes {
+			keepFrom = entries[i].seq + 1
+			break
+		}
+	}
+	if keepFrom == 0 {
+		return nil
+	}
+
+	if err := stream.Purge(ctx, jetstream.WithPurgeSubject(m.subject), jetstream.WithPurgeSequence(keepFrom)); err != nil {
+		return fmt.Errorf("purge backlog: %w", err)
+	}
+
+	slog.Info("mailbox: backlog trimmed", "subject", m.subject, "max_bytes", maxBytes, "keep_from_seq", keepFrom)
+
+	return nil
+}
+
+// consumerName возвращает имя durable consumer для клиента.
+func consumerName(pubKeyHex string) string {
+	return "msg_" + pubKeyHex
+}
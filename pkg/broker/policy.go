@@ -0,0 +1,171 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+// PolicyList — список ключей владельца для политики авторизации.
+type PolicyList string
+
+// Списки владельца.
+const (
+	BlockList   PolicyList = "blocked"  // от кого владелец не принимает сообщения
+	ContactList PolicyList = "contacts" // контакты владельца
+)
+
+// PolicyLists хранит списки получателей в NATS KV и держит их копию в памяти:
+// списки проверяются на каждое сообщение, а изменения с других серверов
+// приходят через watcher.
+// Ключ KV — "<owner>.<list>", значение — сериализованный message.KeySet.
+type PolicyLists struct {
+	kv      jetstream.KeyValue
+	watcher jetstream.KeyWatcher
+
+	mu    sync.RWMutex
+	lists map[string]map[string]struct{}
+}
+
+// NewPolicyLists загружает списки и подписывается на их изменения.
+// Требует включённого JetStream.
+func NewPolicyLists(broker *Broker) (*PolicyLists, error) {
+	if broker.policy == nil {
+		return nil, fmt.Errorf("JetStream is not enabled")
+	}
+
+	watcher, err := broker.policy.WatchAll(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("watch policy lists: %w", err)
+	}
+
+	l := &PolicyLists{
+		kv:      broker.policy,
+		watcher: watcher,
+		lists:   make(map[string]map[string]struct{}),
+	}
+
+	// Дожидаемся начальных значений: nil в канале обновлений отмечает их конец
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+	for loaded := false; !loaded; {
+		select {
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return nil, fmt.Errorf("policy lists watcher closed")
+			}
+			if entry == nil {
+				loaded = true
+				continue
+			}
+			l.apply(entry)
+		case <-ctx.Done():
+			_ = watcher.Stop()
+			return nil, fmt.Errorf("load policy lists: %w", ctx.Err())
+		}
+	}
+
+	go l.watch()
+
+	slog.Info("policy lists: loaded", "lists", len(l.lists))
+
+	return l, nil
+}
+
+// Contains сообщает, входит ли key в список list владельца owner.
+func (l *PolicyLists) Contains(owner string, list PolicyList, key string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.lists[policyKey(owner, list)][key]
+	return ok
+}
+
+// Set заменяет список list владельца owner.
+// Пустой список удаляется.
+func (l *PolicyLists) Set(ctx context.Context, owner string, list PolicyList, keys []string) error {
+	key := policyKey(owner, list)
+
+	normalized := make([]string, len(keys))
+	for i, k := range keys {
+		normalized[i] = strings.ToLower(k)
+	}
+
+	var err error
+	if len(normalized) == 0 {
+		err = l.kv.Delete(ctx, key)
+	} else {
+		var data []byte
+		data, err = proto.Marshal(&message.KeySet{Keys: normalized})
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", key, err)
+		}
+		_, err = l.kv.Put(ctx, key, data)
+	}
+	if err != nil {
+		slog.Error("policy lists: save failed", "key", key, "error", err)
+		return fmt.Errorf("save %s: %w", key, err)
+	}
+
+	// Изменение применяется сразу, не дожидаясь watcher
+	l.store(key, normalized)
+
+	slog.Debug("policy lists: saved", "owner", owner, "list", list, "keys", len(normalized))
+
+	return nil
+}
+
+// Stop останавливает watcher.
+func (l *PolicyLists) Stop() error {
+	if err := l.watcher.Stop(); err != nil {
+		return fmt.Errorf("stop policy lists watcher: %w", err)
+	}
+	return nil
+}
+
+func (l *PolicyLists) watch() {
+	for entry := range l.watcher.Updates() {
+		if entry != nil {
+			l.apply(entry)
+		}
+	}
+}
+
+func (l *PolicyLists) apply(entry jetstream.KeyValueEntry) {
+	if entry.Operation() != jetstream.KeyValuePut {
+		l.store(entry.Key(), nil)
+		return
+	}
+
+	set := &message.KeySet{}
+	if err := proto.Unmarshal(entry.Value(), set); err != nil {
+		slog.Error("policy lists: unmarshal failed", "key", entry.Key(), "error", err)
+		return
+	}
+	l.store(entry.Key(), set.Keys)
+}
+
+func (l *PolicyLists) store(key string, keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(keys) == 0 {
+		delete(l.lists, key)
+		return
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	l.lists[key] = set
+}
+
+func policyKey(owner string, list PolicyList) string {
+	return strings.ToLower(owner) + "." + string(list)
+}
@@ -0,0 +1,164 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+// NATS subjects presence.
+const (
+	// PresenceEventsSubject — события подключения и отключения клиентов
+	// для сервисов кластера. Клиентам не доставляются.
+	PresenceEventsSubject = "goro.presence.events"
+
+	// presenceQueryPrefix — запрос статуса ключа; отвечает сервер,
+	// на котором подключён клиент.
+	presenceQueryPrefix = "goro.presence.query."
+
+	// presenceNotifyPrefix — изменения статусов для наблюдателя.
+	presenceNotifyPrefix = "goro.presence.notify."
+)
+
+// PresenceVisibility решает, виден ли статус клиента наблюдателю watcher.
+type PresenceVisibility func(watcher string) bool
+
+// Presence публикует статусы клиентов и отвечает на запросы статуса.
+//
+// Приватность обеспечивает сервер наблюдаемого клиента: изменения
+// рассылаются только разрешённым наблюдателям, а на запросы остальных
+// отвечает «offline».
+type Presence struct {
+	broker   *Broker
+	serverID string
+}
+
+// NewPresence создаёт сервис presence для сервера serverID.
+func NewPresence(broker *Broker, serverID string) *Presence {
+	return &Presence{broker: broker, serverID: serverID}
+}
+
+// PublishEvent публикует событие подключения или отключения клиента.
+func (p *Presence) PublishEvent(key string, online bool, at time.Time) error {
+	ev := &message.Presence{
+		Key:          key,
+		Online:       online,
+		ServerId:     p.serverID,
+		UnixDateTime: at.Unix(),
+	}
+	return p.publish(PresenceEventsSubject, ev)
+}
+
+// Notify отправляет изменение статуса наблюдателю watcher.
+func (p *Presence) Notify(watcher string, ev *message.Presence) error {
+	return p.publish(presenceNotifyPrefix+watcher, ev)
+}
+
+// Query запрашивает статус ключа key у кластера.
+// watcher — ключ запрашивающего клиента; пустой watcher означает служебный
+// запрос, ответ на который содержит server_id и не фильтруется.
+// Если клиент нигде не подключён, возвращается статус offline.
+func (p *Presence) Query(ctx context.Context, key, watcher string) (*message.Presence, error) {
+	subject := presenceQueryPrefix + key
+
+	// Запросы без deadline ограничиваем opTimeout
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opTimeout)
+		defer cancel()
+	}
+
+	resp, err := p.broker.conn.RequestWithContext(ctx, subject, []byte(watcher))
+	if errors.Is(err, nats.ErrNoResponders) {
+		return &message.Presence{Key: key}, nil
+	}
+	if err != nil {
+		slog.Error("presence: query failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf("query %s: %w", subject, err)
+	}
+
+	ev := &message.Presence{}
+	if err := proto.Unmarshal(resp.Data, ev); err != nil {
+		return nil, fmt.Errorf("unmarshal presence: %w", err)
+	}
+	return ev, nil
+}
+
+// Serve отвечает на запросы статуса ключа key, пока клиент подключён.
+// since — время подключения клиента.
+func (p *Presence) Serve(key string, since time.Time, visible PresenceVisibility) (*Subscriber, error) {
+	subject := presenceQueryPrefix + key
+
+	sub, err := p.broker.conn.Subscribe(subject, func(msg *nats.Msg) {
+		watcher := string(msg.Data)
+
+		ev := &message.Presence{Key: key}
+		switch {
+		case watcher == "":
+			ev.Online = true
+			ev.ServerId = p.serverID
+			ev.UnixDateTime = since.Unix()
+		case visible(watcher):
+			ev.Online = true
+			ev.UnixDateTime = since.Unix()
+		}
+
+		data, err := proto.Marshal(ev)
+		if err != nil {
+			slog.Error("presence: marshal response failed", "subject", subject, "error", err)
+			return
+		}
+		if err := msg.Respond(data); err != nil {
+			slog.Debug("presence: respond failed", "subject", subject, "error", err)
+		}
+	})
+	if err != nil {
+		slog.Error("presence: subscribe query failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf("subscribe to %s: %w", subject, err)
+	}
+
+	slog.Debug("presence: serving queries", "subject", subject)
+
+	return &Subscriber{sub: sub}, nil
+}
+
+// Watch подписывает наблюдателя watcher на изменения статусов.
+func (p *Presence) Watch(watcher string, handler func(*message.Presence)) (*Subscriber, error) {
+	subject := presenceNotifyPrefix + watcher
+
+	sub, err := p.broker.conn.Subscribe(subject, func(msg *nats.Msg) {
+		ev := &message.Presence{}
+		if err := proto.Unmarshal(msg.Data, ev); err != nil {
+			slog.Error("presence: unmarshal notification failed", "subject", subject, "error", err)
+			return
+		}
+		handler(ev)
+	})
+	if err != nil {
+		slog.Error("presence: subscribe notifications failed", "subject", subject, "error", err)
+		return nil, fmt.Errorf("subscribe to %s: %w", subject, err)
+	}
+
+	slog.Debug("presence: watching", "subject", subject)
+
+	return &Subscriber{sub: sub}, nil
+}
+
+func (p *Presence) publish(subject string, ev *message.Presence) error {
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal presence: %w", err)
+	}
+	if err := p.broker.conn.Publish(subject, data); err != nil {
+		slog.Error("presence: publish failed", "subject", subject, "error", err)
+		return fmt.Errorf("publish to %s: %w", subject, err)
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package broker
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
 )
 
 // Publisher публикует сообщения в NATS.
@@ -16,12 +20,72 @@ func NewPublisher(broker *Broker) *Publisher {
 }
 
 // Publish публикует сообщение для указанного получателя.
+// В режиме JetStream дожидается подтверждения сохранения сообщения в stream.
 func (p *Publisher) Publish(toPubKeyHex string, data []byte) error {
 	subject := subjectForClient(toPubKeyHex)
 	slog.Debug("publisher: publishing", "subject", subject, "size", len(data))
+
+	if p.broker.js != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+		defer cancel()
+		if _, err := p.broker.js.Publish(ctx, subject, data); err != nil {
+			slog.Error("publisher: JetStream publish failed", "subject", subject, "error", err)
+			return fmt.Errorf("publish to %s: %w", subject, err)
+		}
+		return nil
+	}
 	if err := p.broker.conn.Publish(subject, data); err != nil {
 		slog.Error("publisher: failed", "subject", subject, "error", err)
 		return fmt.Errorf("publish to %s: %w", subject, err)
 	}
 	return nil
 }
+
+// PublishFuture — публикация, подтверждение которой JetStream ещё не прислал.
+type PublishFuture struct {
+	subject  string
+	ack      jetstream.PubAckFuture // nil — результат известен сразу (err)
+	err      error
+	deadline time.Time
+}
+
+// PublishAsync публикует сообщение для указанного получателя, не дожидаясь
+// подтверждения JetStream: несколько публикаций ждут подтверждений одновременно.
+// Результат возвращает PublishFuture.Wait.
+func (p *Publisher) PublishAsync(toPubKeyHex string, data []byte) *PublishFuture {
+	subject := subjectForClient(toPubKeyHex)
+	slog.Debug("publisher: publishing async", "subject", subject, "size", len(data))
+
+	f := &PublishFuture{subject: subject, deadline: time.Now().Add(opTimeout)}
+	var err error
+	if p.broker.js != nil {
+		f.ack, err = p.broker.js.PublishAsync(subject, data)
+	} else {
+		err = p.broker.conn.Publish(subject, data)
+	}
+	if err != nil {
+		f.err = fmt.Errorf("publish to %s: %w", subject, err)
+	}
+	return f
+}
+
+// Wait ждёт подтверждения публикации. Срок ожидания отсчитывается от вызова
+// PublishAsync, поэтому ожидание нескольких публикаций по очереди в сумме
+// не дольше ожидания одной.
+func (f *PublishFuture) Wait() error {
+	if f.ack == nil {
+		return f.err
+	}
+
+	timer := time.NewTimer(time.Until(f.deadline))
+	defer timer.Stop()
+
+	select {
+	case <-f.ack.Ok():
+		return nil
+	case err := <-f.ack.Err():
+		return fmt.Errorf("publish to %s: %w", f.subject, err)
+	case <-timer.C:
+		return fmt.Errorf("publish to %s: %w", f.subject, context.DeadlineExceeded)
+	}
+}
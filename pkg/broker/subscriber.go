@@ -41,7 +41,10 @@ func (s *Subscriber) Unsubscribe() error {
 	return nil
 }
 
+// subjectPrefix — префикс NATS subject для сообщений клиентам.
+const subjectPrefix = "goro.msg."
+
 // subjectForClient возвращает NATS subject для клиента.
 func subjectForClient(pubKeyHex string) string {
-	return "goro.msg." + pubKeyHex
+	return subjectPrefix + pubKeyHex
 }
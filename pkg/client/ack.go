@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// AckStatus статус обработки исходящего сообщения сервером.
+type AckStatus byte
+
+// Статусы подтверждения.
+const (
+	AckAccepted         = AckStatus(protocol.AckStatusAccepted)
+	AckRejected         = AckStatus(protocol.AckStatusRejected)
+	AckRecipientUnknown = AckStatus(protocol.AckStatusRecipientUnknown)
+	AckForbidden        = AckStatus(protocol.AckStatusForbidden)
+	AckPartial          = AckStatus(protocol.AckStatusPartial)
+)
+
+// String возвращает название статуса.
+func (s AckStatus) String() string {
+	switch s {
+	case AckAccepted:
+		return "accepted"
+	case AckRejected:
+		return "rejected"
+	case AckRecipientUnknown:
+		return "recipient unknown"
+	case AckForbidden:
+		return "forbidden"
+	case AckPartial:
+		return "partial"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(s))
+	}
+}
+
+var (
+	// ErrRejected — сервер отказался принять сообщение.
+	ErrRejected = errors.New("message rejected")
+
+	// ErrRecipientUnknown — сервер не может доставить сообщение адресату.
+	ErrRecipientUnknown = errors.New("recipient unknown")
+
+	// ErrForbidden — доставку запретила политика авторизации сервера
+	// (блок-лист получателя, режим "только контакты" и т.п.).
+	ErrForbidden = errors.New("message forbidden")
+
+	// ErrPartialDelivery — сообщение группе доставлено только части участников.
+	// Повторная отправка продублирует его тем, кто уже получил.
+	ErrPartialDelivery = errors.New("partial delivery")
+)
+
+// SendResult результат обработки исходящего сообщения сервером.
+// Сопоставляется с OutgoingMessage по MsgID.
+type SendResult struct {
+	MsgID  string
+	Status AckStatus
+	Reason string
+}
+
+// Accepted сообщает, принято ли сообщение сервером.
+func (r SendResult) Accepted() bool {
+	return r.Status == AckAccepted
+}
+
+// Err возвращает nil для принятого сообщения, иначе ошибку с причиной отказа.
+func (r SendResult) Err() error {
+	switch r.Status {
+	case AckAccepted:
+		return nil
+	case AckRecipientUnknown:
+		return fmt.Errorf("%w: %s", ErrRecipientUnknown, r.Reason)
+	case AckForbidden:
+		return fmt.Errorf("%w: %s", ErrForbidden, r.Reason)
+	case AckPartial:
+		return fmt.Errorf("%w: %s", ErrPartialDelivery, r.Reason)
+	default:
+		return fmt.Errorf("%w: %s", ErrRejected, r.Reason)
+	}
+}
@@ -1,15 +1,16 @@
 package client
 
 import (
-	"crypto/tls"
 	"fmt"
+	"io"
 
 	"github.com/udisondev/sprut/pkg/identity"
 	"github.com/udisondev/sprut/pkg/protocol"
+	"github.com/udisondev/sprut/pkg/transport"
 )
 
 // signChallenge подписывает challenge от сервера.
-func signChallenge(keys *identity.KeyPair, challenge *protocol.ServerChallenge, conn *tls.Conn) ([protocol.SignatureSize]byte, error) {
+func signChallenge(keys *identity.KeyPair, challenge *protocol.ServerChallenge, conn transport.Conn) ([protocol.SignatureSize]byte, error) {
 	var sig [protocol.SignatureSize]byte
 
 	// Получаем channel binding из TLS соединения
@@ -36,3 +37,39 @@ func signChallenge(keys *identity.KeyPair, challenge *protocol.ServerChallenge,
 
 	return sig, nil
 }
+
+// challengeResponse проходит аутентификацию ed25519 ключом keys.
+func challengeResponse(conn transport.Conn, reader io.Reader, keys *identity.KeyPair) error {
+	// 1. Отправляем ClientHello
+	hello := &protocol.ClientHello{}
+	copy(hello.PubKey[:], keys.PublicKey)
+	if err := hello.Encode(conn); err != nil {
+		return fmt.Errorf("send client hello: %w", err)
+	}
+
+	// 2. Получаем ServerChallenge
+	msgType, err := protocol.ReadMessageType(reader)
+	if err != nil {
+		return fmt.Errorf("read challenge type: %w", err)
+	}
+	if msgType != protocol.TypeServerChallenge {
+		return fmt.Errorf("unexpected message type: %d", msgType)
+	}
+
+	challenge, err := protocol.DecodeServerChallenge(reader)
+	if err != nil {
+		return fmt.Errorf("decode challenge: %w", err)
+	}
+
+	// 3. Подписываем и отправляем ClientResponse
+	signature, err := signChallenge(keys, challenge, conn)
+	if err != nil {
+		return fmt.Errorf("sign challenge: %w", err)
+	}
+
+	response := &protocol.ClientResponse{Signature: signature}
+	if err := response.Encode(conn); err != nil {
+		return fmt.Errorf("send response: %w", err)
+	}
+	return nil
+}
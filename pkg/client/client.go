@@ -2,6 +2,8 @@ package client
 
 import (
 	"bufio"
+	"context"
+	"crypto/ed25519"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -9,20 +11,28 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/udisondev/sprut/pkg/identity"
 	"github.com/udisondev/sprut/pkg/message"
 	"github.com/udisondev/sprut/pkg/protocol"
+	"github.com/udisondev/sprut/pkg/transport"
 	"google.golang.org/protobuf/proto"
 )
 
 // OutgoingMessage сообщение для отправки.
+// Если указан GroupID, сообщение рассылается участникам группы, а To не используется.
 type OutgoingMessage struct {
 	To      string
+	GroupID string
 	MsgID   string
 	Payload []byte
+
+	// frameType задаётся для служебных кадров (изменение состава группы).
+	frameType byte
 }
 
 // buildTLSConfig создаёт TLS конфигурацию на основе опций.
@@ -61,18 +71,53 @@ func (cfg *connectConfig) buildTLSConfig() (*tls.Config, error) {
 		tlsConfig.InsecureSkipVerify = true
 	}
 
+	if cfg.clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cfg.clientCert}
+	}
+
 	return tlsConfig, nil
 }
 
-// Connect устанавливает соединение с сервером и возвращает канал входящих сообщений.
-//
-// Параметры:
-//   - addr: адрес сервера (host:port)
-//   - send: канал исходящих сообщений. Закрытие канала завершает соединение.
-//   - opts: опции подключения
-//
-// Возвращает канал входящих сообщений. Канал закрывается при завершении соединения.
-func Connect(addr string, send <-chan OutgoingMessage, opts ...ConnectOption) (<-chan *message.Message, error) {
+// ErrClosed — клиент закрыт: вызван Close или соединение завершено без повтора.
+var ErrClosed = errors.New("client closed")
+
+// Client соединение с сервером Sprut.
+// Методы безопасны для вызова из нескольких горутин.
+type Client struct {
+	cfg *connectConfig
+
+	// send читает пишущая горутина; закрытие завершает соединение.
+	// sendMu защищает закрытие send от конкурентных Send.
+	send    chan OutgoingMessage
+	sendMu  sync.RWMutex
+	recv    chan *message.Message
+	closing chan struct{}
+
+	// control — служебные кадры: квитанции о доставке и списки presence.
+	// Формирует readLoop и методы клиента, отправляет writeLoop.
+	control chan controlFrame
+
+	// Списки presence повторно отправляются после переподключения:
+	// сервер не хранит их между соединениями. nil — список не задавался.
+	presenceMu sync.Mutex
+	watchKeys  []string
+	allowKeys  []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	state     atomic.Int32
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+}
+
+// Dial устанавливает соединение с сервером и проходит аутентификацию.
+// addr — host:port TCP listener, unix:///path Unix listener (имя сервера
+// для проверки сертификата задаётся WithServerName), wss://host:port/path
+// WebSocket listener или quic://host:port QUIC listener.
+// ctx ограничивает только установку соединения.
+func Dial(ctx context.Context, addr string, opts ...ConnectOption) (*Client, error) {
 	// 1. Дефолтные значения
 	keys, err := identity.Generate()
 	if err != nil {
@@ -85,6 +130,13 @@ func Connect(addr string, send <-chan OutgoingMessage, opts ...ConnectOption) (<
 		dialTimeout:  DefaultDialTimeout,
 		writeTimeout: DefaultWriteTimeout,
 		readBufSize:  DefaultReadBufSize,
+
+		keepaliveInterval: DefaultKeepaliveInterval,
+		keepaliveTimeout:  DefaultKeepaliveTimeout,
+
+		reconnectMin:     DefaultReconnectMinDelay,
+		reconnectMax:     DefaultReconnectMaxDelay,
+		pendingQueueSize: DefaultPendingQueueSize,
 	}
 
 	// 2. Применяем опции
@@ -92,79 +144,285 @@ func Connect(addr string, send <-chan OutgoingMessage, opts ...ConnectOption) (<
 		opt(cfg)
 	}
 
+	if cfg.keepaliveTimeout > 0 && cfg.keepaliveTimeout <= cfg.keepaliveInterval {
+		return nil, fmt.Errorf("keepalive timeout %v must exceed interval %v", cfg.keepaliveTimeout, cfg.keepaliveInterval)
+	}
+
 	// 3. Настраиваем TLS
 	tlsConfig, err := cfg.buildTLSConfig()
 	if err != nil {
 		return nil, fmt.Errorf("build TLS config: %w", err)
 	}
 
-	// 4. Подключаемся
-	dialer := &net.Dialer{
-		Timeout:   cfg.dialTimeout,
-		LocalAddr: cfg.localAddr,
-	}
-	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	// 4. Подключаемся и проходим аутентификацию
+	conn, err := dial(ctx, addr, cfg, tlsConfig)
 	if err != nil {
-		return nil, fmt.Errorf("dial: %w", err)
+		return nil, err
 	}
 
-	// 5. Проходим аутентификацию
-	if err := authenticate(conn, cfg.keys, cfg.dialTimeout); err != nil {
-		_ = conn.Close() // ошибка Close() не важна, возвращаем ошибку authenticate
-		return nil, fmt.Errorf("authenticate: %w", err)
+	// 5. Запускаем цикл обработки
+	c := &Client{
+		send:    make(chan OutgoingMessage),
+		recv:    make(chan *message.Message, cfg.readBufSize),
+		closing: make(chan struct{}),
+		control: make(chan controlFrame, cfg.readBufSize),
+		done:    make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	// Состояние клиента обновляется до вызова обработчика приложения
+	onStateChange := cfg.onStateChange
+	cfg.onStateChange = func(state ConnState) {
+		prev := ConnState(c.state.Swap(int32(state)))
+		if state == StateConnected && prev == StateReconnecting {
+			c.resendPresence()
+		}
+		if onStateChange != nil {
+			onStateChange(state)
+		}
 	}
+	c.cfg = cfg
+	c.state.Store(int32(StateConnected))
 
-	// 6. Запускаем цикл обработки
-	recv := make(chan *message.Message, cfg.readBufSize)
-	go runLoop(conn, cfg, send, recv)
+	go func() {
+		if cfg.reconnect {
+			c.err = runReconnecting(c.ctx, addr, cfg, tlsConfig, conn, c.send, c.control, c.recv)
+		} else {
+			c.err = runLoop(conn, cfg, c.send, c.control, c.recv)
+		}
+		c.cancel()
+		close(c.done)
+	}()
 
-	return recv, nil
+	return c, nil
 }
 
-func authenticate(conn *tls.Conn, keys *identity.KeyPair, timeout time.Duration) error {
-	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
-		return fmt.Errorf("set deadline: %w", err)
+// Send передаёт сообщение пишущей горутине.
+// Возвращает управление, когда сообщение принято к отправке (или
+// поставлено в очередь на время переподключения), а не доставлено:
+// результат обработки сервером сообщает WithOnAck.
+func (c *Client) Send(ctx context.Context, msg OutgoingMessage) error {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	select {
+	case <-c.closing:
+		return ErrClosed
+	case <-c.done:
+		return c.closedErr()
+	default:
 	}
-	defer func() {
-		_ = conn.SetDeadline(time.Time{})
-	}()
 
-	reader := bufio.NewReader(conn)
+	select {
+	case c.send <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closing:
+		return ErrClosed
+	case <-c.done:
+		return c.closedErr()
+	}
+}
 
-	// 1. Отправляем ClientHello
-	hello := &protocol.ClientHello{}
-	copy(hello.PubKey[:], keys.PublicKey)
-	if err := hello.Encode(conn); err != nil {
-		return fmt.Errorf("send client hello: %w", err)
+// Receive возвращает следующее входящее сообщение.
+// После завершения клиента возвращает ошибку, для которой errors.Is(err, ErrClosed).
+func (c *Client) Receive(ctx context.Context) (*message.Message, error) {
+	select {
+	case msg, ok := <-c.recv:
+		if !ok {
+			<-c.done
+			return nil, c.closedErr()
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	// 2. Получаем ServerChallenge
-	msgType, err := protocol.ReadMessageType(reader)
+// Close завершает соединение и ожидает остановки горутин клиента.
+// Сообщения, уже принятые Send, отправляются перед закрытием.
+// Повторный вызов безопасен.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		// Сначала разблокируем ожидающие Send, затем закрываем канал
+		close(c.closing)
+		c.sendMu.Lock()
+		close(c.send)
+		c.sendMu.Unlock()
+		// Прерываем попытку переподключения, если она идёт
+		c.cancel()
+	})
+	<-c.done
+	return nil
+}
+
+// PublicKey возвращает публичный ключ клиента.
+func (c *Client) PublicKey() ed25519.PublicKey {
+	return c.cfg.keys.PublicKey
+}
+
+// State возвращает текущее состояние соединения.
+func (c *Client) State() ConnState {
+	return ConnState(c.state.Load())
+}
+
+// Done возвращает канал, который закрывается после завершения клиента.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err возвращает причину завершения клиента или nil, пока клиент работает
+// или если он закрыт через Close.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// closedErr возвращает ErrClosed с причиной завершения, если она есть.
+// Вызывается только после закрытия done.
+func (c *Client) closedErr() error {
+	if c.err != nil {
+		return fmt.Errorf("%w: %w", ErrClosed, c.err)
+	}
+	return ErrClosed
+}
+
+// Connect устанавливает соединение с сервером и возвращает канал входящих сообщений.
+// Обёртка над Dial для приложений, построенных на каналах.
+//
+// Параметры:
+//   - addr: адрес сервера (host:port, unix:///path, wss://host:port/path или quic://host:port)
+//   - send: канал исходящих сообщений. Закрытие канала завершает соединение.
+//   - opts: опции подключения
+//
+// Возвращает канал входящих сообщений. Канал закрывается при завершении соединения,
+// а с WithReconnect — когда клиент прекращает переподключаться.
+func Connect(addr string, send <-chan OutgoingMessage, opts ...ConnectOption) (<-chan *message.Message, error) {
+	c, err := Dial(context.Background(), addr, opts...)
 	if err != nil {
-		return fmt.Errorf("read challenge type: %w", err)
+		return nil, err
 	}
-	if msgType != protocol.TypeServerChallenge {
-		return fmt.Errorf("unexpected message type: %d", msgType)
+
+	go func() {
+		for {
+			select {
+			case msg, ok := <-send:
+				if !ok {
+					// Канал закрыт — завершаем соединение
+					_ = c.Close()
+					return
+				}
+				if err := c.Send(context.Background(), msg); err != nil {
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return c.recv, nil
+}
+
+// dial устанавливает TLS соединение и проходит аутентификацию.
+// Адрес вида wss://host:port/path — подключение через WebSocket listener сервера,
+// quic://host:port — через QUIC listener, unix:///path — через Unix сокет.
+func dial(ctx context.Context, addr string, cfg *connectConfig, tlsConfig *tls.Config) (transport.Conn, error) {
+	conn, err := dialTransport(ctx, addr, cfg, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
 	}
 
-	challenge, err := protocol.DecodeServerChallenge(reader)
+	// Отмена ctx прерывает аутентификацию закрытием соединения
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+
+	timeout := cfg.dialTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	err = authenticate(conn, cfg, timeout)
+	if !stop() {
+		return nil, fmt.Errorf("authenticate: %w", ctx.Err())
+	}
 	if err != nil {
-		return fmt.Errorf("decode challenge: %w", err)
+		_ = conn.Close() // ошибка Close() не важна, возвращаем ошибку authenticate
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+
+	return conn, nil
+}
+
+// dialTransport открывает соединение транспортом, выбранным по адресу.
+func dialTransport(ctx context.Context, addr string, cfg *connectConfig, tlsConfig *tls.Config) (transport.Conn, error) {
+	netDialer := &net.Dialer{
+		Timeout:   cfg.dialTimeout,
+		LocalAddr: cfg.localAddr,
+	}
+	if strings.HasPrefix(addr, "wss://") {
+		return transport.DialWebSocket(ctx, addr, netDialer, tlsConfig)
+	}
+	if quicAddr, ok := strings.CutPrefix(addr, "quic://"); ok {
+		if cfg.dialTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.dialTimeout)
+			defer cancel()
+		}
+		return transport.DialQUIC(ctx, quicAddr, tlsConfig)
+	}
+
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		// Локальный TCP адрес к Unix сокету не применим
+		network, addr = "unix", path
+		netDialer.LocalAddr = nil
 	}
 
-	// 3. Подписываем и отправляем ClientResponse
-	signature, err := signChallenge(keys, challenge, conn)
+	dialer := &tls.Dialer{NetDialer: netDialer, Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, network, addr)
 	if err != nil {
-		return fmt.Errorf("sign challenge: %w", err)
+		return nil, err
 	}
+	return conn.(*tls.Conn), nil
+}
 
-	response := &protocol.ClientResponse{Signature: signature}
-	if err := response.Encode(conn); err != nil {
-		return fmt.Errorf("send response: %w", err)
+func authenticate(conn transport.Conn, cfg *connectConfig, timeout time.Duration) error {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+	defer func() {
+		_ = conn.SetDeadline(time.Time{})
+	}()
+
+	reader := bufio.NewReader(conn)
+
+	// 1-3. Рукопожатие выбранным способом
+	switch {
+	case cfg.authToken != "":
+		token := &protocol.ClientToken{Token: cfg.authToken}
+		if err := token.Encode(conn); err != nil {
+			return fmt.Errorf("send token: %w", err)
+		}
+	case cfg.certAuth:
+		hello := &protocol.ClientCertHello{}
+		if err := hello.Encode(conn); err != nil {
+			return fmt.Errorf("send cert hello: %w", err)
+		}
+	default:
+		if err := challengeResponse(conn, reader, cfg.keys); err != nil {
+			return err
+		}
 	}
 
 	// 4. Получаем AuthResult (синхронизация с сервером)
-	msgType, err = protocol.ReadMessageType(reader)
+	msgType, err := protocol.ReadMessageType(reader)
 	if err != nil {
 		return fmt.Errorf("read result type: %w", err)
 	}
@@ -177,15 +435,54 @@ func authenticate(conn *tls.Conn, keys *identity.KeyPair, timeout time.Duration)
 		return fmt.Errorf("decode result: %w", err)
 	}
 
-	if result.Status != protocol.AuthStatusOK {
-		return fmt.Errorf("%w: %s", protocol.ErrAuthFailed, result.ErrorMsg)
+	if result.Status == protocol.AuthStatusOK {
+		return nil
 	}
 
-	return nil
+	switch result.Status {
+	case protocol.AuthStatusInvalidSig:
+		return fmt.Errorf("%w: %w: %s", ErrAuthFailed, protocol.ErrInvalidSignature, result.ErrorMsg)
+	case protocol.AuthStatusReplay:
+		return fmt.Errorf("%w: %w: %s", ErrAuthFailed, protocol.ErrChallengeExpired, result.ErrorMsg)
+	default:
+		return fmt.Errorf("%w: %s", ErrAuthFailed, result.ErrorMsg)
+	}
 }
 
 // runLoop управляет соединением: читает и пишет сообщения.
-func runLoop(conn *tls.Conn, cfg *connectConfig, send <-chan OutgoingMessage, recv chan<- *message.Message) {
+// Возвращает причину разрыва или nil, если соединение закрыто приложением.
+func runLoop(conn transport.Conn, cfg *connectConfig, send <-chan OutgoingMessage, control chan controlFrame, recv chan<- *message.Message) error {
+	cfg.setState(StateConnected)
+	res := runSession(conn, cfg, send, control, recv, nil)
+
+	// Закрываем канал получения
+	close(recv)
+	cfg.setState(StateClosed)
+
+	if res.sendClosed {
+		return nil
+	}
+	return res.err
+}
+
+// controlFrame — служебный кадр клиента.
+type controlFrame interface {
+	Encode(w io.Writer) error
+}
+
+// sessionResult итог работы одного соединения.
+type sessionResult struct {
+	// sendClosed — приложение закрыло канал отправки.
+	sendClosed bool
+	// err — причина разрыва со стороны чтения, nil при штатном закрытии.
+	err error
+	// unsent — сообщения, которые не удалось записать в соединение.
+	unsent []OutgoingMessage
+}
+
+// runSession обслуживает одно соединение до его закрытия.
+// Сначала отправляются сообщения из pending, затем из канала send.
+func runSession(conn transport.Conn, cfg *connectConfig, send <-chan OutgoingMessage, control chan controlFrame, recv chan<- *message.Message, pending []OutgoingMessage) sessionResult {
 	var wg sync.WaitGroup
 	closeCh := make(chan struct{})
 	var closeOnce sync.Once
@@ -199,28 +496,31 @@ func runLoop(conn *tls.Conn, cfg *connectConfig, send <-chan OutgoingMessage, re
 		})
 	}
 
+	var res sessionResult
+
 	// Запускаем читающую горутину
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		readLoop(conn, cfg, recv, closeCh, closeAll)
+		res.err = readLoop(conn, cfg, recv, control, closeCh, closeAll)
 	}()
 
 	// Запускаем пишущую горутину
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		writeLoop(conn, cfg, send, closeCh, closeAll)
+		res.sendClosed, res.unsent = writeLoop(conn, cfg, send, pending, control, closeCh, closeAll)
 	}()
 
 	// Ждём завершения обеих горутин
 	wg.Wait()
 
-	// Закрываем канал получения
-	close(recv)
+	return res
 }
 
-func readLoop(conn *tls.Conn, cfg *connectConfig, recv chan<- *message.Message, closeCh <-chan struct{}, closeAll func()) {
+// readLoop читает кадры сервера до разрыва соединения.
+// Возвращает причину разрыва; ошибки уже переданы в обработчик.
+func readLoop(conn transport.Conn, cfg *connectConfig, recv chan<- *message.Message, control chan<- controlFrame, closeCh <-chan struct{}, closeAll func()) error {
 	defer closeAll()
 
 	reader := bufio.NewReader(conn)
@@ -228,59 +528,191 @@ func readLoop(conn *tls.Conn, cfg *connectConfig, recv chan<- *message.Message,
 	for {
 		select {
 		case <-closeCh:
-			return
+			return nil
 		default:
 		}
 
-		serverMsg, err := protocol.DecodeServerMessage(reader)
+		// Сервер отвечает на каждый ping: тишина дольше таймаута — соединение мёртвое
+		if cfg.keepaliveTimeout > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(cfg.keepaliveTimeout)); err != nil {
+				err = fmt.Errorf("set read deadline: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+		}
+
+		frameType, err := protocol.ReadMessageType(reader)
 		if err != nil {
-			// EOF или closed — нормальное завершение
-			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
-				return
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				err = fmt.Errorf("%w: no frames from server for %v", ErrKeepaliveTimeout, cfg.keepaliveTimeout)
+			} else {
+				err = fmt.Errorf("read frame type: %w", err)
 			}
-			// Проверяем closeCh перед логированием
+			handleReadError(cfg, closeCh, err)
+			return err
+		}
+
+		switch frameType {
+		case protocol.TypeServerMessage:
+			serverMsg, err := protocol.DecodeServerMessage(reader)
+			if err != nil {
+				err = fmt.Errorf("decode server message: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+
+			msg := &message.Message{}
+			if err := proto.Unmarshal(serverMsg.Data, msg); err != nil {
+				handleError(cfg, fmt.Errorf("unmarshal message: %w", err))
+				continue
+			}
+
+			if msg.Kind == message.Kind_KIND_RECEIPT {
+				if cfg.onReceipt != nil {
+					cfg.onReceipt(Receipt{
+						MsgID:   msg.Id,
+						From:    msg.From,
+						Time:    time.Unix(msg.UnixDateTime, 0),
+						Dropped: string(msg.Payload) == message.ReceiptDropped,
+					})
+				}
+				continue
+			}
+
 			select {
+			case recv <- msg:
 			case <-closeCh:
-				return
-			default:
-				handleError(cfg, fmt.Errorf("decode server message: %w", err))
-				return
+				return nil
 			}
-		}
 
-		msg := &message.Message{}
-		if err := proto.Unmarshal(serverMsg.Data, msg); err != nil {
-			handleError(cfg, fmt.Errorf("unmarshal message: %w", err))
-			continue
-		}
+			// Сообщение передано приложению — подтверждаем доставку автору.
+			// Изменения состава группы не подтверждаются.
+			if !cfg.disableReceipts && msg.Kind == message.Kind_KIND_MESSAGE {
+				select {
+				case control <- &protocol.ClientReceipt{To: msg.From, MsgID: msg.Id}:
+				case <-closeCh:
+					return nil
+				}
+			}
 
-		select {
-		case recv <- msg:
-		case <-closeCh:
-			return
+		case protocol.TypeServerAck:
+			ack, err := protocol.DecodeServerAck(reader)
+			if err != nil {
+				err = fmt.Errorf("decode server ack: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+			if cfg.onAck != nil {
+				cfg.onAck(SendResult{MsgID: ack.MsgID, Status: AckStatus(ack.Status), Reason: ack.Reason})
+			}
+
+		case protocol.TypeServerPresence:
+			presence, err := protocol.DecodeServerPresence(reader)
+			if err != nil {
+				err = fmt.Errorf("decode server presence: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+			if cfg.onPresence != nil {
+				cfg.onPresence(newPresence(presence))
+			}
+
+		case protocol.TypeServerError:
+			serverErr, err := protocol.DecodeServerError(reader)
+			if err != nil {
+				err = fmt.Errorf("decode server error: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+			// Сервер закрывает соединение сразу после кадра ошибки
+			err = &ServerError{Code: serverErr.Code, Message: serverErr.Message}
+			handleError(cfg, err)
+			return err
+
+		case protocol.TypeServerGoAway:
+			goAway, err := protocol.DecodeServerGoAway(reader)
+			if err != nil {
+				err = fmt.Errorf("decode server go away: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+			// Все кадры до GoAway получены, закрываем соединение сами
+			err = &GoAwayError{Addr: goAway.Addr}
+			handleError(cfg, err)
+			return err
+
+		case protocol.TypeServerPong:
+			// Достаточно того, что кадр получен: дедлайн чтения уже сдвинут
+			if _, err := protocol.DecodeServerPong(reader); err != nil {
+				err = fmt.Errorf("decode server pong: %w", err)
+				handleReadError(cfg, closeCh, err)
+				return err
+			}
+
+		default:
+			err := fmt.Errorf("unexpected frame type: %d", frameType)
+			handleError(cfg, err)
+			return err
 		}
 	}
 }
 
-func writeLoop(conn *tls.Conn, cfg *connectConfig, send <-chan OutgoingMessage, closeCh <-chan struct{}, closeAll func()) {
+// writeLoop отправляет сообщения до закрытия соединения или канала send.
+// Возвращает признак закрытия send и сообщения, которые не удалось записать.
+// После ошибки записи TLS соединение непригодно, поэтому оно закрывается.
+func writeLoop(conn transport.Conn, cfg *connectConfig, send <-chan OutgoingMessage, pending []OutgoingMessage, control <-chan controlFrame, closeCh <-chan struct{}, closeAll func()) (bool, []OutgoingMessage) {
+	// Сначала отправляем сообщения, накопленные за время переподключения
+	for i := range pending {
+		if err := sendMessage(conn, cfg, &pending[i]); err != nil {
+			handleError(cfg, fmt.Errorf("send message: %w", err))
+			closeAll()
+			return false, pending[i:]
+		}
+	}
+
+	// Ping поддерживает соединение живым для idle_timeout сервера
+	// и гарантирует ответный кадр для проверки в readLoop
+	var pings <-chan time.Time
+	if cfg.keepaliveInterval > 0 {
+		ticker := time.NewTicker(cfg.keepaliveInterval)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+	var pingSeq uint64
+
 	for {
 		select {
 		case <-closeCh:
-			return
+			return false, nil
+		case <-pings:
+			pingSeq++
+			if err := sendControl(conn, cfg, &protocol.ClientPing{Seq: pingSeq}); err != nil {
+				handleError(cfg, fmt.Errorf("send ping: %w", err))
+				closeAll()
+				return false, nil
+			}
+		case frame := <-control:
+			if err := sendControl(conn, cfg, frame); err != nil {
+				handleError(cfg, fmt.Errorf("send control frame: %w", err))
+				closeAll()
+				return false, nil
+			}
 		case msg, ok := <-send:
 			if !ok {
 				// Канал закрыт — завершаем соединение
 				closeAll()
-				return
+				return true, nil
 			}
 			if err := sendMessage(conn, cfg, &msg); err != nil {
 				handleError(cfg, fmt.Errorf("send message: %w", err))
+				closeAll()
+				return false, []OutgoingMessage{msg}
 			}
 		}
 	}
 }
 
-func sendMessage(conn *tls.Conn, cfg *connectConfig, msg *OutgoingMessage) error {
+func sendMessage(conn transport.Conn, cfg *connectConfig, msg *OutgoingMessage) error {
 	if cfg.writeTimeout > 0 {
 		if err := conn.SetWriteDeadline(time.Now().Add(cfg.writeTimeout)); err != nil {
 			return fmt.Errorf("set write deadline: %w", err)
@@ -288,14 +720,45 @@ func sendMessage(conn *tls.Conn, cfg *connectConfig, msg *OutgoingMessage) error
 	}
 
 	clientMsg := &protocol.ClientMessage{
+		Type:    msg.frameType,
 		To:      msg.To,
 		MsgID:   msg.MsgID,
 		Payload: msg.Payload,
 	}
+	if msg.GroupID != "" {
+		clientMsg.To = msg.GroupID
+		if clientMsg.Type == 0 {
+			clientMsg.Type = protocol.TypeClientGroupMessage
+		}
+	}
 
 	return clientMsg.Encode(conn)
 }
 
+func sendControl(conn transport.Conn, cfg *connectConfig, frame controlFrame) error {
+	if cfg.writeTimeout > 0 {
+		if err := conn.SetWriteDeadline(time.Now().Add(cfg.writeTimeout)); err != nil {
+			return fmt.Errorf("set write deadline: %w", err)
+		}
+	}
+
+	return frame.Encode(conn)
+}
+
+// handleReadError сообщает об ошибке чтения, если соединение не закрывается штатно.
+func handleReadError(cfg *connectConfig, closeCh <-chan struct{}, err error) {
+	// EOF или closed — нормальное завершение
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return
+	}
+	// Проверяем closeCh перед логированием
+	select {
+	case <-closeCh:
+	default:
+		handleError(cfg, err)
+	}
+}
+
 func handleError(cfg *connectConfig, err error) {
 	if cfg.onError != nil {
 		cfg.onError(err)
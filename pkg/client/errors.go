@@ -0,0 +1,114 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// Ошибки, которыми сервер завершает соединение.
+// Проверяются через errors.Is на ошибке из WithOnError или Connect.
+var (
+	// ErrAuthFailed — сервер отклонил аутентификацию.
+	ErrAuthFailed = protocol.ErrAuthFailed
+
+	// ErrProtocolViolation — клиент нарушил протокол.
+	ErrProtocolViolation = errors.New("protocol violation")
+
+	// ErrMessageTooLarge — сообщение превышает лимит сервера.
+	ErrMessageTooLarge = errors.New("message too large")
+
+	// ErrRateLimited — превышен лимит частоты сообщений.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrSlowConsumer — клиент не успевает читать входящие сообщения.
+	ErrSlowConsumer = errors.New("slow consumer")
+
+	// ErrSessionReplaced — с тем же ключом подключился другой клиент.
+	ErrSessionReplaced = errors.New("session replaced")
+
+	// ErrKicked — соединение закрыто администратором.
+	ErrKicked = errors.New("kicked")
+
+	// ErrServerInternal — внутренняя ошибка сервера.
+	ErrServerInternal = errors.New("server internal error")
+
+	// ErrGoAway — сервер завершает работу и просит переподключиться.
+	ErrGoAway = errors.New("server going away")
+
+	// ErrKeepaliveTimeout — от сервера нет кадров дольше таймаута keepalive
+	// (см. WithKeepalive): соединение считается мёртвым.
+	ErrKeepaliveTimeout = errors.New("keepalive timeout")
+)
+
+// GoAwayError — сервер завершает работу (кадр GoAway).
+// Addr — адрес, к которому следует переподключиться; пустой — к прежнему.
+type GoAwayError struct {
+	Addr string
+}
+
+// Error возвращает описание ошибки.
+func (e *GoAwayError) Error() string {
+	if e.Addr == "" {
+		return ErrGoAway.Error()
+	}
+	return fmt.Sprintf("%v: reconnect to %s", ErrGoAway, e.Addr)
+}
+
+// Unwrap возвращает ErrGoAway.
+func (e *GoAwayError) Unwrap() error {
+	return ErrGoAway
+}
+
+// ServerError ошибка, полученная от сервера перед закрытием соединения.
+type ServerError struct {
+	Code    byte
+	Message string
+}
+
+// Error возвращает описание ошибки.
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Unwrap(), e.Message)
+}
+
+// Unwrap возвращает sentinel-ошибку, соответствующую коду.
+func (e *ServerError) Unwrap() error {
+	switch e.Code {
+	case protocol.ErrorCodeProtocol:
+		return ErrProtocolViolation
+	case protocol.ErrorCodeMessageTooLarge:
+		return ErrMessageTooLarge
+	case protocol.ErrorCodeRateLimited:
+		return ErrRateLimited
+	case protocol.ErrorCodeSlowConsumer:
+		return ErrSlowConsumer
+	case protocol.ErrorCodeReplaced:
+		return ErrSessionReplaced
+	case protocol.ErrorCodeKicked:
+		return ErrKicked
+	default:
+		return ErrServerInternal
+	}
+}
+
+// IsRetryable сообщает, имеет ли смысл переподключаться после ошибки.
+// Повторное подключение не поможет при отказе в аутентификации,
+// нарушении протокола, слишком большом сообщении, вытеснении сессии
+// принудительном отключении и недоверенном сертификате сервера.
+func IsRetryable(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &certErr):
+		return false
+	case errors.Is(err, ErrAuthFailed),
+		errors.Is(err, ErrProtocolViolation),
+		errors.Is(err, ErrMessageTooLarge),
+		errors.Is(err, ErrSessionReplaced),
+		errors.Is(err, ErrKicked):
+		return false
+	default:
+		return true
+	}
+}
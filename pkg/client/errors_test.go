@@ -0,0 +1,84 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func TestServerErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		code byte
+		want error
+	}{
+		{protocol.ErrorCodeInternal, ErrServerInternal},
+		{protocol.ErrorCodeProtocol, ErrProtocolViolation},
+		{protocol.ErrorCodeMessageTooLarge, ErrMessageTooLarge},
+		{protocol.ErrorCodeRateLimited, ErrRateLimited},
+		{protocol.ErrorCodeSlowConsumer, ErrSlowConsumer},
+		{protocol.ErrorCodeReplaced, ErrSessionReplaced},
+		{protocol.ErrorCodeKicked, ErrKicked},
+		{0xFF, ErrServerInternal},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("read: %w", &ServerError{Code: tt.code, Message: "details"})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("code %d: %v is not %v", tt.code, err, tt.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"auth", fmt.Errorf("authenticate: %w", ErrAuthFailed), false},
+		{"protocol", &ServerError{Code: protocol.ErrorCodeProtocol}, false},
+		{"too_large", &ServerError{Code: protocol.ErrorCodeMessageTooLarge}, false},
+		{"replaced", &ServerError{Code: protocol.ErrorCodeReplaced}, false},
+		{"kicked", &ServerError{Code: protocol.ErrorCodeKicked}, false},
+		{"rate_limited", &ServerError{Code: protocol.ErrorCodeRateLimited}, true},
+		{"slow_consumer", &ServerError{Code: protocol.ErrorCodeSlowConsumer}, true},
+		{"internal", &ServerError{Code: protocol.ErrorCodeInternal}, true},
+		{"go_away", fmt.Errorf("read: %w", &GoAwayError{Addr: "sprut-2:8443"}), true},
+		{"network", errors.New("connection reset"), true},
+		{"certificate", fmt.Errorf("dial: %w", &tls.CertificateVerificationError{Err: errors.New("unknown authority")}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendResultErr(t *testing.T) {
+	tests := []struct {
+		status AckStatus
+		want   error
+	}{
+		{AckRejected, ErrRejected},
+		{AckRecipientUnknown, ErrRecipientUnknown},
+		{AckForbidden, ErrForbidden},
+		{AckPartial, ErrPartialDelivery},
+		{AckStatus(0xFF), ErrRejected},
+	}
+
+	if err := (SendResult{Status: AckAccepted}).Err(); err != nil {
+		t.Errorf("accepted: %v, want nil", err)
+	}
+	for _, tt := range tests {
+		err := SendResult{MsgID: "m", Status: tt.status, Reason: "details"}.Err()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %s: %v is not %v", tt.status, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/group"
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// GroupOp операция над составом группы.
+type GroupOp = message.GroupOp
+
+// Операции над составом группы.
+const (
+	GroupCreate = message.GroupOp_GROUP_OP_CREATE
+	GroupAdd    = message.GroupOp_GROUP_OP_ADD
+	GroupRemove = message.GroupOp_GROUP_OP_REMOVE
+	GroupLeave  = message.GroupOp_GROUP_OP_LEAVE
+)
+
+// GroupChange изменение состава группы.
+// Для GroupCreate автор становится владельцем, Members — начальными участниками.
+// Для GroupLeave Members не используется.
+type GroupChange struct {
+	GroupID string
+	Op      GroupOp
+	Members []string
+}
+
+// NewGroupID генерирует случайный id для новой группы.
+func NewGroupID() (string, error) {
+	return group.NewID()
+}
+
+// ChangeGroup подписывает изменение состава группы ключом клиента
+// и отправляет его серверу. Результат сообщает WithOnAck по msgID.
+// Участники получают изменение как сообщение с Kind_KIND_GROUP_CONTROL,
+// подпись которого можно проверить через group.Verify.
+// Группы требуют JetStream на сервере: без него изменение и сообщения
+// группе отклоняются с AckRejected.
+func (c *Client) ChangeGroup(ctx context.Context, msgID string, change GroupChange) error {
+	ctl, err := group.Sign(c.cfg.keys.PrivateKey, &message.GroupChange{
+		GroupId:      change.GroupID,
+		Op:           change.Op,
+		Members:      change.Members,
+		Actor:        c.cfg.keys.PublicKeyHex(),
+		UnixDateTime: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	payload, err := proto.Marshal(ctl)
+	if err != nil {
+		return fmt.Errorf("marshal group control: %w", err)
+	}
+
+	return c.Send(ctx, OutgoingMessage{
+		GroupID:   change.GroupID,
+		MsgID:     msgID,
+		Payload:   payload,
+		frameType: protocol.TypeClientGroupControl,
+	})
+}
@@ -14,6 +14,16 @@ const (
 	DefaultDialTimeout  = 10 * time.Second
 	DefaultWriteTimeout = 30 * time.Second
 	DefaultReadBufSize  = 100
+
+	// Keepalive: ping раз в DefaultKeepaliveInterval; соединение считается
+	// мёртвым, если от сервера нет кадров дольше DefaultKeepaliveTimeout.
+	// Интервал меньше idle_timeout сервера по умолчанию (90s).
+	DefaultKeepaliveInterval = 30 * time.Second
+	DefaultKeepaliveTimeout  = 60 * time.Second
+
+	DefaultReconnectMinDelay = 500 * time.Millisecond
+	DefaultReconnectMaxDelay = 30 * time.Second
+	DefaultPendingQueueSize  = 1000
 )
 
 // DefaultLocalAddr адрес для исходящих соединений по умолчанию.
@@ -23,6 +33,11 @@ var DefaultLocalAddr = &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}
 type connectConfig struct {
 	keys *identity.KeyPair
 
+	// Способ аутентификации: по умолчанию challenge/response ключом keys
+	authToken  string
+	certAuth   bool
+	clientCert *tls.Certificate
+
 	tlsConfig *tls.Config
 
 	// TLS builder fields
@@ -33,10 +48,24 @@ type connectConfig struct {
 
 	localAddr    *net.TCPAddr
 	onError      func(error)
+	onAck        func(SendResult)
+	onReceipt    func(Receipt)
+	onPresence   func(Presence)
+
+	disableReceipts bool
 	dialTimeout  time.Duration
 	writeTimeout time.Duration
 
 	readBufSize int
+
+	keepaliveInterval time.Duration
+	keepaliveTimeout  time.Duration
+
+	reconnect        bool
+	reconnectMin     time.Duration
+	reconnectMax     time.Duration
+	pendingQueueSize int
+	onStateChange    func(ConnState)
 }
 
 // ConnectOption конфигурирует соединение.
@@ -50,6 +79,32 @@ func WithKeys(keys *identity.KeyPair) ConnectOption {
 	}
 }
 
+// WithAuthToken включает аутентификацию подписанным JWT вместо
+// challenge/response. Сервер берёт PeerID из claim sub токена.
+func WithAuthToken(token string) ConnectOption {
+	return func(c *connectConfig) {
+		c.authToken = token
+	}
+}
+
+// WithClientCertificate добавляет клиентский сертификат в TLS конфигурацию
+// и включает аутентификацию по нему (см. WithCertAuth).
+func WithClientCertificate(cert tls.Certificate) ConnectOption {
+	return func(c *connectConfig) {
+		c.clientCert = &cert
+		c.certAuth = true
+	}
+}
+
+// WithCertAuth включает аутентификацию по клиентскому TLS сертификату
+// вместо challenge/response. Сертификат передаётся через WithClientCertificate
+// или WithTLSConfig.
+func WithCertAuth() ConnectOption {
+	return func(c *connectConfig) {
+		c.certAuth = true
+	}
+}
+
 // WithTLSConfig устанавливает TLS конфигурацию.
 func WithTLSConfig(cfg *tls.Config) ConnectOption {
 	return func(c *connectConfig) {
@@ -64,6 +119,38 @@ func WithOnError(handler func(error)) ConnectOption {
 	}
 }
 
+// WithOnAck устанавливает обработчик подтверждений исходящих сообщений.
+// Вызывается из читающей горутины, поэтому не должен блокироваться.
+func WithOnAck(handler func(SendResult)) ConnectOption {
+	return func(c *connectConfig) {
+		c.onAck = handler
+	}
+}
+
+// WithOnReceipt устанавливает обработчик квитанций о доставке от получателей.
+// Вызывается из читающей горутины, поэтому не должен блокироваться.
+func WithOnReceipt(handler func(Receipt)) ConnectOption {
+	return func(c *connectConfig) {
+		c.onReceipt = handler
+	}
+}
+
+// WithOnPresence устанавливает обработчик статусов контактов (см. Client.WatchPresence).
+// Вызывается из читающей горутины, поэтому не должен блокироваться.
+func WithOnPresence(handler func(Presence)) ConnectOption {
+	return func(c *connectConfig) {
+		c.onPresence = handler
+	}
+}
+
+// WithoutDeliveryReceipts отключает автоматическую отправку квитанций
+// о доставке авторам входящих сообщений.
+func WithoutDeliveryReceipts() ConnectOption {
+	return func(c *connectConfig) {
+		c.disableReceipts = true
+	}
+}
+
 // WithDialTimeout устанавливает таймаут подключения.
 func WithDialTimeout(d time.Duration) ConnectOption {
 	return func(c *connectConfig) {
@@ -78,6 +165,24 @@ func WithReadBufSize(n int) ConnectOption {
 	}
 }
 
+// WithKeepalive задаёт интервал ping и таймаут, после которого соединение
+// без единого кадра от сервера считается мёртвым (ошибка ErrKeepaliveTimeout,
+// с WithReconnect — переподключение). timeout должен быть больше interval.
+func WithKeepalive(interval, timeout time.Duration) ConnectOption {
+	return func(c *connectConfig) {
+		c.keepaliveInterval = interval
+		c.keepaliveTimeout = timeout
+	}
+}
+
+// WithoutKeepalive отключает ping и обнаружение мёртвого соединения.
+// Сервер с limits.idle_timeout отключит молчащего клиента.
+func WithoutKeepalive() ConnectOption {
+	return func(c *connectConfig) {
+		c.keepaliveInterval = 0
+		c.keepaliveTimeout = 0
+	}
+}
 
 // WithWriteTimeout устанавливает таймаут записи.
 func WithWriteTimeout(d time.Duration) ConnectOption {
@@ -119,8 +224,45 @@ func WithInsecureSkipVerify() ConnectOption {
 // WithLocalAddr устанавливает локальный адрес для исходящих соединений.
 // По умолчанию используется DefaultLocalAddr (127.0.0.1).
 // Передайте nil чтобы использовать системный выбор адреса.
+// На quic:// адреса не влияет: UDP сокет выбирает адрес сам.
 func WithLocalAddr(addr *net.TCPAddr) ConnectOption {
 	return func(c *connectConfig) {
 		c.localAddr = addr
 	}
 }
+
+// WithReconnect включает автоматическое переподключение.
+// После разрыва клиент повторяет подключение и аутентификацию,
+// сохраняя каналы send и recv. Переподключение прекращается, если
+// приложение закрыло send или ошибка не допускает повтора (см. IsRetryable).
+func WithReconnect() ConnectOption {
+	return func(c *connectConfig) {
+		c.reconnect = true
+	}
+}
+
+// WithReconnectBackoff устанавливает границы задержки между попытками
+// переподключения. Задержка удваивается с каждой попыткой.
+func WithReconnectBackoff(minDelay, maxDelay time.Duration) ConnectOption {
+	return func(c *connectConfig) {
+		c.reconnectMin = minDelay
+		c.reconnectMax = maxDelay
+	}
+}
+
+// WithPendingQueueSize устанавливает, сколько исходящих сообщений
+// накапливается, пока соединения нет. Когда очередь заполнена,
+// канал send не читается и отправитель блокируется.
+func WithPendingQueueSize(n int) ConnectOption {
+	return func(c *connectConfig) {
+		c.pendingQueueSize = n
+	}
+}
+
+// WithOnStateChange устанавливает обработчик смены состояния соединения.
+// Вызывается из управляющей горутины клиента, поэтому не должен блокироваться.
+func WithOnStateChange(handler func(ConnState)) ConnectOption {
+	return func(c *connectConfig) {
+		c.onStateChange = handler
+	}
+}
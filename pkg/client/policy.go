@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// SetBlockList задаёт ключи, от которых клиент не принимает сообщения,
+// заменяя предыдущий список. Список хранится на сервере и действует,
+// если сервер учитывает блок-листы (policy.blocklists). Отправитель
+// получает отказ с ErrForbidden.
+//
+// Сервер подтверждает сохранение списка ack с MsgID protocol.BlockListAckID
+// (см. WithOnAck); при AckRejected список не сохранён и его следует отправить
+// повторно.
+func (c *Client) SetBlockList(ctx context.Context, keys []string) error {
+	return c.setPolicyList(ctx, protocol.TypeClientBlockList, keys)
+}
+
+// SetContacts задаёт контакты клиента, заменяя предыдущий список.
+// Список хранится на сервере; в режиме "только контакты"
+// (policy.contacts_only) клиенту могут писать только они. Сохранение
+// подтверждается ack с MsgID protocol.ContactListAckID, как в SetBlockList.
+func (c *Client) SetContacts(ctx context.Context, keys []string) error {
+	return c.setPolicyList(ctx, protocol.TypeClientContactList, keys)
+}
+
+func (c *Client) setPolicyList(ctx context.Context, frameType byte, keys []string) error {
+	if err := validateKeyList(frameType, keys); err != nil {
+		return err
+	}
+
+	keys = slices.Clone(keys)
+	if keys == nil {
+		keys = []string{}
+	}
+	return c.sendControl(ctx, &protocol.KeyList{Type: frameType, Keys: keys})
+}
+
+// validateKeyList проверяет размер списка и формат ключей.
+func validateKeyList(frameType byte, keys []string) error {
+	if maxKeys := protocol.MaxKeyListKeys(frameType); len(keys) > maxKeys {
+		return fmt.Errorf("too many keys: %d > %d", len(keys), maxKeys)
+	}
+	for _, key := range keys {
+		if b, err := hex.DecodeString(key); err != nil || len(b) != protocol.PublicKeySize {
+			return fmt.Errorf("invalid key: %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// Presence статус контакта, за которым наблюдает клиент.
+type Presence struct {
+	Key    string
+	Online bool
+	// Time — время подключения (online) или отключения (offline).
+	// Нулевое, если сервер его не знает.
+	Time time.Time
+}
+
+func newPresence(m *protocol.ServerPresence) Presence {
+	p := Presence{Key: m.Key, Online: m.Status == protocol.PresenceOnline}
+	if m.UnixDateTime != 0 {
+		p.Time = time.Unix(m.UnixDateTime, 0)
+	}
+	return p
+}
+
+// WatchPresence подписывает клиента на статусы ключей keys,
+// заменяя предыдущий список. Статусы приходят в обработчик WithOnPresence:
+// сначала текущие, затем изменения. Владелец ключа, не разрешивший
+// клиенту видеть свой статус через AllowPresence, всегда выглядит offline.
+func (c *Client) WatchPresence(ctx context.Context, keys []string) error {
+	return c.setPresenceList(ctx, protocol.TypeClientPresenceWatch, keys)
+}
+
+// AllowPresence задаёт ключи, которым виден статус клиента,
+// заменяя предыдущий список. По умолчанию статус не виден никому.
+func (c *Client) AllowPresence(ctx context.Context, keys []string) error {
+	return c.setPresenceList(ctx, protocol.TypeClientPresenceAllow, keys)
+}
+
+func (c *Client) setPresenceList(ctx context.Context, frameType byte, keys []string) error {
+	if err := validateKeyList(frameType, keys); err != nil {
+		return err
+	}
+
+	keys = slices.Clone(keys)
+	if keys == nil {
+		keys = []string{}
+	}
+
+	c.presenceMu.Lock()
+	if frameType == protocol.TypeClientPresenceWatch {
+		c.watchKeys = keys
+	} else {
+		c.allowKeys = keys
+	}
+	c.presenceMu.Unlock()
+
+	return c.sendControl(ctx, &protocol.KeyList{Type: frameType, Keys: keys})
+}
+
+// resendPresence повторно отправляет списки presence после переподключения.
+func (c *Client) resendPresence() {
+	c.presenceMu.Lock()
+	var frames []controlFrame
+	if c.allowKeys != nil {
+		frames = append(frames, &protocol.KeyList{Type: protocol.TypeClientPresenceAllow, Keys: c.allowKeys})
+	}
+	if c.watchKeys != nil {
+		frames = append(frames, &protocol.KeyList{Type: protocol.TypeClientPresenceWatch, Keys: c.watchKeys})
+	}
+	c.presenceMu.Unlock()
+
+	if len(frames) == 0 {
+		return
+	}
+
+	// Вызывается до запуска writeLoop: не блокируем управляющую горутину
+	go func() {
+		for _, frame := range frames {
+			select {
+			case c.control <- frame:
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// sendControl передаёт служебный кадр пишущей горутине.
+func (c *Client) sendControl(ctx context.Context, frame controlFrame) error {
+	select {
+	case <-c.closing:
+		return ErrClosed
+	case <-c.done:
+		return c.closedErr()
+	default:
+	}
+
+	select {
+	case c.control <- frame:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closing:
+		return ErrClosed
+	case <-c.done:
+		return c.closedErr()
+	}
+}
@@ -0,0 +1,17 @@
+package client
+
+import "time"
+
+// Receipt квитанция о доставке отправленного сообщения получателю.
+// Отправляется клиентом получателя, когда сообщение передано в канал recv,
+// или сервером, если сообщение отброшено (см. Dropped).
+type Receipt struct {
+	MsgID string    // ID доставленного сообщения
+	From  string    // hex-encoded публичный ключ получателя сообщения
+	Time  time.Time // время приёма квитанции сервером
+
+	// Dropped — сообщение не доставлено: сервер отбросил его, потому что
+	// получатель не успевал принимать сообщения. Такую квитанцию отправляет
+	// сервер от имени получателя.
+	Dropped bool
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/transport"
+)
+
+// ConnState состояние соединения клиента.
+type ConnState int
+
+// Состояния соединения.
+const (
+	// StateConnected — соединение установлено, аутентификация пройдена.
+	StateConnected ConnState = iota
+	// StateReconnecting — соединение потеряно, клиент переподключается.
+	StateReconnecting
+	// StateClosed — клиент завершил работу, канал recv закрыт.
+	StateClosed
+)
+
+// String возвращает название состояния.
+func (s ConnState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// runReconnecting обслуживает соединение и переподключается после разрыва.
+// Каналы send и recv живут всё время работы клиента; recv закрывается,
+// когда приложение закрыло send или ошибка не допускает повтора.
+// Отмена ctx прерывает текущую попытку подключения.
+// Возвращает последнюю ошибку или nil, если клиент закрыт приложением.
+func runReconnecting(ctx context.Context, addr string, cfg *connectConfig, tlsConfig *tls.Config, conn transport.Conn, send <-chan OutgoingMessage, control chan controlFrame, recv chan<- *message.Message) error {
+	defer func() {
+		close(recv)
+		cfg.setState(StateClosed)
+	}()
+
+	var pending []OutgoingMessage
+	for {
+		cfg.setState(StateConnected)
+
+		// Служебные кадры, не отправленные до разрыва, уйдут после переподключения
+		res := runSession(conn, cfg, send, control, recv, pending)
+		pending = res.unsent
+		if res.sendClosed {
+			return nil
+		}
+		if !IsRetryable(res.err) {
+			return res.err
+		}
+
+		// Сервер завершает работу и указал, куда переподключаться
+		var goAway *GoAwayError
+		if errors.As(res.err, &goAway) && goAway.Addr != "" {
+			addr = goAway.Addr
+		}
+
+		cfg.setState(StateReconnecting)
+
+		var err error
+		conn, pending, err = reconnect(ctx, addr, cfg, tlsConfig, send, pending)
+		if err != nil {
+			return err
+		}
+		if conn == nil {
+			return nil
+		}
+	}
+}
+
+// reconnect повторяет подключение с экспоненциальной задержкой.
+// Пока соединения нет, сообщения из send накапливаются в pending
+// (не больше cfg.pendingQueueSize, дальше send не читается).
+// Возвращает nil соединение, если приложение закрыло send, и ошибку,
+// если она не допускает повтора; накопленные сообщения в этих случаях
+// отбрасываются.
+func reconnect(ctx context.Context, addr string, cfg *connectConfig, tlsConfig *tls.Config, send <-chan OutgoingMessage, pending []OutgoingMessage) (transport.Conn, []OutgoingMessage, error) {
+	for attempt := 0; ; attempt++ {
+		timer := time.NewTimer(backoffDelay(cfg.reconnectMin, cfg.reconnectMax, attempt))
+
+	wait:
+		for {
+			var in <-chan OutgoingMessage
+			if len(pending) < cfg.pendingQueueSize {
+				in = send
+			}
+
+			select {
+			case <-timer.C:
+				break wait
+			case <-ctx.Done():
+				// Клиент закрывается: при полной очереди закрытие send не видно
+				timer.Stop()
+				return nil, pending, nil
+			case msg, ok := <-in:
+				if !ok {
+					timer.Stop()
+					return nil, pending, nil
+				}
+				pending = append(pending, msg)
+			}
+		}
+
+		conn, err := dial(ctx, addr, cfg, tlsConfig)
+		if err == nil {
+			return conn, pending, nil
+		}
+		if ctx.Err() != nil {
+			// Клиент закрывается — send уже закрыт или будет закрыт
+			return nil, pending, nil
+		}
+
+		handleError(cfg, fmt.Errorf("reconnect attempt %d: %w", attempt+1, err))
+		if !IsRetryable(err) {
+			return nil, pending, err
+		}
+	}
+}
+
+// backoffDelay возвращает задержку перед попыткой attempt (с нуля):
+// minDelay удваивается с каждой попыткой, но не превышает maxDelay.
+// Jitter выбирает случайное значение в [d/2, d), чтобы клиенты после
+// перезапуска сервера не переподключались одновременно.
+func backoffDelay(minDelay, maxDelay time.Duration, attempt int) time.Duration {
+	d := maxDelay
+	if attempt < 32 {
+		if next := minDelay << attempt; next > 0 && next < maxDelay {
+			d = next
+		}
+	}
+
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// setState вызывает обработчик смены состояния, если он задан.
+func (cfg *connectConfig) setState(state ConnState) {
+	if cfg.onStateChange != nil {
+		cfg.onStateChange(state)
+	}
+}
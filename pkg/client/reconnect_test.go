@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	const (
+		minDelay = 100 * time.Millisecond
+		maxDelay = 2 * time.Second
+	)
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{5, maxDelay},
+		{64, maxDelay},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			d := backoffDelay(minDelay, maxDelay, tt.attempt)
+			if d < tt.base/2 || d >= tt.base {
+				t.Fatalf("attempt %d: delay %v not in [%v, %v)", tt.attempt, d, tt.base/2, tt.base)
+			}
+		}
+	}
+}
+
+func TestBackoffDelayZero(t *testing.T) {
+	if d := backoffDelay(0, 0, 3); d != 0 {
+		t.Errorf("delay: got %v, want 0", d)
+	}
+}
+
+func TestReconnectClosedDuringBackoff(t *testing.T) {
+	cfg := &connectConfig{
+		reconnectMin:     time.Hour,
+		reconnectMax:     time.Hour,
+		pendingQueueSize: 2,
+	}
+	// Очередь заполнена — send не читается, закрытие клиента видно только по ctx
+	pending := []OutgoingMessage{{MsgID: "1"}, {MsgID: "2"}}
+	send := make(chan OutgoingMessage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type result struct {
+		connected bool
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, _, err := reconnect(ctx, "127.0.0.1:1", cfg, nil, send, pending)
+		done <- result{conn != nil, err}
+	}()
+
+	// Как Client.Close: закрывает send и отменяет ctx
+	time.Sleep(10 * time.Millisecond)
+	close(send)
+	cancel()
+
+	select {
+	case res := <-done:
+		if res.connected || res.err != nil {
+			t.Errorf("reconnect: connected=%v, err=%v; want no connection and nil error", res.connected, res.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect did not return after close during backoff")
+	}
+}
@@ -2,10 +2,18 @@
 package config
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/listenfd"
+	"github.com/udisondev/sprut/pkg/protocol"
 )
 
 // Config конфигурация сервера.
@@ -13,12 +21,26 @@ type Config struct {
 	Server ServerConfig `yaml:"server"`
 	TLS    TLSConfig    `yaml:"tls"`
 	NATS   NATSConfig   `yaml:"nats"`
+	Auth   AuthConfig   `yaml:"auth"`
+	Policy PolicyConfig `yaml:"policy"`
 	Limits LimitsConfig `yaml:"limits"`
+	HTTP   HTTPConfig   `yaml:"http"`
+	Admin  AdminConfig  `yaml:"admin"`
+	Bans   BansConfig   `yaml:"bans"`
 	Log    LogConfig    `yaml:"log"`
 
 	// Ready закрывается когда сервер полностью готов к приёму соединений.
-	// Опциональное поле, используется для тестов.
+	// Опциональное поле: используется в тестах и при обновлении без простоя.
 	Ready chan struct{} `yaml:"-"`
+
+	// Reload передаёт роутеру конфигурацию, перечитанную по SIGHUP.
+	// Опциональное поле: без него конфигурация не перечитывается.
+	Reload <-chan *Config `yaml:"-"`
+
+	// Sockets — слушающие сокеты, полученные от systemd или предыдущего
+	// процесса. Роутер берёт из него сокеты всех listener и запоминает их
+	// для передачи новому процессу. Опциональное поле: без него сокеты создаются заново.
+	Sockets *listenfd.Set `yaml:"-"`
 }
 
 // ServerConfig конфигурация TCP сервера.
@@ -26,6 +48,106 @@ type ServerConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	ServerID string `yaml:"server_id"`
+
+	// DrainTimeout — сколько при завершении ждать, пока клиенты получат
+	// очередь и GoAway и отключатся сами; 0 = закрыть соединения сразу.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// DrainAddr — адрес сервера, к которому клиентам переподключаться (опционально).
+	DrainAddr string `yaml:"drain_addr"`
+
+	// ProxyProtocol — соединения на host:port приходят от балансировщика
+	// с заголовком PROXY protocol v1 или v2; адрес клиента берётся из заголовка.
+	// Соединения без заголовка отклоняются.
+	ProxyProtocol bool `yaml:"proxy_protocol"`
+
+	// WebSocket — дополнительный listener для браузерных клиентов.
+	WebSocket WebSocketConfig `yaml:"websocket"`
+
+	// QUIC — дополнительный listener для мобильных клиентов.
+	QUIC QUICConfig `yaml:"quic"`
+
+	// Listeners — дополнительные TCP и Unix listener, например внутренний порт
+	// для доверенных ботов со своими лимитами. Основной listener — host:port.
+	Listeners []ListenerConfig `yaml:"listeners"`
+}
+
+// Сети дополнительных listener.
+const (
+	NetworkTCP  = "tcp"
+	NetworkUnix = "unix"
+)
+
+// ListenerConfig конфигурация дополнительного listener. Протокол тот же,
+// что и на основном порту; общие реестр клиентов и лимит соединений.
+type ListenerConfig struct {
+	Name    string `yaml:"name"`    // имя в логах; пустое = addr
+	Network string `yaml:"network"` // tcp или unix; пустой = tcp
+	Addr    string `yaml:"addr"`    // host:port для tcp, путь сокета для unix
+
+	// ProxyProtocol — соединения приходят от балансировщика с заголовком
+	// PROXY protocol (см. ServerConfig.ProxyProtocol).
+	ProxyProtocol bool `yaml:"proxy_protocol"`
+
+	// TLS — свой сертификат listener; nil = общий tls.
+	TLS *TLSConfig `yaml:"tls"`
+	// Limits — лимиты клиентов этого listener вместо общих.
+	Limits ListenerLimits `yaml:"limits"`
+}
+
+// ListenerLimits — лимиты клиентов listener, заменяющие значения из limits.
+// Нулевое значение поля — общее значение из limits.
+type ListenerLimits struct {
+	RateLimitPerSec float64       `yaml:"rate_limit_per_sec"`
+	RateLimitBurst  int           `yaml:"rate_limit_burst"`
+	RateLimitMode   string        `yaml:"rate_limit_mode"`
+	OverflowPolicy  string        `yaml:"overflow_policy"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+}
+
+// Apply возвращает limits с заменёнными значениями listener.
+func (l ListenerLimits) Apply(limits LimitsConfig) LimitsConfig {
+	if l.RateLimitPerSec > 0 {
+		limits.RateLimitPerSec = l.RateLimitPerSec
+	}
+	if l.RateLimitBurst > 0 {
+		limits.RateLimitBurst = l.RateLimitBurst
+	}
+	if l.RateLimitMode != "" {
+		limits.RateLimitMode = l.RateLimitMode
+	}
+	if l.OverflowPolicy != "" {
+		limits.OverflowPolicy = l.OverflowPolicy
+	}
+	if l.IdleTimeout > 0 {
+		limits.IdleTimeout = l.IdleTimeout
+	}
+	return limits
+}
+
+// DisplayName возвращает имя listener для логов.
+func (c ListenerConfig) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Addr
+}
+
+// WebSocketConfig конфигурация WebSocket listener: то же рукопожатие и те же
+// кадры, что и на TCP порту, в бинарных сообщениях WebSocket поверх HTTPS.
+// Используются TLS сертификат, лимиты и реестр клиентов основного listener.
+type WebSocketConfig struct {
+	Addr string `yaml:"addr"` // host:port; пустой = listener не запускается
+	Path string `yaml:"path"` // путь запроса upgrade; пустой = "/"
+	// AllowedOrigins — шаблоны Origin (path.Match) для страниц с другого хоста;
+	// запросы с хоста самого сервера разрешены всегда.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// QUICConfig конфигурация QUIC listener: то же рукопожатие, что и на TCP порту,
+// по первому потоку соединения, каждое сообщение — в отдельном потоке.
+// Используются TLS сертификат, лимиты и реестр клиентов основного listener.
+type QUICConfig struct {
+	Addr string `yaml:"addr"` // UDP host:port; пустой = listener не запускается
 }
 
 // Addr возвращает адрес сервера в формате host:port.
@@ -38,16 +160,84 @@ type TLSConfig struct {
 	CertFile   string `yaml:"cert_file"`
 	KeyFile    string `yaml:"key_file"`
 	MinVersion string `yaml:"min_version"`
+
+	// ReloadInterval — как часто проверять файлы сертификата на изменение;
+	// 0 = только по SIGHUP.
+	ReloadInterval time.Duration `yaml:"reload_interval"`
 }
 
 // NATSConfig конфигурация NATS.
 type NATSConfig struct {
-	URLs          []string      `yaml:"urls"`
-	ReconnectWait time.Duration `yaml:"reconnect_wait"`
-	MaxReconnects int           `yaml:"max_reconnects"`
+	URLs          []string        `yaml:"urls"`
+	ReconnectWait time.Duration   `yaml:"reconnect_wait"`
+	MaxReconnects int             `yaml:"max_reconnects"`
+	JetStream     JetStreamConfig `yaml:"jetstream"`
+}
+
+// JetStreamConfig конфигурация offline-доставки через NATS JetStream.
+// Лимиты применяются к каждому получателю отдельно. Без JetStream недоступны
+// также группы, блок-листы и контакты.
+type JetStreamConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	Stream               string        `yaml:"stream"`
+	MaxMsgsPerRecipient  int64         `yaml:"max_msgs_per_recipient"`  // 0 = без ограничения
+	MaxBytesPerRecipient int64         `yaml:"max_bytes_per_recipient"` // 0 = без ограничения
+	MaxAge               time.Duration `yaml:"max_age"`
+	Replicas             int           `yaml:"replicas"`
+	GroupsBucket         string        `yaml:"groups_bucket"` // KV bucket состава групп
+	PolicyBucket         string        `yaml:"policy_bucket"` // KV bucket блок-листов и контактов
+	BansBucket           string        `yaml:"bans_bucket"`   // KV bucket блокировок (bans.store: nats)
+}
+
+// Способы аутентификации клиентов.
+const (
+	AuthMethodEd25519 = "ed25519" // challenge/response ключом клиента
+	AuthMethodJWT     = "jwt"     // JWT, подписанный доверенным издателем
+	AuthMethodMTLS    = "mtls"    // клиентский TLS сертификат
+)
+
+// AuthConfig конфигурация аутентификации.
+// Пустой Methods означает только ed25519.
+type AuthConfig struct {
+	Methods []string       `yaml:"methods"`
+	JWT     JWTAuthConfig  `yaml:"jwt"`
+	MTLS    MTLSAuthConfig `yaml:"mtls"`
+}
+
+// Enabled сообщает, включён ли способ аутентификации.
+func (c AuthConfig) Enabled(method string) bool {
+	if len(c.Methods) == 0 {
+		return method == AuthMethodEd25519
+	}
+	return slices.Contains(c.Methods, method)
+}
+
+// JWTAuthConfig конфигурация аутентификации по JWT (alg EdDSA).
+// PeerID клиента берётся из claim sub.
+type JWTAuthConfig struct {
+	PublicKeys []string `yaml:"public_keys"` // hex ed25519 ключи издателей
+	Issuer     string   `yaml:"issuer"`      // пустой = не проверяется
+	Audience   string   `yaml:"audience"`    // пустой = не проверяется
+}
+
+// MTLSAuthConfig конфигурация аутентификации по клиентскому сертификату.
+type MTLSAuthConfig struct {
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// PolicyConfig конфигурация авторизации: кто кому может писать.
+// Включённые проверки применяются вместе: сообщение доставляется,
+// только если его разрешают все. Квитанции о доставке проверяются так же,
+// кроме квитанции на сообщение, доставленное клиенту: она всегда доходит до автора.
+type PolicyConfig struct {
+	AllowlistFile string `yaml:"allowlist_file"` // пары "from to", * — любой ключ; пустой = без ограничений
+	Blocklists    bool   `yaml:"blocklists"`     // учитывать блок-листы получателей (требует JetStream)
+	ContactsOnly  bool   `yaml:"contacts_only"`  // доставлять только от контактов получателя (требует JetStream)
 }
 
 // LimitsConfig конфигурация лимитов.
+// RateLimitPerSec и RateLimitBurst ограничивают сообщения и кадры групп клиента:
+// квитанции на доставленные ему сообщения, ping и списки ключей не учитываются.
 type LimitsConfig struct {
 	MaxConnections  int           `yaml:"max_connections"`
 	MaxMessageSize  int           `yaml:"max_message_size"`
@@ -55,6 +245,80 @@ type LimitsConfig struct {
 	RateLimitBurst  int           `yaml:"rate_limit_burst"`
 	AuthTimeout     time.Duration `yaml:"auth_timeout"`
 	ChallengeTTL    time.Duration `yaml:"challenge_ttl"`
+	// IdleTimeout — сколько сервер ждёт кадра от клиента, прежде чем считать
+	// соединение мёртвым; 0 = без ограничения. Клиент с keepalive шлёт ping чаще.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// OverflowPolicy — что делать с сообщением, когда очередь записи клиента заполнена
+	// (см. Overflow*); пустой = disconnect. Служебные кадры (ack, presence, GoAway,
+	// pong) не отбрасываются: при переполнении их очереди клиент отключается.
+	OverflowPolicy string `yaml:"overflow_policy"`
+	// RateLimitMode — что делать, когда клиент превысил rate_limit_per_sec
+	// (см. RateLimitMode*); пустой = disconnect.
+	RateLimitMode string `yaml:"rate_limit_mode"`
+
+	// Лимиты на источник соединений, проверяются до TLS рукопожатия.
+	// Подсеть — /24 для IPv4 и /64 для IPv6; 0 = без ограничения.
+	MaxConnsPerIP           int      `yaml:"max_conns_per_ip"`
+	MaxConnsPerSubnet       int      `yaml:"max_conns_per_subnet"`
+	HandshakeRatePerIP      float64  `yaml:"handshake_rate_per_ip"` // новых соединений в секунду
+	HandshakeBurstPerIP     int      `yaml:"handshake_burst_per_ip"`
+	HandshakeRatePerSubnet  float64  `yaml:"handshake_rate_per_subnet"`
+	HandshakeBurstPerSubnet int      `yaml:"handshake_burst_per_subnet"`
+	TrustedProxies          []string `yaml:"trusted_proxies"` // адреса и CIDR, на которые лимиты на источник не действуют
+}
+
+// Политики переполнения очереди записи клиента.
+const (
+	OverflowDisconnect = "disconnect"  // отключить клиента с ErrorCodeSlowConsumer
+	OverflowDropOldest = "drop_oldest" // вытеснить самое старое сообщение очереди
+	OverflowDropNewest = "drop_newest" // отбросить новое сообщение
+	OverflowSpill      = "spill"       // оставить сообщение в JetStream и доставить позже (требует JetStream)
+)
+
+// Режимы ограничения частоты сообщений от клиента.
+const (
+	RateLimitModeDisconnect = "disconnect" // отключить клиента с ErrorCodeRateLimited
+	RateLimitModeThrottle   = "throttle"   // не читать следующий кадр, пока лимит его не разрешит
+)
+
+// HTTPConfig конфигурация служебного HTTP сервера:
+// метрики Prometheus на /metrics, проверки оркестратора на /healthz и /readyz.
+type HTTPConfig struct {
+	Addr string `yaml:"addr"` // host:port; пустой = сервер не запускается
+}
+
+// AdminConfig конфигурация admin API (список клиентов, отключение, блокировка ключей).
+// Запросы авторизуются заголовком "Authorization: Bearer <token>".
+type AdminConfig struct {
+	Addr      string `yaml:"addr"`       // host:port или unix:/path; пустой = API выключен
+	TokenFile string `yaml:"token_file"` // файл с токеном доступа
+}
+
+// Хранилища блокировок.
+const (
+	BanStoreMemory = "memory" // только в памяти, до перезапуска
+	BanStoreFile   = "file"   // JSON файл на этом сервере
+	BanStoreNATS   = "nats"   // NATS KV, общий для всех серверов (требует JetStream)
+)
+
+// BansConfig конфигурация блокировок ключей и диапазонов адресов.
+// Заблокированный адрес отключается до аутентификации, ключ — сразу после неё.
+type BansConfig struct {
+	Store string        `yaml:"store"` // memory (по умолчанию), file или nats
+	File  string        `yaml:"file"`  // путь к файлу для store: file
+	Auto  AutoBanConfig `yaml:"auto"`
+}
+
+// AutoBanConfig конфигурация автоматических временных блокировок.
+// Порог считается за окно Window; 0 = проверка выключена. Неудачной
+// аутентификацией считаются неверная подпись, повтор и отказ (не таймаут
+// и не истёкший токен); адреса limits.trusted_proxies не блокируются.
+type AutoBanConfig struct {
+	AuthFailures         int           `yaml:"auth_failures"`          // неудачных аутентификаций с адреса
+	RateLimitDisconnects int           `yaml:"rate_limit_disconnects"` // отключений за rate limit адреса или ключа
+	Window               time.Duration `yaml:"window"`
+	Duration             time.Duration `yaml:"duration"` // срок блокировки
 }
 
 // LogConfig конфигурация логирования.
@@ -75,6 +339,27 @@ func (c *Config) Validate() error {
 	if c.Server.ServerID == "" {
 		errs = append(errs, fmt.Errorf("server_id is required"))
 	}
+	if c.Server.DrainTimeout < 0 {
+		errs = append(errs, fmt.Errorf("server.drain_timeout must not be negative"))
+	}
+	if len(c.Server.DrainAddr) > protocol.MaxGoAwayAddr {
+		errs = append(errs, fmt.Errorf("server.drain_addr too long: %d > %d", len(c.Server.DrainAddr), protocol.MaxGoAwayAddr))
+	}
+	if ws := c.Server.WebSocket; ws.Addr != "" {
+		if _, _, err := net.SplitHostPort(ws.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("server.websocket.addr: %w", err))
+		}
+		if ws.Path != "" && !strings.HasPrefix(ws.Path, "/") {
+			errs = append(errs, fmt.Errorf("server.websocket.path must start with /: %q", ws.Path))
+		}
+	}
+	if addr := c.Server.QUIC.Addr; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errs = append(errs, fmt.Errorf("server.quic.addr: %w", err))
+		}
+	}
+
+	errs = append(errs, c.validateListeners()...)
 
 	// TLS
 	if c.TLS.CertFile == "" {
@@ -87,11 +372,76 @@ func (c *Config) Validate() error {
 	} else if _, err := os.Stat(c.TLS.KeyFile); err != nil {
 		errs = append(errs, fmt.Errorf("tls.key_file: %w", err))
 	}
+	if c.TLS.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("tls.reload_interval must not be negative"))
+	}
 
 	// NATS
 	if len(c.NATS.URLs) == 0 {
 		errs = append(errs, fmt.Errorf("nats.urls is required"))
 	}
+	if js := c.NATS.JetStream; js.Enabled {
+		if js.Stream == "" {
+			errs = append(errs, fmt.Errorf("nats.jetstream.stream is required"))
+		}
+		if js.MaxMsgsPerRecipient < 0 {
+			errs = append(errs, fmt.Errorf("nats.jetstream.max_msgs_per_recipient must not be negative"))
+		}
+		if js.MaxBytesPerRecipient < 0 {
+			errs = append(errs, fmt.Errorf("nats.jetstream.max_bytes_per_recipient must not be negative"))
+		}
+		if js.MaxAge <= 0 {
+			errs = append(errs, fmt.Errorf("nats.jetstream.max_age must be positive"))
+		}
+		if js.Replicas < 1 {
+			errs = append(errs, fmt.Errorf("nats.jetstream.replicas must be positive"))
+		}
+		if js.GroupsBucket == "" {
+			errs = append(errs, fmt.Errorf("nats.jetstream.groups_bucket is required"))
+		}
+		if js.PolicyBucket == "" {
+			errs = append(errs, fmt.Errorf("nats.jetstream.policy_bucket is required"))
+		}
+		if c.Bans.Store == BanStoreNATS && js.BansBucket == "" {
+			errs = append(errs, fmt.Errorf("nats.jetstream.bans_bucket is required"))
+		}
+	}
+
+	// Auth
+	for _, method := range c.Auth.Methods {
+		switch method {
+		case AuthMethodEd25519, AuthMethodJWT, AuthMethodMTLS:
+		default:
+			errs = append(errs, fmt.Errorf("auth.methods: unknown method %q", method))
+		}
+	}
+	if c.Auth.Enabled(AuthMethodJWT) {
+		if len(c.Auth.JWT.PublicKeys) == 0 {
+			errs = append(errs, fmt.Errorf("auth.jwt.public_keys is required"))
+		}
+		for _, key := range c.Auth.JWT.PublicKeys {
+			if b, err := hex.DecodeString(key); err != nil || len(b) != 32 {
+				errs = append(errs, fmt.Errorf("auth.jwt.public_keys: invalid ed25519 key %q", key))
+			}
+		}
+	}
+	if c.Auth.Enabled(AuthMethodMTLS) {
+		if c.Auth.MTLS.ClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("auth.mtls.client_ca_file is required"))
+		} else if _, err := os.Stat(c.Auth.MTLS.ClientCAFile); err != nil {
+			errs = append(errs, fmt.Errorf("auth.mtls.client_ca_file: %w", err))
+		}
+	}
+
+	// Policy
+	if c.Policy.AllowlistFile != "" {
+		if _, err := os.Stat(c.Policy.AllowlistFile); err != nil {
+			errs = append(errs, fmt.Errorf("policy.allowlist_file: %w", err))
+		}
+	}
+	if (c.Policy.Blocklists || c.Policy.ContactsOnly) && !c.NATS.JetStream.Enabled {
+		errs = append(errs, fmt.Errorf("policy.blocklists and policy.contacts_only require nats.jetstream.enabled"))
+	}
 
 	// Limits
 	if c.Limits.MaxConnections < 1 {
@@ -106,10 +456,159 @@ func (c *Config) Validate() error {
 	if c.Limits.ChallengeTTL <= 0 {
 		errs = append(errs, fmt.Errorf("limits.challenge_ttl must be positive"))
 	}
+	if c.Limits.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("limits.idle_timeout must not be negative"))
+	}
+	if err := c.validateOverflowPolicy(c.Limits.OverflowPolicy); err != nil {
+		errs = append(errs, fmt.Errorf("limits.overflow_policy: %w", err))
+	}
+	if err := validateRateLimitMode(c.Limits.RateLimitMode); err != nil {
+		errs = append(errs, fmt.Errorf("limits.rate_limit_mode: %w", err))
+	}
+	if c.Limits.MaxConnsPerIP < 0 || c.Limits.MaxConnsPerSubnet < 0 {
+		errs = append(errs, fmt.Errorf("limits.max_conns_per_ip and limits.max_conns_per_subnet must not be negative"))
+	}
+	if c.Limits.HandshakeRatePerIP < 0 || c.Limits.HandshakeRatePerSubnet < 0 {
+		errs = append(errs, fmt.Errorf("limits handshake rates must not be negative"))
+	}
+	if c.Limits.HandshakeRatePerIP > 0 && c.Limits.HandshakeBurstPerIP < 1 {
+		errs = append(errs, fmt.Errorf("limits.handshake_burst_per_ip must be positive"))
+	}
+	if c.Limits.HandshakeRatePerSubnet > 0 && c.Limits.HandshakeBurstPerSubnet < 1 {
+		errs = append(errs, fmt.Errorf("limits.handshake_burst_per_subnet must be positive"))
+	}
+	for _, proxy := range c.Limits.TrustedProxies {
+		if _, ok := admin.ParseCIDR(proxy); !ok {
+			errs = append(errs, fmt.Errorf("limits.trusted_proxies: invalid address or CIDR %q", proxy))
+		}
+	}
+
+	// HTTP
+	if c.HTTP.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("http.addr: %w", err))
+		}
+	}
+
+	// Admin
+	if c.Admin.Addr != "" {
+		if !strings.HasPrefix(c.Admin.Addr, admin.UnixPrefix) {
+			if _, _, err := net.SplitHostPort(c.Admin.Addr); err != nil {
+				errs = append(errs, fmt.Errorf("admin.addr: %w", err))
+			}
+		}
+		if c.Admin.TokenFile == "" {
+			errs = append(errs, fmt.Errorf("admin.token_file is required"))
+		} else if _, err := os.Stat(c.Admin.TokenFile); err != nil {
+			errs = append(errs, fmt.Errorf("admin.token_file: %w", err))
+		}
+	}
+
+	// Bans
+	switch c.Bans.Store {
+	case "", BanStoreMemory:
+	case BanStoreFile:
+		if c.Bans.File == "" {
+			errs = append(errs, fmt.Errorf("bans.file is required for store %q", BanStoreFile))
+		}
+	case BanStoreNATS:
+		if !c.NATS.JetStream.Enabled {
+			errs = append(errs, fmt.Errorf("bans.store %q requires nats.jetstream.enabled", BanStoreNATS))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("bans.store: unknown store %q", c.Bans.Store))
+	}
+	if auto := c.Bans.Auto; auto.AuthFailures < 0 || auto.RateLimitDisconnects < 0 {
+		errs = append(errs, fmt.Errorf("bans.auto thresholds must not be negative"))
+	} else if auto.AuthFailures > 0 || auto.RateLimitDisconnects > 0 {
+		if auto.Window <= 0 {
+			errs = append(errs, fmt.Errorf("bans.auto.window must be positive"))
+		}
+		if auto.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("bans.auto.duration must be positive"))
+		}
+	}
 
 	return errors.Join(errs...)
 }
 
+// validateListeners проверяет server.listeners.
+func (c *Config) validateListeners() []error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, l := range c.Server.Listeners {
+		field := fmt.Sprintf("server.listeners[%d]", i)
+
+		name := l.DisplayName()
+		if names[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate listener %q", field, name))
+		}
+		names[name] = true
+
+		switch l.Network {
+		case "", NetworkTCP:
+			if _, _, err := net.SplitHostPort(l.Addr); err != nil {
+				errs = append(errs, fmt.Errorf("%s.addr: %w", field, err))
+			}
+		case NetworkUnix:
+			if l.Addr == "" {
+				errs = append(errs, fmt.Errorf("%s.addr is required", field))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s.network: unknown network %q", field, l.Network))
+		}
+
+		if l.TLS != nil {
+			if l.TLS.CertFile == "" || l.TLS.KeyFile == "" {
+				errs = append(errs, fmt.Errorf("%s.tls: cert_file and key_file are required", field))
+			}
+			for _, file := range []string{l.TLS.CertFile, l.TLS.KeyFile} {
+				if _, err := os.Stat(file); file != "" && err != nil {
+					errs = append(errs, fmt.Errorf("%s.tls: %w", field, err))
+				}
+			}
+			if l.TLS.ReloadInterval < 0 {
+				errs = append(errs, fmt.Errorf("%s.tls.reload_interval must not be negative", field))
+			}
+		}
+
+		if l.Limits.RateLimitPerSec < 0 || l.Limits.RateLimitBurst < 0 || l.Limits.IdleTimeout < 0 {
+			errs = append(errs, fmt.Errorf("%s.limits must not be negative", field))
+		}
+		if err := c.validateOverflowPolicy(l.Limits.OverflowPolicy); err != nil {
+			errs = append(errs, fmt.Errorf("%s.limits.overflow_policy: %w", field, err))
+		}
+		if err := validateRateLimitMode(l.Limits.RateLimitMode); err != nil {
+			errs = append(errs, fmt.Errorf("%s.limits.rate_limit_mode: %w", field, err))
+		}
+	}
+	return errs
+}
+
+// validateOverflowPolicy проверяет политику переполнения очереди записи.
+func (c *Config) validateOverflowPolicy(policy string) error {
+	switch policy {
+	case "", OverflowDisconnect, OverflowDropOldest, OverflowDropNewest:
+	case OverflowSpill:
+		if !c.NATS.JetStream.Enabled {
+			return fmt.Errorf("%q requires nats.jetstream.enabled", OverflowSpill)
+		}
+	default:
+		return fmt.Errorf("unknown policy %q", policy)
+	}
+	return nil
+}
+
+// validateRateLimitMode проверяет режим ограничения частоты сообщений.
+func validateRateLimitMode(mode string) error {
+	switch mode {
+	case "", RateLimitModeDisconnect, RateLimitModeThrottle:
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+}
+
 // Default возвращает конфигурацию по умолчанию.
 func Default() *Config {
 	return &Config{
@@ -117,14 +616,31 @@ func Default() *Config {
 			Host:     "0.0.0.0",
 			Port:     8443,
 			ServerID: "goro-1",
+
+			DrainTimeout: 30 * time.Second,
 		},
 		TLS: TLSConfig{
-			MinVersion: "1.3",
+			MinVersion:     "1.3",
+			ReloadInterval: time.Minute,
 		},
 		NATS: NATSConfig{
 			URLs:          []string{"nats://localhost:4222"},
 			ReconnectWait: 2 * time.Second,
 			MaxReconnects: -1,
+			JetStream: JetStreamConfig{
+				Enabled:              false,
+				Stream:               "SPRUT_MSG",
+				MaxMsgsPerRecipient:  1000,
+				MaxBytesPerRecipient: 10 << 20,
+				MaxAge:               7 * 24 * time.Hour,
+				Replicas:             1,
+				GroupsBucket:         "SPRUT_GROUPS",
+				PolicyBucket:         "SPRUT_POLICY",
+				BansBucket:           "SPRUT_BANS",
+			},
+		},
+		Auth: AuthConfig{
+			Methods: []string{AuthMethodEd25519},
 		},
 		Limits: LimitsConfig{
 			MaxConnections:  10000,
@@ -133,6 +649,25 @@ func Default() *Config {
 			RateLimitBurst:  10,
 			AuthTimeout:     10 * time.Second,
 			ChallengeTTL:    60 * time.Second,
+			IdleTimeout:     90 * time.Second,
+			OverflowPolicy:  OverflowDisconnect,
+			RateLimitMode:   RateLimitModeDisconnect,
+
+			MaxConnsPerIP:           100,
+			MaxConnsPerSubnet:       1000,
+			HandshakeRatePerIP:      10,
+			HandshakeBurstPerIP:     20,
+			HandshakeRatePerSubnet:  50,
+			HandshakeBurstPerSubnet: 100,
+		},
+		Bans: BansConfig{
+			Store: BanStoreMemory,
+			Auto: AutoBanConfig{
+				AuthFailures:         20,
+				RateLimitDisconnects: 5,
+				Window:               10 * time.Minute,
+				Duration:             time.Hour,
+			},
 		},
 		Log: LogConfig{
 			Level:  "debug",
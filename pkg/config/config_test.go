@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateListeners(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Listeners = []ListenerConfig{
+		{Name: "bots", Network: NetworkUnix, Addr: "/run/sprut.sock"},
+		{Name: "bots", Addr: "127.0.0.1:9443"},
+		{Network: "udp", Addr: "127.0.0.1:9444"},
+		{Addr: "no-port"},
+		{Addr: "127.0.0.1:9445", Limits: ListenerLimits{RateLimitMode: "slow", OverflowPolicy: OverflowSpill}},
+	}
+
+	var errs []string
+	for _, err := range cfg.validateListeners() {
+		errs = append(errs, err.Error())
+	}
+	got := strings.Join(errs, "\n")
+
+	for _, want := range []string{
+		`server.listeners[1]: duplicate listener "bots"`,
+		`server.listeners[2].network: unknown network "udp"`,
+		`server.listeners[3].addr`,
+		`server.listeners[4].limits.overflow_policy: "spill" requires nats.jetstream.enabled`,
+		`server.listeners[4].limits.rate_limit_mode: unknown mode "slow"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing error %q in:\n%s", want, got)
+		}
+	}
+	if len(errs) != 5 {
+		t.Errorf("got %d errors, want 5:\n%s", len(errs), got)
+	}
+}
+
+func TestListenerLimitsApply(t *testing.T) {
+	limits := Default().Limits
+	got := ListenerLimits{RateLimitBurst: 500, OverflowPolicy: OverflowDropOldest}.Apply(limits)
+
+	if got.RateLimitBurst != 500 || got.OverflowPolicy != OverflowDropOldest {
+		t.Errorf("overrides not applied: burst = %d, overflow = %q", got.RateLimitBurst, got.OverflowPolicy)
+	}
+	if got.RateLimitPerSec != limits.RateLimitPerSec || got.MaxConnections != limits.MaxConnections {
+		t.Error("values not set by listener changed")
+	}
+}
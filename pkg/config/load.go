@@ -49,4 +49,9 @@ func resolvePaths(cfg *Config) {
 	if cfg.Log.File == "" {
 		cfg.Log.File = appdir.LogFilePath()
 	}
+
+	// Файл блокировок
+	if cfg.Bans.Store == BanStoreFile && cfg.Bans.File == "" {
+		cfg.Bans.File = appdir.BansPath()
+	}
 }
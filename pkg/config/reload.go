@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable — поля (yaml пути), которые применяются без перезапуска.
+var reloadable = map[string]bool{
+	"limits": true,
+}
+
+// NonReloadableChanges возвращает yaml пути полей, которые в next отличаются
+// от c, но применяются только при перезапуске (например, server.port).
+// Перезагружаются limits; сертификат перечитывается из прежних tls.cert_file и tls.key_file.
+func (c *Config) NonReloadableChanges(next *Config) []string {
+	var changed []string
+	diffFields(reflect.ValueOf(*c), reflect.ValueOf(*next), "", &changed)
+	return changed
+}
+
+// diffFields добавляет в changed пути различающихся полей структур a и b.
+func diffFields(a, b reflect.Value, prefix string, changed *[]string) {
+	t := a.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		path := prefix + name
+		if reloadable[path] {
+			continue
+		}
+
+		fa, fb := a.Field(i), b.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			diffFields(fa, fb, path+".", changed)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			*changed = append(*changed, path)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNonReloadableChanges(t *testing.T) {
+	cur := Default()
+
+	next := Default()
+	next.Limits.RateLimitPerSec = 500
+	next.Limits.MaxConnections = 10
+	next.Ready = make(chan struct{})
+	if got := cur.NonReloadableChanges(next); len(got) != 0 {
+		t.Errorf("limits only: got %v, want none", got)
+	}
+
+	next.Server.Port = 9443
+	next.TLS.ReloadInterval = time.Hour
+	next.NATS.URLs = []string{"nats://other:4222"}
+	want := []string{"server.port", "tls.reload_interval", "nats.urls"}
+	if got := cur.NonReloadableChanges(next); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+// Package group реализует подписанные изменения состава групп.
+//
+// Изменение (GroupChange) подписывает его автор, сервер проверяет подпись
+// и права автора, применяет изменение и рассылает подписанный GroupControl
+// участникам, чтобы они могли проверить его независимо от сервера.
+package group
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+// SignatureDomain — префикс подписываемых данных, отделяющий подписи
+// изменений группы от других подписей того же ключа.
+const SignatureDomain = "goro-group-v1"
+
+// Ограничения группы.
+const (
+	IDSize     = 32
+	MaxMembers = 256
+)
+
+var (
+	// ErrInvalidSignature — подпись изменения не прошла проверку.
+	ErrInvalidSignature = errors.New("invalid group signature")
+
+	// ErrInvalidChange — изменение сформировано некорректно.
+	ErrInvalidChange = errors.New("invalid group change")
+
+	// ErrGroupExists — группа с таким id уже существует.
+	ErrGroupExists = errors.New("group already exists")
+
+	// ErrNotOwner — операция доступна только владельцу группы.
+	ErrNotOwner = errors.New("not group owner")
+
+	// ErrNotMember — автор изменения не состоит в группе.
+	ErrNotMember = errors.New("not group member")
+
+	// ErrTooManyMembers — превышен MaxMembers.
+	ErrTooManyMembers = errors.New("too many group members")
+)
+
+// NewID генерирует случайный id группы.
+func NewID() (string, error) {
+	var id [IDSize]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("generate group id: %w", err)
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// Sign подписывает изменение ключом автора.
+func Sign(priv ed25519.PrivateKey, change *message.GroupChange) (*message.GroupControl, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(change)
+	if err != nil {
+		return nil, fmt.Errorf("marshal group change: %w", err)
+	}
+	return &message.GroupControl{
+		Change:    data,
+		Signature: ed25519.Sign(priv, signedData(data)),
+	}, nil
+}
+
+// Verify проверяет подпись изменения ключом, указанным в GroupChange.Actor,
+// и возвращает изменение.
+func Verify(ctl *message.GroupControl) (*message.GroupChange, error) {
+	change := &message.GroupChange{}
+	if err := proto.Unmarshal(ctl.Change, change); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal: %w", ErrInvalidChange, err)
+	}
+
+	actor, err := hex.DecodeString(change.Actor)
+	if err != nil || len(actor) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid actor", ErrInvalidChange)
+	}
+	if !ed25519.Verify(actor, signedData(ctl.Change), ctl.Signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return change, nil
+}
+
+// Apply применяет проверенное изменение к группе и возвращает новый состав.
+// Для GROUP_OP_CREATE g должен быть nil. Исходная группа не изменяется.
+func Apply(g *message.Group, change *message.GroupChange) (*message.Group, error) {
+	if !isValidKey(change.GroupId) {
+		return nil, fmt.Errorf("%w: invalid group id", ErrInvalidChange)
+	}
+	for _, member := range change.Members {
+		if !isValidKey(member) {
+			return nil, fmt.Errorf("%w: invalid member %q", ErrInvalidChange, member)
+		}
+	}
+
+	if change.Op == message.GroupOp_GROUP_OP_CREATE {
+		if g != nil {
+			return nil, ErrGroupExists
+		}
+		next := &message.Group{
+			Id:      change.GroupId,
+			Owner:   change.Actor,
+			Members: []string{change.Actor},
+			Version: 1,
+		}
+		if err := addMembers(next, change.Members); err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+
+	if g == nil || g.Id != change.GroupId {
+		return nil, fmt.Errorf("%w: group mismatch", ErrInvalidChange)
+	}
+
+	next := proto.Clone(g).(*message.Group)
+	next.Version++
+
+	switch change.Op {
+	case message.GroupOp_GROUP_OP_ADD:
+		if change.Actor != g.Owner {
+			return nil, ErrNotOwner
+		}
+		if err := addMembers(next, change.Members); err != nil {
+			return nil, err
+		}
+		return next, nil
+
+	case message.GroupOp_GROUP_OP_REMOVE:
+		if change.Actor != g.Owner {
+			return nil, ErrNotOwner
+		}
+		if slices.Contains(change.Members, g.Owner) {
+			return nil, fmt.Errorf("%w: owner cannot be removed", ErrInvalidChange)
+		}
+		next.Members = slices.DeleteFunc(next.Members, func(m string) bool {
+			return slices.Contains(change.Members, m)
+		})
+		return next, nil
+
+	case message.GroupOp_GROUP_OP_LEAVE:
+		if change.Actor == g.Owner {
+			return nil, fmt.Errorf("%w: owner cannot leave", ErrInvalidChange)
+		}
+		if !IsMember(g, change.Actor) {
+			return nil, ErrNotMember
+		}
+		next.Members = slices.DeleteFunc(next.Members, func(m string) bool {
+			return m == change.Actor
+		})
+		return next, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %d", ErrInvalidChange, change.Op)
+	}
+}
+
+// IsMember сообщает, состоит ли ключ в группе.
+func IsMember(g *message.Group, key string) bool {
+	return slices.Contains(g.Members, key)
+}
+
+// addMembers добавляет отсутствующих участников с учётом MaxMembers.
+func addMembers(g *message.Group, members []string) error {
+	for _, member := range members {
+		if !IsMember(g, member) {
+			g.Members = append(g.Members, member)
+		}
+	}
+	if len(g.Members) > MaxMembers {
+		return ErrTooManyMembers
+	}
+	return nil
+}
+
+// signedData возвращает данные для подписи: SignatureDomain || change.
+func signedData(change []byte) []byte {
+	data := make([]byte, 0, len(SignatureDomain)+len(change))
+	data = append(data, SignatureDomain...)
+	return append(data, change...)
+}
+
+// isValidKey проверяет hex-представление 32-байтного ключа или id в нижнем регистре.
+func isValidKey(s string) bool {
+	if len(s) != IDSize*2 {
+		return false
+	}
+	for i := range len(s) {
+		c := s[i]
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
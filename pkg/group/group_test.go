@@ -0,0 +1,168 @@
+package group
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/udisondev/sprut/pkg/identity"
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+func mustKeys(t *testing.T) *identity.KeyPair {
+	t.Helper()
+	keys, err := identity.Generate()
+	if err != nil {
+		t.Fatalf("generate keys: %v", err)
+	}
+	return keys
+}
+
+func mustID(t *testing.T) string {
+	t.Helper()
+	id, err := NewID()
+	if err != nil {
+		t.Fatalf("new id: %v", err)
+	}
+	return id
+}
+
+func TestSignVerify(t *testing.T) {
+	owner := mustKeys(t)
+	change := &message.GroupChange{
+		GroupId:      mustID(t),
+		Op:           message.GroupOp_GROUP_OP_CREATE,
+		Members:      []string{mustKeys(t).PublicKeyHex()},
+		Actor:        owner.PublicKeyHex(),
+		UnixDateTime: 1700000000,
+	}
+
+	ctl, err := Sign(owner.PrivateKey, change)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	verified, err := Verify(ctl)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if verified.GroupId != change.GroupId || verified.Actor != change.Actor || !slices.Equal(verified.Members, change.Members) {
+		t.Errorf("change: got %v, want %v", verified, change)
+	}
+
+	// Подпись другим ключом
+	change.Actor = mustKeys(t).PublicKeyHex()
+	forged, err := Sign(owner.PrivateKey, change)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := Verify(forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged: got %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// Изменённые данные
+	ctl.Change = append(ctl.Change, 0x00)
+	if _, err := Verify(ctl); err == nil {
+		t.Error("tampered change must fail verification")
+	}
+}
+
+func TestApply(t *testing.T) {
+	owner := mustKeys(t).PublicKeyHex()
+	alice := mustKeys(t).PublicKeyHex()
+	bob := mustKeys(t).PublicKeyHex()
+	id := mustID(t)
+
+	change := func(actor string, op message.GroupOp, members ...string) *message.GroupChange {
+		return &message.GroupChange{GroupId: id, Op: op, Members: members, Actor: actor}
+	}
+
+	g, err := Apply(nil, change(owner, message.GroupOp_GROUP_OP_CREATE, alice))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if g.Owner != owner || g.Version != 1 || !slices.Equal(g.Members, []string{owner, alice}) {
+		t.Fatalf("create: got %v", g)
+	}
+
+	if _, err := Apply(g, change(owner, message.GroupOp_GROUP_OP_CREATE)); !errors.Is(err, ErrGroupExists) {
+		t.Errorf("create existing: got %v, want %v", err, ErrGroupExists)
+	}
+	if _, err := Apply(g, change(alice, message.GroupOp_GROUP_OP_ADD, bob)); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("add by member: got %v, want %v", err, ErrNotOwner)
+	}
+
+	added, err := Apply(g, change(owner, message.GroupOp_GROUP_OP_ADD, bob, alice))
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if added.Version != 2 || !slices.Equal(added.Members, []string{owner, alice, bob}) {
+		t.Fatalf("add: got %v", added)
+	}
+	if len(g.Members) != 2 {
+		t.Error("apply must not modify source group")
+	}
+
+	if _, err := Apply(added, change(owner, message.GroupOp_GROUP_OP_REMOVE, owner)); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("remove owner: got %v, want %v", err, ErrInvalidChange)
+	}
+	removed, err := Apply(added, change(owner, message.GroupOp_GROUP_OP_REMOVE, alice))
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if !slices.Equal(removed.Members, []string{owner, bob}) {
+		t.Fatalf("remove: got %v", removed.Members)
+	}
+
+	if _, err := Apply(removed, change(alice, message.GroupOp_GROUP_OP_LEAVE)); !errors.Is(err, ErrNotMember) {
+		t.Errorf("leave by non-member: got %v, want %v", err, ErrNotMember)
+	}
+	if _, err := Apply(removed, change(owner, message.GroupOp_GROUP_OP_LEAVE)); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("leave by owner: got %v, want %v", err, ErrInvalidChange)
+	}
+	left, err := Apply(removed, change(bob, message.GroupOp_GROUP_OP_LEAVE))
+	if err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if !slices.Equal(left.Members, []string{owner}) || left.Version != 4 {
+		t.Fatalf("leave: got %v", left)
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	owner := mustKeys(t).PublicKeyHex()
+
+	tests := []struct {
+		name   string
+		change *message.GroupChange
+	}{
+		{"bad_group_id", &message.GroupChange{GroupId: "xyz", Op: message.GroupOp_GROUP_OP_CREATE, Actor: owner}},
+		{"bad_member", &message.GroupChange{GroupId: mustID(t), Op: message.GroupOp_GROUP_OP_CREATE, Actor: owner, Members: []string{"not-a-key"}}},
+		{"unknown_op", &message.GroupChange{GroupId: mustID(t), Op: message.GroupOp_GROUP_OP_UNSPECIFIED, Actor: owner}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &message.Group{Id: tt.change.GroupId, Owner: owner, Members: []string{owner}}
+			if tt.change.Op == message.GroupOp_GROUP_OP_CREATE {
+				g = nil
+			}
+			if _, err := Apply(g, tt.change); !errors.Is(err, ErrInvalidChange) {
+				t.Errorf("got %v, want %v", err, ErrInvalidChange)
+			}
+		})
+	}
+}
+
+func TestApplyTooManyMembers(t *testing.T) {
+	owner := mustKeys(t).PublicKeyHex()
+	members := make([]string, MaxMembers)
+	for i := range members {
+		members[i] = mustID(t)
+	}
+
+	change := &message.GroupChange{GroupId: mustID(t), Op: message.GroupOp_GROUP_OP_CREATE, Actor: owner, Members: members}
+	if _, err := Apply(nil, change); !errors.Is(err, ErrTooManyMembers) {
+		t.Errorf("got %v, want %v", err, ErrTooManyMembers)
+	}
+}
@@ -0,0 +1,442 @@
+// Package listenfd передаёт слушающие сокеты между процессами. Процесс получает
+// их от systemd (socket activation, LISTEN_FDS) или от предыдущего процесса sprut
+// при обновлении без простоя и передаёт новому процессу (Set.Upgrade): пока оба
+// процесса держат сокет, соединения не отклоняются.
+//
+// Унаследованный сокет отдаётся listener по имени (FileDescriptorName в unit
+// systemd, при обновлении — имя из предыдущего процесса), а если имени нет —
+// по адресу.
+package listenfd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Переменные окружения протокола systemd (sd_listen_fds) и дескриптор,
+// через который новый процесс сообщает о готовности предыдущему.
+const (
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+	envReadyFD       = "SPRUT_UPGRADE_READY_FD"
+
+	// listenFDsStart — номер первого переданного дескриптора (SD_LISTEN_FDS_START).
+	listenFDsStart = 3
+)
+
+// Set — слушающие сокеты процесса. Сокеты, полученные от родителя, отдаются
+// Listen и ListenPacket по имени или адресу; созданные и отданные сокеты
+// запоминаются для передачи новому процессу. Нулевое значение готово к работе.
+type Set struct {
+	mu         sync.Mutex
+	inherited  []*socket // ещё не отданные унаследованные сокеты
+	active     []*socket // сокеты, которые слушает процесс
+	ready      *os.File  // сообщает предыдущему процессу о готовности; nil без обновления
+	upgrading  bool
+	handedOver chan struct{} // закрывается после успешного Upgrade; создаётся по требованию
+}
+
+// socket — слушающий сокет: потоковый (lis) или датаграммный (pc).
+type socket struct {
+	name string
+	lis  net.Listener
+	pc   net.PacketConn
+}
+
+func (s *socket) addr() net.Addr {
+	if s.lis != nil {
+		return s.lis.Addr()
+	}
+	return s.pc.LocalAddr()
+}
+
+// Inherit возвращает сокеты, переданные процессу через LISTEN_FDS, и убирает
+// переменные протокола из окружения, чтобы их не унаследовали дочерние процессы.
+// Без переданных сокетов возвращает пустой набор.
+func Inherit() (*Set, error) {
+	defer func() {
+		for _, env := range []string{envListenPID, envListenFDs, envListenFDNames, envReadyFD} {
+			_ = os.Unsetenv(env)
+		}
+	}()
+
+	set := &Set{}
+	if fd := os.Getenv(envReadyFD); fd != "" {
+		n, err := strconv.Atoi(fd)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envReadyFD, err)
+		}
+		set.ready = os.NewFile(uintptr(n), "ready")
+	}
+
+	// LISTEN_PID выставляет systemd; при обновлении его нет — PID нового процесса заранее неизвестен
+	if pid := os.Getenv(envListenPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return set, nil
+	}
+	count := os.Getenv(envListenFDs)
+	if count == "" {
+		return set, nil
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%s: invalid count %q", envListenFDs, count)
+	}
+
+	var names []string
+	if v := os.Getenv(envListenFDNames); v != "" {
+		names = strings.Split(v, ":")
+	}
+	for i := range n {
+		name := "unknown" // имя по умолчанию в systemd
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		sock, err := fileSocket(os.NewFile(uintptr(listenFDsStart+i), name), name)
+		if err != nil {
+			set.closeInherited()
+			return nil, fmt.Errorf("inherited socket %d (%s): %w", listenFDsStart+i, name, err)
+		}
+		set.inherited = append(set.inherited, sock)
+	}
+	return set, nil
+}
+
+// fileSocket создаёт сокет из дескриптора и закрывает f: у сокета своя копия дескриптора.
+func fileSocket(f *os.File, name string) (*socket, error) {
+	defer f.Close()
+
+	if lis, err := net.FileListener(f); err == nil {
+		return &socket{name: name, lis: lis}, nil
+	}
+	pc, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, errors.New("not a listening or datagram socket")
+	}
+	return &socket{name: name, pc: pc}, nil
+}
+
+// Listen возвращает унаследованный потоковый сокет с именем name (если задано) или адресом addr,
+// а если такого нет — создаёт новый (tcp или unix). Unix сокет, оставшийся
+// от завершившегося процесса, удаляется перед созданием.
+func (s *Set) Listen(name, network, addr string) (net.Listener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sock := s.take(name, network, addr, true); sock != nil {
+		return sock.lis, nil
+	}
+
+	if network == "unix" {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	}
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	s.active = append(s.active, &socket{name: name, lis: lis})
+	return lis, nil
+}
+
+// ListenPacket возвращает унаследованный датаграммный сокет с именем name
+// или адресом addr, а если такого нет — создаёт новый.
+func (s *Set) ListenPacket(name, network, addr string) (net.PacketConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sock := s.take(name, network, addr, false); sock != nil {
+		return sock.pc, nil
+	}
+
+	pc, err := net.ListenPacket(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	s.active = append(s.active, &socket{name: name, pc: pc})
+	return pc, nil
+}
+
+// take отдаёт унаследованный сокет нужного типа: сначала по имени, затем по адресу.
+// Вызывается под mu.
+func (s *Set) take(name, network, addr string, stream bool) *socket {
+	idx := -1
+	if name != "" {
+		idx = s.find(stream, func(sock *socket) bool { return sock.name == name })
+	}
+	if idx < 0 {
+		idx = s.find(stream, func(sock *socket) bool { return sameAddr(sock.addr(), network, addr) })
+	}
+	if idx < 0 {
+		return nil
+	}
+
+	sock := s.inherited[idx]
+	s.inherited = append(s.inherited[:idx], s.inherited[idx+1:]...)
+	sock.name = name
+	s.active = append(s.active, sock)
+	return sock
+}
+
+func (s *Set) find(stream bool, match func(*socket) bool) int {
+	for i, sock := range s.inherited {
+		if (sock.lis != nil) == stream && match(sock) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameAddr сообщает, слушает ли сокет с адресом a адрес addr сети network.
+// Хост без адреса и 0.0.0.0 / :: совпадают с любым неуказанным адресом.
+func sameAddr(a net.Addr, network, addr string) bool {
+	if network == "unix" {
+		return a.Network() == "unix" && a.String() == addr
+	}
+	if !strings.HasPrefix(a.Network(), strings.TrimRight(network, "46")) {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	got, err := netip.ParseAddrPort(a.String())
+	if err != nil || strconv.Itoa(int(got.Port())) != port || port == "0" {
+		return false
+	}
+	if host == "" {
+		return got.Addr().IsUnspecified()
+	}
+	want, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	if want.IsUnspecified() {
+		return got.Addr().IsUnspecified()
+	}
+	return want.Unmap() == got.Addr().Unmap()
+}
+
+// CloseUnused закрывает унаследованные сокеты, которые так и не были запрошены,
+// и возвращает их имена.
+func (s *Set) CloseUnused() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for _, sock := range s.inherited {
+		names = append(names, sock.name)
+	}
+	s.closeInherited()
+	return names
+}
+
+func (s *Set) closeInherited() {
+	for _, sock := range s.inherited {
+		if sock.lis != nil {
+			_ = sock.lis.Close()
+		} else {
+			_ = sock.pc.Close()
+		}
+	}
+	s.inherited = nil
+}
+
+// Ready сообщает процессу, передавшему сокеты при обновлении, что этот процесс
+// принимает соединения и предыдущий может завершаться. Без обновления ничего не делает.
+func (s *Set) Ready() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ready == nil {
+		return nil
+	}
+	defer func() {
+		_ = s.ready.Close()
+		s.ready = nil
+	}()
+	if _, err := s.ready.Write([]byte{1}); err != nil {
+		return fmt.Errorf("notify previous process: %w", err)
+	}
+	return nil
+}
+
+// Upgrade запускает cmd (новый процесс), передав ему сокеты набора,
+// и ждёт, пока он вызовет Ready. Ошибка — процесс завершился до готовности
+// или истёк ctx (тогда процесс убивается); в этом случае текущий процесс
+// продолжает работу как прежде. После успеха текущему процессу остаётся
+// закрыть свои сокеты и завершить работу с клиентами: Unix сокеты при
+// закрытии больше не удаляются, их файлы принадлежат новому процессу.
+func (s *Set) Upgrade(ctx context.Context, cmd *exec.Cmd) error {
+	s.mu.Lock()
+	if s.upgrading {
+		s.mu.Unlock()
+		return errors.New("upgrade already in progress")
+	}
+	select {
+	case <-s.handedOverCh():
+		s.mu.Unlock()
+		return errors.New("sockets already handed over")
+	default:
+	}
+	s.upgrading = true
+	files, names, err := s.files()
+	s.mu.Unlock()
+
+	defer closeFiles(files)
+	if err != nil {
+		s.finishUpgrade(false)
+		return err
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		s.finishUpgrade(false)
+		return fmt.Errorf("create ready pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env,
+		envListenFDs+"="+strconv.Itoa(len(files)),
+		envListenFDNames+"="+strings.Join(names, ":"),
+		envReadyFD+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+	cmd.ExtraFiles = append(files, readyW)
+
+	err = cmd.Start()
+	_ = readyW.Close() // копия остаётся только у нового процесса: его завершение закроет канал
+	if err != nil {
+		s.finishUpgrade(false)
+		return fmt.Errorf("start process: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+
+	ready := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		_, err := readyR.Read(b[:])
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			s.finishUpgrade(false)
+			return fmt.Errorf("process %d exited before ready", cmd.Process.Pid)
+		}
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		s.finishUpgrade(false)
+		return fmt.Errorf("process %d not ready: %w", cmd.Process.Pid, ctx.Err())
+	}
+
+	s.finishUpgrade(true)
+	return nil
+}
+
+// files возвращает копии дескрипторов активных сокетов и их имена. Вызывается под mu.
+func (s *Set) files() ([]*os.File, []string, error) {
+	var (
+		files []*os.File
+		names []string
+	)
+	for _, sock := range s.active {
+		var c any = sock.lis
+		if sock.pc != nil {
+			c = sock.pc
+		}
+		fc, ok := c.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("socket %s: %T cannot be passed to another process", sock.name, c)
+		}
+		f, err := fc.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("socket %s: %w", sock.name, err)
+		}
+		files = append(files, f)
+		// ":" разделяет имена в LISTEN_FDNAMES; сокет без имени найдётся по адресу
+		names = append(names, strings.ReplaceAll(sock.name, ":", "_"))
+	}
+	return files, names, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// finishUpgrade завершает попытку обновления. После успешного обновления
+// Unix сокеты при закрытии не удаляются: их слушает новый процесс.
+func (s *Set) finishUpgrade(ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.upgrading = false
+	if !ok {
+		return
+	}
+	for _, sock := range s.active {
+		if ul, isUnix := sock.lis.(*net.UnixListener); isUnix {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	close(s.handedOverCh())
+}
+
+// HandedOver возвращает канал, закрываемый после успешного Upgrade: сокеты
+// слушает и новый процесс. Датаграммный сокет нельзя читать вдвоём — каждый
+// пакет достаётся одному из процессов, — поэтому его владелец должен
+// закрыть свои соединения на нём сразу, не дожидаясь завершения работы.
+func (s *Set) HandedOver() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handedOverCh()
+}
+
+// handedOverCh возвращает канал handedOver, создавая его. Вызывается под mu.
+func (s *Set) handedOverCh() chan struct{} {
+	if s.handedOver == nil {
+		s.handedOver = make(chan struct{})
+	}
+	return s.handedOver
+}
+
+// removeStaleSocket удаляет Unix сокет, оставшийся от прежнего процесса.
+// Сокет, который кто-то слушает, и файл другого типа не трогаются: net.Listen вернёт ошибку.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat socket: %w", err)
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return nil
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale socket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package listenfd
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envHelper переводит тестовый бинарник в режим нового процесса для TestUpgrade:
+// значение — адрес, который должен прийти от родителя, или "fail".
+const envHelper = "SPRUT_LISTENFD_HELPER"
+
+func TestMain(m *testing.M) {
+	if addr := os.Getenv(envHelper); addr != "" {
+		os.Exit(helperProcess(addr))
+	}
+	os.Exit(m.Run())
+}
+
+// helperProcess принимает сокет от родителя, сообщает о готовности
+// и отвечает "child" на одно соединение.
+func helperProcess(addr string) int {
+	if addr == "fail" {
+		return 1
+	}
+	set, err := Inherit()
+	if err != nil {
+		return 2
+	}
+	lis, err := set.Listen("main", "tcp", "127.0.0.1:0")
+	if err != nil || lis.Addr().String() != addr {
+		return 3
+	}
+	if err := set.Ready(); err != nil {
+		return 4
+	}
+	conn, err := lis.Accept()
+	if err != nil {
+		return 5
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("child"))
+	return 0
+}
+
+func TestUpgrade(t *testing.T) {
+	set := &Set{}
+	lis, err := set.Listen("main", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+
+	// Новый процесс не готов — старый продолжает работу с тем же сокетом
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), envHelper+"=fail")
+	if err := set.Upgrade(ctx, cmd); err == nil || !strings.Contains(err.Error(), "exited before ready") {
+		t.Fatalf("upgrade with failing process: got %v, want exited before ready", err)
+	}
+
+	cmd = exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), envHelper+"="+addr)
+	select {
+	case <-set.HandedOver():
+		t.Fatal("handed over after failed upgrade")
+	default:
+	}
+	if err := set.Upgrade(ctx, cmd); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	select {
+	case <-set.HandedOver():
+	default:
+		t.Error("HandedOver not closed after upgrade")
+	}
+	if err := set.Upgrade(ctx, exec.Command(os.Args[0])); err == nil {
+		t.Error("second upgrade after handover succeeded")
+	}
+
+	// После закрытия сокета в старом процессе соединения принимает новый
+	if err := lis.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial after upgrade: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil || string(got) != "child" {
+		t.Errorf("response = %q, %v; want %q", got, err, "child")
+	}
+}
+
+func TestListenInherited(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen packet: %v", err)
+	}
+	extra, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	set := &Set{inherited: []*socket{
+		{name: "unknown", lis: tcp},
+		{name: "quic", pc: udp},
+		{name: "admin", lis: extra},
+	}}
+
+	// Без совпадения по имени сокет находится по адресу
+	lis, err := set.Listen("main", "tcp", tcp.Addr().String())
+	if err != nil || lis != tcp {
+		t.Errorf("listen by addr: got %v, %v; want inherited", lis, err)
+	}
+	// Имя важнее адреса
+	pc, err := set.ListenPacket("quic", "udp", "127.0.0.1:0")
+	if err != nil || pc != udp {
+		t.Errorf("listen packet by name: got %v, %v; want inherited", pc, err)
+	}
+	// Не унаследованный сокет создаётся
+	lis, err = set.Listen("ws", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen new: %v", err)
+	}
+	defer lis.Close()
+	if lis == tcp || lis == extra {
+		t.Error("new listener reused inherited socket")
+	}
+
+	if got := set.CloseUnused(); len(got) != 1 || got[0] != "admin" {
+		t.Errorf("unused = %v, want [admin]", got)
+	}
+	if _, err := extra.Accept(); err == nil {
+		t.Error("unused inherited socket not closed")
+	}
+
+	files, names, err := set.files()
+	if err != nil {
+		t.Fatalf("files: %v", err)
+	}
+	defer closeFiles(files)
+	if got := strings.Join(names, ":"); got != "main:quic:ws" {
+		t.Errorf("handed over sockets = %s, want main:quic:ws", got)
+	}
+}
+
+func TestSameAddr(t *testing.T) {
+	tcp4 := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7000}
+	any4 := &net.TCPAddr{IP: net.IPv4zero, Port: 7000}
+	any6 := &net.TCPAddr{IP: net.IPv6unspecified, Port: 7000}
+	udp := &net.UDPAddr{IP: net.IPv4zero, Port: 7000}
+	unix := &net.UnixAddr{Name: "/run/sprut.sock", Net: "unix"}
+
+	tests := []struct {
+		addr    net.Addr
+		network string
+		want    string
+		match   bool
+	}{
+		{tcp4, "tcp", "127.0.0.1:7000", true},
+		{tcp4, "tcp", "127.0.0.1:7001", false},
+		{tcp4, "tcp", ":7000", false},
+		{any4, "tcp", ":7000", true},
+		{any6, "tcp", "0.0.0.0:7000", true},
+		{any4, "tcp", "127.0.0.1:7000", false},
+		{any4, "udp", ":7000", false},
+		{udp, "udp", ":7000", true},
+		{any4, "tcp", "127.0.0.1:0", false},
+		{unix, "unix", "/run/sprut.sock", true},
+		{unix, "unix", "/run/other.sock", false},
+		{tcp4, "unix", "127.0.0.1:7000", false},
+	}
+	for _, tt := range tests {
+		if got := sameAddr(tt.addr, tt.network, tt.want); got != tt.match {
+			t.Errorf("sameAddr(%s %s, %s %s) = %v, want %v", tt.addr.Network(), tt.addr, tt.network, tt.want, got, tt.match)
+		}
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	// Сокет, оставшийся после падения процесса, удаляется
+	stale := filepath.Join(dir, "stale.sock")
+	lis, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := lis.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := removeStaleSocket(stale); err != nil {
+		t.Fatalf("remove stale socket: %v", err)
+	}
+	if _, err := os.Lstat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale socket not removed: %v", err)
+	}
+
+	// Сокет, который слушают, остаётся
+	live := filepath.Join(dir, "live.sock")
+	lis, err = net.Listen("unix", live)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	if err := removeStaleSocket(live); err != nil {
+		t.Fatalf("remove live socket: %v", err)
+	}
+	if _, err := os.Lstat(live); err != nil {
+		t.Errorf("live socket removed: %v", err)
+	}
+
+	// Обычный файл не трогается
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := removeStaleSocket(file); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	if _, err := os.Lstat(file); err != nil {
+		t.Errorf("regular file removed: %v", err)
+	}
+}
@@ -21,6 +21,112 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Kind тип сообщения
+type Kind int32
+
+const (
+	Kind_KIND_MESSAGE       Kind = 0 // пользовательское сообщение
+	Kind_KIND_RECEIPT       Kind = 1 // квитанция о доставке: id — ID доставленного сообщения
+	Kind_KIND_GROUP_CONTROL Kind = 2 // изменение состава группы: payload — GroupControl
+)
+
+// Enum value maps for Kind.
+var (
+	Kind_name = map[int32]string{
+		0: "KIND_MESSAGE",
+		1: "KIND_RECEIPT",
+		2: "KIND_GROUP_CONTROL",
+	}
+	Kind_value = map[string]int32{
+		"KIND_MESSAGE":       0,
+		"KIND_RECEIPT":       1,
+		"KIND_GROUP_CONTROL": 2,
+	}
+)
+
+func (x Kind) Enum() *Kind {
+	p := new(Kind)
+	*p = x
+	return p
+}
+
+func (x Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_message_message_proto_enumTypes[0].Descriptor()
+}
+
+func (Kind) Type() protoreflect.EnumType {
+	return &file_pkg_message_message_proto_enumTypes[0]
+}
+
+func (x Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Kind.Descriptor instead.
+func (Kind) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{0}
+}
+
+// GroupOp операция над составом группы
+type GroupOp int32
+
+const (
+	GroupOp_GROUP_OP_UNSPECIFIED GroupOp = 0
+	GroupOp_GROUP_OP_CREATE      GroupOp = 1 // создать группу: автор становится владельцем
+	GroupOp_GROUP_OP_ADD         GroupOp = 2 // добавить участников (только владелец)
+	GroupOp_GROUP_OP_REMOVE      GroupOp = 3 // удалить участников (только владелец)
+	GroupOp_GROUP_OP_LEAVE       GroupOp = 4 // выйти из группы (любой участник, кроме владельца)
+)
+
+// Enum value maps for GroupOp.
+var (
+	GroupOp_name = map[int32]string{
+		0: "GROUP_OP_UNSPECIFIED",
+		1: "GROUP_OP_CREATE",
+		2: "GROUP_OP_ADD",
+		3: "GROUP_OP_REMOVE",
+		4: "GROUP_OP_LEAVE",
+	}
+	GroupOp_value = map[string]int32{
+		"GROUP_OP_UNSPECIFIED": 0,
+		"GROUP_OP_CREATE":      1,
+		"GROUP_OP_ADD":         2,
+		"GROUP_OP_REMOVE":      3,
+		"GROUP_OP_LEAVE":       4,
+	}
+)
+
+func (x GroupOp) Enum() *GroupOp {
+	p := new(GroupOp)
+	*p = x
+	return p
+}
+
+func (x GroupOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GroupOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_message_message_proto_enumTypes[1].Descriptor()
+}
+
+func (GroupOp) Type() protoreflect.EnumType {
+	return &file_pkg_message_message_proto_enumTypes[1]
+}
+
+func (x GroupOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GroupOp.Descriptor instead.
+func (GroupOp) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{1}
+}
+
 // Message представляет сообщение между клиентами
 type Message struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -29,6 +135,8 @@ type Message struct {
 	Id            string                 `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`                                            // message ID from client
 	Payload       []byte                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`                                  // raw payload
 	UnixDateTime  int64                  `protobuf:"varint,5,opt,name=unix_date_time,json=unixDateTime,proto3" json:"unix_date_time,omitempty"` // server timestamp
+	Kind          Kind                   `protobuf:"varint,6,opt,name=kind,proto3,enum=goro.Kind" json:"kind,omitempty"`                        // message kind
+	GroupId       string                 `protobuf:"bytes,7,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`                   // hex-encoded group id, пустой для личных сообщений
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -98,17 +206,382 @@ func (x *Message) GetUnixDateTime() int64 {
 	return 0
 }
 
+func (x *Message) GetKind() Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return Kind_KIND_MESSAGE
+}
+
+func (x *Message) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+// Presence статус клиента в кластере.
+// Публикуется в NATS при подключении и отключении клиента и
+// возвращается в ответ на запрос статуса.
+type Presence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`                                          // hex-encoded pubkey клиента
+	Online        bool                   `protobuf:"varint,2,opt,name=online,proto3" json:"online,omitempty"`                                   // подключён ли клиент
+	ServerId      string                 `protobuf:"bytes,3,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`                // сервер, на котором подключён клиент (только для служебных запросов)
+	UnixDateTime  int64                  `protobuf:"varint,4,opt,name=unix_date_time,json=unixDateTime,proto3" json:"unix_date_time,omitempty"` // время подключения или отключения
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	mi := &file_pkg_message_message_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_message_message_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Presence) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Presence) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *Presence) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *Presence) GetUnixDateTime() int64 {
+	if x != nil {
+		return x.UnixDateTime
+	}
+	return 0
+}
+
+// GroupChange изменение состава группы
+type GroupChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`                   // hex-encoded id группы
+	Op            GroupOp                `protobuf:"varint,2,opt,name=op,proto3,enum=goro.GroupOp" json:"op,omitempty"`                         // операция
+	Members       []string               `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`                                  // hex-encoded pubkeys участников операции
+	Actor         string                 `protobuf:"bytes,4,opt,name=actor,proto3" json:"actor,omitempty"`                                      // hex-encoded pubkey автора изменения
+	UnixDateTime  int64                  `protobuf:"varint,5,opt,name=unix_date_time,json=unixDateTime,proto3" json:"unix_date_time,omitempty"` // время создания изменения
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupChange) Reset() {
+	*x = GroupChange{}
+	mi := &file_pkg_message_message_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupChange) ProtoMessage() {}
+
+func (x *GroupChange) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_message_message_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupChange.ProtoReflect.Descriptor instead.
+func (*GroupChange) Descriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GroupChange) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GroupChange) GetOp() GroupOp {
+	if x != nil {
+		return x.Op
+	}
+	return GroupOp_GROUP_OP_UNSPECIFIED
+}
+
+func (x *GroupChange) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *GroupChange) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *GroupChange) GetUnixDateTime() int64 {
+	if x != nil {
+		return x.UnixDateTime
+	}
+	return 0
+}
+
+// GroupControl подписанное автором изменение состава группы.
+// Рассылается участникам, чтобы они могли проверить подпись сами.
+type GroupControl struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Change        []byte                 `protobuf:"bytes,1,opt,name=change,proto3" json:"change,omitempty"`       // сериализованный GroupChange
+	Signature     []byte                 `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"` // ed25519 подпись автора
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupControl) Reset() {
+	*x = GroupControl{}
+	mi := &file_pkg_message_message_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupControl) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupControl) ProtoMessage() {}
+
+func (x *GroupControl) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_message_message_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupControl.ProtoReflect.Descriptor instead.
+func (*GroupControl) Descriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GroupControl) GetChange() []byte {
+	if x != nil {
+		return x.Change
+	}
+	return nil
+}
+
+func (x *GroupControl) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// Group состав группы
+type Group struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`            // hex-encoded id группы
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`      // hex-encoded pubkey владельца
+	Members       []string               `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`  // hex-encoded pubkeys участников, включая владельца
+	Version       uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"` // номер изменения состава
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_pkg_message_message_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_message_message_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Group) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Group) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Group) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *Group) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// KeySet список ключей владельца для политики авторизации:
+// заблокированные отправители или контакты.
+type KeySet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []string               `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"` // hex-encoded pubkeys
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeySet) Reset() {
+	*x = KeySet{}
+	mi := &file_pkg_message_message_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeySet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeySet) ProtoMessage() {}
+
+func (x *KeySet) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_message_message_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeySet.ProtoReflect.Descriptor instead.
+func (*KeySet) Descriptor() ([]byte, []int) {
+	return file_pkg_message_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *KeySet) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
 var File_pkg_message_message_proto protoreflect.FileDescriptor
 
 const file_pkg_message_message_proto_rawDesc = "" +
 	"\n" +
-	"\x19pkg/message/message.proto\x12\x04goro\"}\n" +
+	"\x19pkg/message/message.proto\x12\x04goro\"\xb8\x01\n" +
 	"\aMessage\x12\x12\n" +
 	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
 	"\x02to\x18\x02 \x01(\tR\x02to\x12\x0e\n" +
 	"\x02id\x18\x03 \x01(\tR\x02id\x12\x18\n" +
 	"\apayload\x18\x04 \x01(\fR\apayload\x12$\n" +
-	"\x0eunix_date_time\x18\x05 \x01(\x03R\funixDateTimeB(Z&github.com/udisondev/sprut/pkg/messageb\x06proto3"
+	"\x0eunix_date_time\x18\x05 \x01(\x03R\funixDateTime\x12\x1e\n" +
+	"\x04kind\x18\x06 \x01(\x0e2\n" +
+	".goro.KindR\x04kind\x12\x19\n" +
+	"\bgroup_id\x18\a \x01(\tR\agroupId\"w\n" +
+	"\bPresence\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06online\x18\x02 \x01(\bR\x06online\x12\x1b\n" +
+	"\tserver_id\x18\x03 \x01(\tR\bserverId\x12$\n" +
+	"\x0eunix_date_time\x18\x04 \x01(\x03R\funixDateTime\"\x9d\x01\n" +
+	"\vGroupChange\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x1d\n" +
+	"\x02op\x18\x02 \x01(\x0e2\r.goro.GroupOpR\x02op\x12\x18\n" +
+	"\amembers\x18\x03 \x03(\tR\amembers\x12\x14\n" +
+	"\x05actor\x18\x04 \x01(\tR\x05actor\x12$\n" +
+	"\x0eunix_date_time\x18\x05 \x01(\x03R\funixDateTime\"D\n" +
+	"\fGroupControl\x12\x16\n" +
+	"\x06change\x18\x01 \x01(\fR\x06change\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"a\n" +
+	"\x05Group\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x18\n" +
+	"\amembers\x18\x03 \x03(\tR\amembers\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x04R\aversion\"\x1c\n" +
+	"\x06KeySet\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys*B\n" +
+	"\x04Kind\x12\x10\n" +
+	"\fKIND_MESSAGE\x10\x00\x12\x10\n" +
+	"\fKIND_RECEIPT\x10\x01\x12\x16\n" +
+	"\x12KIND_GROUP_CONTROL\x10\x02*s\n" +
+	"\aGroupOp\x12\x18\n" +
+	"\x14GROUP_OP_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fGROUP_OP_CREATE\x10\x01\x12\x10\n" +
+	"\fGROUP_OP_ADD\x10\x02\x12\x13\n" +
+	"\x0fGROUP_OP_REMOVE\x10\x03\x12\x12\n" +
+	"\x0eGROUP_OP_LEAVE\x10\x04B(Z&github.com/udisondev/sprut/pkg/messageb\x06proto3"
 
 var (
 	file_pkg_message_message_proto_rawDescOnce sync.Once
@@ -122,16 +595,26 @@ func file_pkg_message_message_proto_rawDescGZIP() []byte {
 	return file_pkg_message_message_proto_rawDescData
 }
 
-var file_pkg_message_message_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pkg_message_message_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pkg_message_message_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_pkg_message_message_proto_goTypes = []any{
-	(*Message)(nil), // 0: goro.Message
+	(Kind)(0),            // 0: goro.Kind
+	(GroupOp)(0),         // 1: goro.GroupOp
+	(*Message)(nil),      // 2: goro.Message
+	(*Presence)(nil),     // 3: goro.Presence
+	(*GroupChange)(nil),  // 4: goro.GroupChange
+	(*GroupControl)(nil), // 5: goro.GroupControl
+	(*Group)(nil),        // 6: goro.Group
+	(*KeySet)(nil),       // 7: goro.KeySet
 }
 var file_pkg_message_message_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: goro.Message.kind:type_name -> goro.Kind
+	1, // 1: goro.GroupChange.op:type_name -> goro.GroupOp
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_pkg_message_message_proto_init() }
@@ -144,13 +627,14 @@ func file_pkg_message_message_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_message_message_proto_rawDesc), len(file_pkg_message_message_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   1,
+			NumEnums:      2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_pkg_message_message_proto_goTypes,
 		DependencyIndexes: file_pkg_message_message_proto_depIdxs,
+		EnumInfos:         file_pkg_message_message_proto_enumTypes,
 		MessageInfos:      file_pkg_message_message_proto_msgTypes,
 	}.Build()
 	File_pkg_message_message_proto = out.File
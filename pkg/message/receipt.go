@@ -0,0 +1,7 @@
+package message
+
+// ReceiptDropped — payload квитанции (KIND_RECEIPT), которую сервер отправляет
+// автору от имени получателя, если сообщение отброшено из-за переполнения
+// очереди записи получателя (limits.overflow_policy drop_oldest или drop_newest).
+// Квитанции о доставке, отправленные клиентами, payload не содержат.
+const ReceiptDropped = "dropped"
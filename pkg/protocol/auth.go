@@ -99,6 +99,55 @@ func DecodeClientResponse(r io.Reader) (*ClientResponse, error) {
 	return &m, nil
 }
 
+// ClientToken — рукопожатие с подписанным JWT вместо challenge/response.
+type ClientToken struct {
+	Token string
+}
+
+// Encode записывает ClientToken в writer.
+func (m *ClientToken) Encode(w io.Writer) error {
+	if len(m.Token) > MaxTokenSize {
+		return fmt.Errorf("token too long: %d > %d", len(m.Token), MaxTokenSize)
+	}
+	buf := make([]byte, 3+len(m.Token))
+	buf[0] = TypeClientToken
+	binary.BigEndian.PutUint16(buf[1:3], uint16(len(m.Token)))
+	copy(buf[3:], m.Token)
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write token: %w", err)
+	}
+	return nil
+}
+
+// DecodeClientToken читает ClientToken из reader (без байта типа).
+func DecodeClientToken(r io.Reader) (*ClientToken, error) {
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read token len: %w", err)
+	}
+	tokenLen := binary.BigEndian.Uint16(lenBuf[:])
+	if tokenLen > MaxTokenSize {
+		return nil, fmt.Errorf("token too long: %d", tokenLen)
+	}
+	token := make([]byte, tokenLen)
+	if _, err := io.ReadFull(r, token); err != nil {
+		return nil, fmt.Errorf("read token: %w", err)
+	}
+	return &ClientToken{Token: string(token)}, nil
+}
+
+// ClientCertHello — рукопожатие по клиентскому TLS сертификату.
+// Тела нет: сервер берёт PeerID из проверенного сертификата.
+type ClientCertHello struct{}
+
+// Encode записывает ClientCertHello в writer.
+func (m *ClientCertHello) Encode(w io.Writer) error {
+	if _, err := w.Write([]byte{TypeClientCertHello}); err != nil {
+		return fmt.Errorf("write type: %w", err)
+	}
+	return nil
+}
+
 // AuthResult — результат аутентификации от сервера.
 type AuthResult struct {
 	Status   byte
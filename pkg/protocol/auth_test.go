@@ -97,6 +97,33 @@ func TestClientResponseEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestClientTokenEncodeDecode(t *testing.T) {
+	original := &ClientToken{Token: "header.claims.signature"}
+
+	var buf bytes.Buffer
+	if err := original.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	data := buf.Bytes()
+	if data[0] != TypeClientToken {
+		t.Errorf("type: got %d, want %d", data[0], TypeClientToken)
+	}
+
+	decoded, err := DecodeClientToken(bytes.NewReader(data[1:]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.Token != original.Token {
+		t.Errorf("token: got %q, want %q", decoded.Token, original.Token)
+	}
+
+	tooLong := &ClientToken{Token: string(make([]byte, MaxTokenSize+1))}
+	if err := tooLong.Encode(&buf); err == nil {
+		t.Error("expected error for too long token")
+	}
+}
+
 func TestAuthResultEncodeDecode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -106,6 +133,7 @@ func TestAuthResultEncodeDecode(t *testing.T) {
 		{"ok", AuthStatusOK, ""},
 		{"invalid_sig", AuthStatusInvalidSig, "invalid signature"},
 		{"replay", AuthStatusReplay, "replay detected"},
+		{"failed", AuthStatusFailed, "unexpected message type: 16"},
 	}
 
 	for _, tt := range tests {
@@ -175,3 +203,13 @@ func TestBuildSignedData(t *testing.T) {
 		t.Errorf("length: got %d, want %d", len(data), expectedLen)
 	}
 }
+
+func TestLegacyProtocolVersion(t *testing.T) {
+	// Сервер подставляет прежнюю версию в тот же буфер подписи
+	if len(LegacyProtocolVersion) != len(ProtocolVersion) {
+		t.Errorf("legacy version length %d, want %d", len(LegacyProtocolVersion), len(ProtocolVersion))
+	}
+	if LegacyProtocolVersion == ProtocolVersion {
+		t.Error("protocol version not bumped")
+	}
+}
@@ -8,13 +8,25 @@ import (
 
 // ClientMessage — сообщение от клиента к серверу.
 type ClientMessage struct {
-	To      string // hex-encoded публичный ключ получателя (64 символа)
+	// Type — тип кадра: TypeClientMessage (по умолчанию),
+	// TypeClientGroupMessage или TypeClientGroupControl.
+	Type    byte
+	To      string // hex-encoded публичный ключ получателя или id группы (64 символа)
 	MsgID   string
 	Payload []byte
 }
 
 // Encode записывает ClientMessage в writer.
 func (m *ClientMessage) Encode(w io.Writer) error {
+	frameType := m.Type
+	switch frameType {
+	case 0:
+		frameType = TypeClientMessage
+	case TypeClientMessage, TypeClientGroupMessage, TypeClientGroupControl:
+	default:
+		return fmt.Errorf("invalid message frame type: %d", frameType)
+	}
+
 	toBytes := []byte(m.To)
 	msgIDBytes := []byte(m.MsgID)
 
@@ -30,10 +42,8 @@ func (m *ClientMessage) Encode(w io.Writer) error {
 		return fmt.Errorf("message too large: %d > %d", totalLen, MaxMessageSize)
 	}
 
-	var lenBuf [4]byte
-	binary.BigEndian.PutUint32(lenBuf[:], uint32(totalLen))
-	if _, err := w.Write(lenBuf[:]); err != nil {
-		return fmt.Errorf("write total len: %w", err)
+	if err := writeFrameHeader(w, frameType, totalLen); err != nil {
+		return err
 	}
 
 	if _, err := w.Write(toBytes); err != nil {
@@ -57,7 +67,7 @@ func (m *ClientMessage) Encode(w io.Writer) error {
 	return nil
 }
 
-// DecodeClientMessage читает ClientMessage из reader.
+// DecodeClientMessage читает ClientMessage из reader (без байта типа).
 func DecodeClientMessage(r io.Reader) (*ClientMessage, error) {
 	var lenBuf [4]byte
 	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
@@ -95,6 +105,51 @@ func DecodeClientMessage(r io.Reader) (*ClientMessage, error) {
 	return m, nil
 }
 
+// ClientReceipt — квитанция о доставке сообщения MsgID, отправляемая его автору To.
+// Body имеет формат ClientMessage без payload.
+type ClientReceipt struct {
+	To    string // hex-encoded публичный ключ автора сообщения (64 символа)
+	MsgID string
+}
+
+// Encode записывает ClientReceipt в writer.
+func (m *ClientReceipt) Encode(w io.Writer) error {
+	toBytes := []byte(m.To)
+	msgIDBytes := []byte(m.MsgID)
+
+	if len(toBytes) != PublicKeySize*2 {
+		return fmt.Errorf("invalid to length: expected %d, got %d", PublicKeySize*2, len(toBytes))
+	}
+	if len(msgIDBytes) > MaxMsgIDLen {
+		return fmt.Errorf("msg_id too long: %d > %d", len(msgIDBytes), MaxMsgIDLen)
+	}
+
+	totalLen := PublicKeySize*2 + 2 + len(msgIDBytes)
+	buf := make([]byte, FrameHeaderSize+totalLen)
+	buf[0] = TypeClientReceipt
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], uint32(totalLen))
+	offset := FrameHeaderSize + copy(buf[FrameHeaderSize:], toBytes)
+	binary.BigEndian.PutUint16(buf[offset:], uint16(len(msgIDBytes)))
+	copy(buf[offset+2:], msgIDBytes)
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write receipt: %w", err)
+	}
+	return nil
+}
+
+// DecodeClientReceipt читает ClientReceipt из reader (без байта типа).
+func DecodeClientReceipt(r io.Reader) (*ClientReceipt, error) {
+	m, err := DecodeClientMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Payload) != 0 {
+		return nil, fmt.Errorf("unexpected receipt payload: %d bytes", len(m.Payload))
+	}
+	return &ClientReceipt{To: m.To, MsgID: m.MsgID}, nil
+}
+
 // ServerMessage — сообщение от сервера к клиенту (protobuf-wrapped).
 type ServerMessage struct {
 	Data []byte // marshaled protobuf Message
@@ -106,10 +161,8 @@ func (m *ServerMessage) Encode(w io.Writer) error {
 		return fmt.Errorf("message too large: %d > %d", len(m.Data), MaxMessageSize)
 	}
 
-	var lenBuf [4]byte
-	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(m.Data)))
-	if _, err := w.Write(lenBuf[:]); err != nil {
-		return fmt.Errorf("write total len: %w", err)
+	if err := writeFrameHeader(w, TypeServerMessage, len(m.Data)); err != nil {
+		return err
 	}
 
 	if _, err := w.Write(m.Data); err != nil {
@@ -119,7 +172,7 @@ func (m *ServerMessage) Encode(w io.Writer) error {
 	return nil
 }
 
-// DecodeServerMessage читает ServerMessage из reader.
+// DecodeServerMessage читает ServerMessage из reader (без байта типа).
 func DecodeServerMessage(r io.Reader) (*ServerMessage, error) {
 	var lenBuf [4]byte
 	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
@@ -137,3 +190,270 @@ func DecodeServerMessage(r io.Reader) (*ServerMessage, error) {
 
 	return &ServerMessage{Data: data}, nil
 }
+
+// ServerAck — подтверждение сервером сообщения клиента.
+// Сопоставляется с исходящим сообщением по MsgID.
+type ServerAck struct {
+	Status byte
+	MsgID  string
+	Reason string // причина отказа, пустая для AckStatusAccepted
+}
+
+// Encode записывает ServerAck в writer.
+// Body: Status(1) + MsgIDLen(2) + MsgID + ReasonLen(2) + Reason.
+func (m *ServerAck) Encode(w io.Writer) error {
+	msgIDBytes := []byte(m.MsgID)
+	if len(msgIDBytes) > MaxMsgIDLen {
+		return fmt.Errorf("msg_id too long: %d > %d", len(msgIDBytes), MaxMsgIDLen)
+	}
+	reasonBytes := []byte(m.Reason)
+	if len(reasonBytes) > MaxErrorMsgLen {
+		reasonBytes = reasonBytes[:MaxErrorMsgLen]
+	}
+
+	totalLen := 1 + 2 + len(msgIDBytes) + 2 + len(reasonBytes)
+	buf := make([]byte, FrameHeaderSize+totalLen)
+	buf[0] = TypeServerAck
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], uint32(totalLen))
+
+	offset := FrameHeaderSize
+	buf[offset] = m.Status
+	offset++
+	binary.BigEndian.PutUint16(buf[offset:], uint16(len(msgIDBytes)))
+	offset += 2
+	offset += copy(buf[offset:], msgIDBytes)
+	binary.BigEndian.PutUint16(buf[offset:], uint16(len(reasonBytes)))
+	offset += 2
+	copy(buf[offset:], reasonBytes)
+
+	// Одна запись — один TLS record
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write ack: %w", err)
+	}
+	return nil
+}
+
+// DecodeServerAck читает ServerAck из reader (без байта типа).
+func DecodeServerAck(r io.Reader) (*ServerAck, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read total len: %w", err)
+	}
+	totalLen := binary.BigEndian.Uint32(lenBuf[:])
+	if totalLen > 1+2+MaxMsgIDLen+2+MaxErrorMsgLen {
+		return nil, fmt.Errorf("ack too large: %d", totalLen)
+	}
+
+	data := make([]byte, totalLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read ack data: %w", err)
+	}
+
+	if len(data) < 1+2 {
+		return nil, fmt.Errorf("ack too short")
+	}
+	m := &ServerAck{Status: data[0]}
+	data = data[1:]
+
+	msgIDLen := int(binary.BigEndian.Uint16(data[:2]))
+	data = data[2:]
+	if msgIDLen+2 > len(data) {
+		return nil, fmt.Errorf("invalid msg_id length")
+	}
+	m.MsgID = string(data[:msgIDLen])
+	data = data[msgIDLen:]
+
+	reasonLen := int(binary.BigEndian.Uint16(data[:2]))
+	data = data[2:]
+	if reasonLen != len(data) {
+		return nil, fmt.Errorf("invalid reason length")
+	}
+	m.Reason = string(data)
+
+	return m, nil
+}
+
+// ServerError — ошибка, после которой сервер закрывает соединение.
+type ServerError struct {
+	Code    byte
+	Message string
+}
+
+// Encode записывает ServerError в writer.
+// Body: Code(1) + MsgLen(2) + Msg.
+func (m *ServerError) Encode(w io.Writer) error {
+	msgBytes := []byte(m.Message)
+	if len(msgBytes) > MaxErrorMsgLen {
+		msgBytes = msgBytes[:MaxErrorMsgLen]
+	}
+
+	totalLen := 1 + 2 + len(msgBytes)
+	buf := make([]byte, FrameHeaderSize+totalLen)
+	buf[0] = TypeServerError
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], uint32(totalLen))
+	buf[FrameHeaderSize] = m.Code
+	binary.BigEndian.PutUint16(buf[FrameHeaderSize+1:], uint16(len(msgBytes)))
+	copy(buf[FrameHeaderSize+3:], msgBytes)
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// DecodeServerError читает ServerError из reader (без байта типа).
+func DecodeServerError(r io.Reader) (*ServerError, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read total len: %w", err)
+	}
+	totalLen := binary.BigEndian.Uint32(lenBuf[:])
+	if totalLen < 1+2 || totalLen > 1+2+MaxErrorMsgLen {
+		return nil, fmt.Errorf("invalid error frame length: %d", totalLen)
+	}
+
+	data := make([]byte, totalLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read error data: %w", err)
+	}
+
+	msgLen := int(binary.BigEndian.Uint16(data[1:3]))
+	if msgLen != len(data)-3 {
+		return nil, fmt.Errorf("invalid error message length")
+	}
+
+	return &ServerError{Code: data[0], Message: string(data[3:])}, nil
+}
+
+// ServerGoAway — сервер завершает работу и закроет соединение
+// после отправки очереди. Addr — адрес сервера для переподключения (может быть пустым).
+type ServerGoAway struct {
+	Addr string
+}
+
+// Encode записывает ServerGoAway в writer.
+// Body: AddrLen(2) + Addr.
+func (m *ServerGoAway) Encode(w io.Writer) error {
+	if len(m.Addr) > MaxGoAwayAddr {
+		return fmt.Errorf("go away address too long: %d > %d", len(m.Addr), MaxGoAwayAddr)
+	}
+
+	totalLen := 2 + len(m.Addr)
+	buf := make([]byte, FrameHeaderSize+totalLen)
+	buf[0] = TypeServerGoAway
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], uint32(totalLen))
+	binary.BigEndian.PutUint16(buf[FrameHeaderSize:], uint16(len(m.Addr)))
+	copy(buf[FrameHeaderSize+2:], m.Addr)
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write go away: %w", err)
+	}
+	return nil
+}
+
+// DecodeServerGoAway читает ServerGoAway из reader (без байта типа).
+func DecodeServerGoAway(r io.Reader) (*ServerGoAway, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read total len: %w", err)
+	}
+	totalLen := binary.BigEndian.Uint32(lenBuf[:])
+	if totalLen < 2 || totalLen > 2+MaxGoAwayAddr {
+		return nil, fmt.Errorf("invalid go away frame length: %d", totalLen)
+	}
+
+	data := make([]byte, totalLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read go away data: %w", err)
+	}
+
+	addrLen := int(binary.BigEndian.Uint16(data[:2]))
+	if addrLen != len(data)-2 {
+		return nil, fmt.Errorf("invalid go away address length")
+	}
+
+	return &ServerGoAway{Addr: string(data[2:])}, nil
+}
+
+// ClientPing — проверка живости соединения клиентом.
+// Seq возвращается в ServerPong и позволяет сопоставить ответ запросу.
+type ClientPing struct {
+	Seq uint64
+}
+
+// Encode записывает ClientPing в writer.
+// Body: Seq(8).
+func (m *ClientPing) Encode(w io.Writer) error {
+	if err := writePingFrame(w, TypeClientPing, m.Seq); err != nil {
+		return fmt.Errorf("write ping: %w", err)
+	}
+	return nil
+}
+
+// DecodeClientPing читает ClientPing из reader (без байта типа).
+func DecodeClientPing(r io.Reader) (*ClientPing, error) {
+	seq, err := readPingFrame(r)
+	if err != nil {
+		return nil, fmt.Errorf("read ping: %w", err)
+	}
+	return &ClientPing{Seq: seq}, nil
+}
+
+// ServerPong — ответ сервера на ClientPing.
+type ServerPong struct {
+	Seq uint64
+}
+
+// Encode записывает ServerPong в writer.
+// Body: Seq(8).
+func (m *ServerPong) Encode(w io.Writer) error {
+	if err := writePingFrame(w, TypeServerPong, m.Seq); err != nil {
+		return fmt.Errorf("write pong: %w", err)
+	}
+	return nil
+}
+
+// DecodeServerPong читает ServerPong из reader (без байта типа).
+func DecodeServerPong(r io.Reader) (*ServerPong, error) {
+	seq, err := readPingFrame(r)
+	if err != nil {
+		return nil, fmt.Errorf("read pong: %w", err)
+	}
+	return &ServerPong{Seq: seq}, nil
+}
+
+// writePingFrame записывает кадр ping/pong одним вызовом Write.
+func writePingFrame(w io.Writer, frameType byte, seq uint64) error {
+	var buf [FrameHeaderSize + PingSeqSize]byte
+	buf[0] = frameType
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], PingSeqSize)
+	binary.BigEndian.PutUint64(buf[FrameHeaderSize:], seq)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// readPingFrame читает Len и Seq кадра ping/pong.
+func readPingFrame(r io.Reader) (uint64, error) {
+	var buf [4 + PingSeqSize]byte
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return 0, fmt.Errorf("read total len: %w", err)
+	}
+	if totalLen := binary.BigEndian.Uint32(buf[:4]); totalLen != PingSeqSize {
+		return 0, fmt.Errorf("invalid frame length: %d", totalLen)
+	}
+	if _, err := io.ReadFull(r, buf[4:]); err != nil {
+		return 0, fmt.Errorf("read seq: %w", err)
+	}
+	return binary.BigEndian.Uint64(buf[4:]), nil
+}
+
+// writeFrameHeader записывает заголовок кадра: Type(1) + Len(4).
+func writeFrameHeader(w io.Writer, frameType byte, bodyLen int) error {
+	var header [FrameHeaderSize]byte
+	header[0] = frameType
+	binary.BigEndian.PutUint32(header[1:], uint32(bodyLen))
+	if _, err := w.Write(header[:]); err != nil {
+		return fmt.Errorf("write frame header: %w", err)
+	}
+	return nil
+}
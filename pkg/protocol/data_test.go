@@ -20,7 +20,12 @@ func TestClientMessageEncodeDecode(t *testing.T) {
 		t.Fatalf("encode: %v", err)
 	}
 
-	decoded, err := DecodeClientMessage(&buf)
+	data := buf.Bytes()
+	if data[0] != TypeClientMessage {
+		t.Errorf("type: got %d, want %d", data[0], TypeClientMessage)
+	}
+
+	decoded, err := DecodeClientMessage(bytes.NewReader(data[1:]))
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -36,6 +41,27 @@ func TestClientMessageEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestClientMessageFrameType(t *testing.T) {
+	to := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	for _, frameType := range []byte{TypeClientGroupMessage, TypeClientGroupControl} {
+		msg := &ClientMessage{Type: frameType, To: to, MsgID: "group-msg", Payload: []byte("hi")}
+
+		var buf bytes.Buffer
+		if err := msg.Encode(&buf); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		if got := buf.Bytes()[0]; got != frameType {
+			t.Errorf("type: got %d, want %d", got, frameType)
+		}
+	}
+
+	msg := &ClientMessage{Type: TypeServerAck, To: to}
+	if err := msg.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for non-message frame type")
+	}
+}
+
 func TestClientMessageInvalidTo(t *testing.T) {
 	msg := &ClientMessage{
 		To:      "too_short",
@@ -66,6 +92,34 @@ func TestClientMessageTooLong(t *testing.T) {
 	}
 }
 
+func TestClientReceiptEncodeDecode(t *testing.T) {
+	to := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	original := &ClientReceipt{
+		To:    to,
+		MsgID: "test-msg-123",
+	}
+
+	var buf bytes.Buffer
+	if err := original.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	data := buf.Bytes()
+	if data[0] != TypeClientReceipt {
+		t.Errorf("type: got %d, want %d", data[0], TypeClientReceipt)
+	}
+
+	decoded, err := DecodeClientReceipt(bytes.NewReader(data[1:]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if *decoded != *original {
+		t.Errorf("receipt: got %+v, want %+v", decoded, original)
+	}
+}
+
 func TestServerMessageEncodeDecode(t *testing.T) {
 	original := &ServerMessage{
 		Data: []byte("test protobuf data"),
@@ -76,7 +130,12 @@ func TestServerMessageEncodeDecode(t *testing.T) {
 		t.Fatalf("encode: %v", err)
 	}
 
-	decoded, err := DecodeServerMessage(&buf)
+	data := buf.Bytes()
+	if data[0] != TypeServerMessage {
+		t.Errorf("type: got %d, want %d", data[0], TypeServerMessage)
+	}
+
+	decoded, err := DecodeServerMessage(bytes.NewReader(data[1:]))
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -97,3 +156,176 @@ func TestServerMessageTooLarge(t *testing.T) {
 		t.Error("expected error for too large message")
 	}
 }
+
+func TestServerAckEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		status byte
+		msgID  string
+		reason string
+	}{
+		{"accepted", AckStatusAccepted, "msg-1", ""},
+		{"rejected", AckStatusRejected, "msg-2", "publish failed"},
+		{"recipient_unknown", AckStatusRecipientUnknown, "msg-3", "invalid recipient"},
+		{"empty_msg_id", AckStatusAccepted, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &ServerAck{
+				Status: tt.status,
+				MsgID:  tt.msgID,
+				Reason: tt.reason,
+			}
+
+			var buf bytes.Buffer
+			if err := original.Encode(&buf); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			data := buf.Bytes()
+			if data[0] != TypeServerAck {
+				t.Errorf("type: got %d, want %d", data[0], TypeServerAck)
+			}
+
+			decoded, err := DecodeServerAck(bytes.NewReader(data[1:]))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if *decoded != *original {
+				t.Errorf("ack: got %+v, want %+v", decoded, original)
+			}
+		})
+	}
+}
+
+func TestServerAckMsgIDTooLong(t *testing.T) {
+	ack := &ServerAck{
+		Status: AckStatusAccepted,
+		MsgID:  string(make([]byte, MaxMsgIDLen+1)),
+	}
+
+	var buf bytes.Buffer
+	if err := ack.Encode(&buf); err == nil {
+		t.Error("expected error for too long msg_id")
+	}
+}
+
+func TestServerErrorEncodeDecode(t *testing.T) {
+	original := &ServerError{
+		Code:    ErrorCodeRateLimited,
+		Message: "rate limit exceeded",
+	}
+
+	var buf bytes.Buffer
+	if err := original.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	data := buf.Bytes()
+	if data[0] != TypeServerError {
+		t.Errorf("type: got %d, want %d", data[0], TypeServerError)
+	}
+
+	decoded, err := DecodeServerError(bytes.NewReader(data[1:]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if *decoded != *original {
+		t.Errorf("error: got %+v, want %+v", decoded, original)
+	}
+}
+
+func TestServerErrorMessageTruncated(t *testing.T) {
+	original := &ServerError{
+		Code:    ErrorCodeProtocol,
+		Message: string(bytes.Repeat([]byte("x"), MaxErrorMsgLen+100)),
+	}
+
+	var buf bytes.Buffer
+	if err := original.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	decoded, err := DecodeServerError(bytes.NewReader(buf.Bytes()[1:]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(decoded.Message) != MaxErrorMsgLen {
+		t.Errorf("message len: got %d, want %d", len(decoded.Message), MaxErrorMsgLen)
+	}
+}
+
+func TestServerGoAwayEncodeDecode(t *testing.T) {
+	for _, addr := range []string{"", "sprut-2.example.com:8443"} {
+		original := &ServerGoAway{Addr: addr}
+
+		var buf bytes.Buffer
+		if err := original.Encode(&buf); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+
+		data := buf.Bytes()
+		if data[0] != TypeServerGoAway {
+			t.Errorf("type: got %d, want %d", data[0], TypeServerGoAway)
+		}
+
+		decoded, err := DecodeServerGoAway(bytes.NewReader(data[1:]))
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if *decoded != *original {
+			t.Errorf("go away: got %+v, want %+v", decoded, original)
+		}
+	}
+}
+
+func TestServerGoAwayAddrTooLong(t *testing.T) {
+	m := &ServerGoAway{Addr: string(bytes.Repeat([]byte("a"), MaxGoAwayAddr+1))}
+	if err := m.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for too long address")
+	}
+}
+
+func TestPingPongEncodeDecode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&ClientPing{Seq: 42}).Encode(&buf); err != nil {
+		t.Fatalf("encode ping: %v", err)
+	}
+	if err := (&ServerPong{Seq: 1<<63 + 7}).Encode(&buf); err != nil {
+		t.Fatalf("encode pong: %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if typ, _ := r.ReadByte(); typ != TypeClientPing {
+		t.Fatalf("type: got %d, want %d", typ, TypeClientPing)
+	}
+	ping, err := DecodeClientPing(r)
+	if err != nil {
+		t.Fatalf("decode ping: %v", err)
+	}
+	if ping.Seq != 42 {
+		t.Errorf("ping seq: got %d, want 42", ping.Seq)
+	}
+
+	if typ, _ := r.ReadByte(); typ != TypeServerPong {
+		t.Fatalf("type: got %d, want %d", typ, TypeServerPong)
+	}
+	pong, err := DecodeServerPong(r)
+	if err != nil {
+		t.Fatalf("decode pong: %v", err)
+	}
+	if pong.Seq != 1<<63+7 {
+		t.Errorf("pong seq: got %d, want %d", pong.Seq, uint64(1<<63+7))
+	}
+}
+
+func TestPingInvalidLength(t *testing.T) {
+	data := []byte{0, 0, 0, 4, 1, 2, 3, 4}
+	if _, err := DecodeClientPing(bytes.NewReader(data)); err == nil {
+		t.Error("expected error for invalid ping length")
+	}
+}
@@ -12,6 +12,9 @@ var (
 	// ErrChallengeExpired — challenge истёк (replay attack protection).
 	ErrChallengeExpired = errors.New("challenge expired")
 
+	// ErrUnsupportedVersion — клиент подписал рукопожатие прежней версией протокола.
+	ErrUnsupportedVersion = errors.New("unsupported protocol version " + LegacyProtocolVersion + ", update the client")
+
 	// ErrConnectionClosed — соединение закрыто.
 	ErrConnectionClosed = errors.New("connection closed")
 )
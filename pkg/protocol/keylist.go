@@ -0,0 +1,104 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// KeyList — список ключей, заменяющий предыдущий список того же типа.
+// Type: TypeClientPresenceWatch, TypeClientPresenceAllow,
+// TypeClientBlockList или TypeClientContactList.
+type KeyList struct {
+	Type byte
+	Keys []string // hex-encoded публичные ключи (по 64 символа)
+}
+
+// MaxKeyListKeys возвращает максимальное число ключей в списке типа frameType
+// или 0, если frameType не является списком ключей.
+func MaxKeyListKeys(frameType byte) int {
+	switch frameType {
+	case TypeClientPresenceWatch, TypeClientPresenceAllow:
+		return MaxPresenceKeys
+	case TypeClientBlockList, TypeClientContactList:
+		return MaxPolicyListKeys
+	default:
+		return 0
+	}
+}
+
+// Encode записывает KeyList в writer.
+// Body: Count(2) + Keys(64 * Count).
+func (m *KeyList) Encode(w io.Writer) error {
+	maxKeys := MaxKeyListKeys(m.Type)
+	if maxKeys == 0 {
+		return fmt.Errorf("invalid key list type: %d", m.Type)
+	}
+	if len(m.Keys) > maxKeys {
+		return fmt.Errorf("too many keys: %d > %d", len(m.Keys), maxKeys)
+	}
+
+	totalLen := 2 + len(m.Keys)*PublicKeySize*2
+	buf := make([]byte, FrameHeaderSize+totalLen)
+	buf[0] = m.Type
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], uint32(totalLen))
+	binary.BigEndian.PutUint16(buf[FrameHeaderSize:], uint16(len(m.Keys)))
+
+	offset := FrameHeaderSize + 2
+	for _, key := range m.Keys {
+		if len(key) != PublicKeySize*2 {
+			return fmt.Errorf("invalid key length: expected %d, got %d", PublicKeySize*2, len(key))
+		}
+		offset += copy(buf[offset:], key)
+	}
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write key list: %w", err)
+	}
+	return nil
+}
+
+// DecodeKeyList читает KeyList из reader (без байта типа).
+func DecodeKeyList(r io.Reader, frameType byte) (*KeyList, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read total len: %w", err)
+	}
+	totalLen := binary.BigEndian.Uint32(lenBuf[:])
+	if totalLen > uint32(2+MaxKeyListKeys(frameType)*PublicKeySize*2) {
+		return nil, fmt.Errorf("key list too large: %d", totalLen)
+	}
+
+	data := make([]byte, totalLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read key list: %w", err)
+	}
+
+	keys, err := ParseKeyList(frameType, data)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyList{Type: frameType, Keys: keys}, nil
+}
+
+// ParseKeyList разбирает body кадра KeyList типа frameType.
+// Формат ключей (hex) не проверяется.
+func ParseKeyList(frameType byte, body []byte) ([]string, error) {
+	if len(body) < 2 {
+		return nil, fmt.Errorf("key list too short")
+	}
+	count := int(binary.BigEndian.Uint16(body[:2]))
+	if maxKeys := MaxKeyListKeys(frameType); count > maxKeys {
+		return nil, fmt.Errorf("too many keys: %d > %d", count, maxKeys)
+	}
+	body = body[2:]
+	if len(body) != count*PublicKeySize*2 {
+		return nil, fmt.Errorf("invalid key list length: %d keys, %d bytes", count, len(body))
+	}
+
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = string(body[i*PublicKeySize*2 : (i+1)*PublicKeySize*2])
+	}
+	return keys, nil
+}
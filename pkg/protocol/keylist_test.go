@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeyListEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		frameType byte
+		keys      []string
+	}{
+		{"watch", TypeClientPresenceWatch, []string{strings.Repeat("a", 64), strings.Repeat("b", 64)}},
+		{"allow", TypeClientPresenceAllow, []string{strings.Repeat("c", 64)}},
+		{"empty", TypeClientPresenceAllow, []string{}},
+		{"block", TypeClientBlockList, []string{strings.Repeat("d", 64)}},
+		{"contacts", TypeClientContactList, []string{strings.Repeat("e", 64)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &KeyList{Type: tt.frameType, Keys: tt.keys}
+
+			var buf bytes.Buffer
+			if err := original.Encode(&buf); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			data := buf.Bytes()
+			if data[0] != tt.frameType {
+				t.Errorf("type: got %d, want %d", data[0], tt.frameType)
+			}
+
+			decoded, err := DecodeKeyList(bytes.NewReader(data[1:]), data[0])
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Type != tt.frameType {
+				t.Errorf("type: got %d, want %d", decoded.Type, tt.frameType)
+			}
+			if !slices.Equal(decoded.Keys, tt.keys) {
+				t.Errorf("keys: got %v, want %v", decoded.Keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestKeyListInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		list KeyList
+	}{
+		{"wrong_type", KeyList{Type: TypeClientMessage}},
+		{"short_key", KeyList{Type: TypeClientPresenceWatch, Keys: []string{"abc"}}},
+		{"too_many", KeyList{Type: TypeClientPresenceWatch, Keys: make([]string, MaxPresenceKeys+1)}},
+		{"too_many_blocked", KeyList{Type: TypeClientBlockList, Keys: make([]string, MaxPolicyListKeys+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.list.Encode(&buf); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseKeyListInvalidLength(t *testing.T) {
+	// Count = 2, но передан только один ключ
+	body := append([]byte{0x00, 0x02}, strings.Repeat("a", 64)...)
+	if _, err := ParseKeyList(TypeClientPresenceWatch, body); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,58 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ServerPresence — изменение статуса ключа, на который подписан клиент.
+type ServerPresence struct {
+	Key          string // hex-encoded публичный ключ (64 символа)
+	Status       byte   // PresenceOnline или PresenceOffline
+	UnixDateTime int64  // время подключения или отключения, 0 если неизвестно
+}
+
+// serverPresenceSize — размер body ServerPresence: Key(64) + Status(1) + Time(8).
+const serverPresenceSize = PublicKeySize*2 + 1 + 8
+
+// Encode записывает ServerPresence в writer.
+func (m *ServerPresence) Encode(w io.Writer) error {
+	if len(m.Key) != PublicKeySize*2 {
+		return fmt.Errorf("invalid key length: expected %d, got %d", PublicKeySize*2, len(m.Key))
+	}
+
+	var buf [FrameHeaderSize + serverPresenceSize]byte
+	buf[0] = TypeServerPresence
+	binary.BigEndian.PutUint32(buf[1:FrameHeaderSize], serverPresenceSize)
+	offset := FrameHeaderSize + copy(buf[FrameHeaderSize:], m.Key)
+	buf[offset] = m.Status
+	binary.BigEndian.PutUint64(buf[offset+1:], uint64(m.UnixDateTime))
+
+	if _, err := w.Write(buf[:]); err != nil {
+		return fmt.Errorf("write presence: %w", err)
+	}
+	return nil
+}
+
+// DecodeServerPresence читает ServerPresence из reader (без байта типа).
+func DecodeServerPresence(r io.Reader) (*ServerPresence, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, fmt.Errorf("read total len: %w", err)
+	}
+	if totalLen := binary.BigEndian.Uint32(lenBuf[:]); totalLen != serverPresenceSize {
+		return nil, fmt.Errorf("invalid presence frame length: %d", totalLen)
+	}
+
+	var data [serverPresenceSize]byte
+	if _, err := io.ReadFull(r, data[:]); err != nil {
+		return nil, fmt.Errorf("read presence data: %w", err)
+	}
+
+	return &ServerPresence{
+		Key:          string(data[:PublicKeySize*2]),
+		Status:       data[PublicKeySize*2],
+		UnixDateTime: int64(binary.BigEndian.Uint64(data[PublicKeySize*2+1:])),
+	}, nil
+}
@@ -0,0 +1,33 @@
+package protocol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestServerPresenceEncodeDecode(t *testing.T) {
+	original := &ServerPresence{
+		Key:          strings.Repeat("d", 64),
+		Status:       PresenceOnline,
+		UnixDateTime: 1700000000,
+	}
+
+	var buf bytes.Buffer
+	if err := original.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	data := buf.Bytes()
+	if data[0] != TypeServerPresence {
+		t.Errorf("type: got %d, want %d", data[0], TypeServerPresence)
+	}
+
+	decoded, err := DecodeServerPresence(bytes.NewReader(data[1:]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *decoded != *original {
+		t.Errorf("presence: got %+v, want %+v", decoded, original)
+	}
+}
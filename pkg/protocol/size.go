@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Размеры сообщений рукопожатия фиксированной длины, включая байт типа.
+const (
+	clientHelloSize     = 1 + PublicKeySize
+	serverChallengeSize = 1 + ChallengeSize + TimestampSize + ServerIDSize
+	clientResponseSize  = 1 + SignatureSize
+	clientCertHelloSize = 1
+)
+
+// MessageSize возвращает полный размер сообщения (включая байт типа),
+// начало которого лежит в prefix. 0 — для определения размера нужно
+// больше байт. Используется транспортами, передающими каждое сообщение
+// протокола отдельно (WebSocket).
+func MessageSize(prefix []byte) (int, error) {
+	if len(prefix) == 0 {
+		return 0, nil
+	}
+
+	switch t := prefix[0]; {
+	case t == TypeClientHello:
+		return clientHelloSize, nil
+	case t == TypeServerChallenge:
+		return serverChallengeSize, nil
+	case t == TypeClientResponse:
+		return clientResponseSize, nil
+	case t == TypeClientCertHello:
+		return clientCertHelloSize, nil
+	case t == TypeClientToken:
+		// Type(1) + Len(2) + Token
+		if len(prefix) < 3 {
+			return 0, nil
+		}
+		return 3 + int(binary.BigEndian.Uint16(prefix[1:3])), nil
+	case t == TypeAuthResult:
+		// Type(1) + Status(1) [+ Len(2) + ErrorMsg]
+		if len(prefix) < 2 {
+			return 0, nil
+		}
+		if prefix[1] == AuthStatusOK {
+			return 2, nil
+		}
+		if len(prefix) < 4 {
+			return 0, nil
+		}
+		return 4 + int(binary.BigEndian.Uint16(prefix[2:4])), nil
+	case t >= TypeClientMessage && t <= TypeServerPong:
+		if len(prefix) < FrameHeaderSize {
+			return 0, nil
+		}
+		return FrameHeaderSize + int(binary.BigEndian.Uint32(prefix[1:FrameHeaderSize])), nil
+	default:
+		return 0, fmt.Errorf("unknown message type: %d", t)
+	}
+}
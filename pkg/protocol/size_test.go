@@ -0,0 +1,58 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMessageSize(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  interface{ Encode(io.Writer) error }
+	}{
+		{"client hello", &ClientHello{}},
+		{"server challenge", &ServerChallenge{Timestamp: 1}},
+		{"client response", &ClientResponse{}},
+		{"client token", &ClientToken{Token: "header.payload.signature"}},
+		{"client cert hello", &ClientCertHello{}},
+		{"auth result ok", &AuthResult{Status: AuthStatusOK}},
+		{"auth result failed", &AuthResult{Status: AuthStatusFailed, ErrorMsg: "invalid signature"}},
+		{"client message", &ClientMessage{To: string(bytes.Repeat([]byte("a"), 64)), MsgID: "m1", Payload: []byte("hi")}},
+		{"server message", &ServerMessage{Data: []byte("data")}},
+		{"server ack", &ServerAck{Status: AckStatusAccepted, MsgID: "m1"}},
+		{"server go away", &ServerGoAway{Addr: "sprut-2:8443"}},
+		{"client ping", &ClientPing{Seq: 1}},
+		{"server pong", &ServerPong{Seq: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.msg.Encode(&buf); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			data := buf.Bytes()
+
+			// Любой префикс либо ещё не определяет размер, либо определяет его верно
+			for i := range len(data) + 1 {
+				size, err := MessageSize(data[:i])
+				if err != nil {
+					t.Fatalf("prefix %d: %v", i, err)
+				}
+				if size != 0 && size != len(data) {
+					t.Fatalf("prefix %d: size %d, want %d", i, size, len(data))
+				}
+			}
+			if size, _ := MessageSize(data); size != len(data) {
+				t.Errorf("size: got %d, want %d", size, len(data))
+			}
+		})
+	}
+}
+
+func TestMessageSizeUnknownType(t *testing.T) {
+	if _, err := MessageSize([]byte{0x7F}); err == nil {
+		t.Error("expected error for unknown message type")
+	}
+}
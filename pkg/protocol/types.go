@@ -9,6 +9,55 @@ const (
 	TypeAuthResult      byte = 0x04
 )
 
+// Типы рукопожатия: первый байт от клиента выбирает способ аутентификации.
+// TypeClientHello — ed25519 challenge/response.
+const (
+	TypeClientToken     byte = 0x05 // подписанный JWT: Type(1) + Len(2) + Token
+	TypeClientCertHello byte = 0x06 // PeerID из клиентского TLS сертификата
+)
+
+// Типы кадров после аутентификации.
+// Формат кадра: Type(1) + Len(4) + Body(Len).
+const (
+	TypeClientMessage byte = 0x10
+	TypeServerMessage byte = 0x11
+	TypeServerAck     byte = 0x12
+	TypeClientReceipt byte = 0x13
+	TypeServerError   byte = 0x14
+
+	TypeClientPresenceWatch byte = 0x15 // чьи статусы клиент хочет получать
+	TypeClientPresenceAllow byte = 0x16 // кому разрешено видеть статус клиента
+	TypeServerPresence      byte = 0x17
+
+	// Кадры групп имеют формат ClientMessage, где To — id группы.
+	TypeClientGroupMessage byte = 0x18
+	TypeClientGroupControl byte = 0x19 // payload — подписанный message.GroupControl
+
+	// Списки получателя для политики авторизации, формат KeyList.
+	// Сервер отвечает ServerAck с MsgID BlockListAckID или ContactListAckID.
+	TypeClientBlockList   byte = 0x1A // от кого клиент не принимает сообщения
+	TypeClientContactList byte = 0x1B // контакты клиента (режим "только контакты")
+
+	// TypeServerGoAway — сервер завершает работу: клиенту следует
+	// переподключиться, при наличии адреса — к указанному серверу.
+	TypeServerGoAway byte = 0x1C
+
+	// Проверка живости соединения: клиент шлёт ClientPing,
+	// сервер отвечает ServerPong с тем же Seq.
+	TypeClientPing byte = 0x1D
+	TypeServerPong byte = 0x1E
+)
+
+// MsgID ServerAck на списки политики: AckStatusAccepted — список сохранён,
+// AckStatusRejected — не сохранён, клиенту следует повторить.
+const (
+	BlockListAckID   = "policy:block_list"
+	ContactListAckID = "policy:contact_list"
+)
+
+// FrameHeaderSize — размер заголовка кадра: Type(1) + Len(4).
+const FrameHeaderSize = 5
+
 // Размеры полей
 const (
 	PublicKeySize      = 32
@@ -17,6 +66,7 @@ const (
 	ServerIDSize       = 32
 	SignatureSize      = 64
 	ChannelBindingSize = 32
+	PingSeqSize        = 8
 )
 
 // ChannelBinding — alias для обратной совместимости.
@@ -26,16 +76,58 @@ const ChannelBinding = ChannelBindingSize
 // Статусы аутентификации
 const (
 	AuthStatusOK         byte = 0x00
+	AuthStatusFailed     byte = 0x01
 	AuthStatusInvalidSig byte = 0x02
 	AuthStatusReplay     byte = 0x03
 )
 
-// Версия протокола для подписи
-const ProtocolVersion = "goro-auth-v1"
+// Статусы подтверждения сообщения сервером
+const (
+	AckStatusAccepted         byte = 0x00
+	AckStatusRejected         byte = 0x01
+	AckStatusRecipientUnknown byte = 0x02
+	AckStatusForbidden        byte = 0x03 // запрещено политикой авторизации
+	AckStatusPartial          byte = 0x04 // сообщение группе доставлено не всем участникам
+)
+
+// Коды ошибок в кадре ServerError.
+// После кадра ошибки сервер закрывает соединение.
+const (
+	ErrorCodeInternal        byte = 0x01
+	ErrorCodeProtocol        byte = 0x02
+	ErrorCodeMessageTooLarge byte = 0x03
+	ErrorCodeRateLimited     byte = 0x04
+	ErrorCodeSlowConsumer    byte = 0x05
+	ErrorCodeReplaced        byte = 0x06 // подключился другой клиент с тем же ключом
+	ErrorCodeKicked          byte = 0x07 // отключён оператором
+)
+
+// Статусы в кадре ServerPresence
+const (
+	PresenceOffline byte = 0x00
+	PresenceOnline  byte = 0x01
+)
+
+// Версия протокола для подписи.
+// v2 несовместима с v1: кадры после аутентификации получили тип, заголовок
+// Type(1) + Len(4) вместо Len(4) (FrameHeaderSize). Подпись клиента v1 сервер
+// отклоняет с ErrUnsupportedVersion, а не рассинхронизируется на первом кадре.
+const ProtocolVersion = "goro-auth-v2"
+
+// LegacyProtocolVersion — предыдущая версия протокола. Той же длины, что
+// ProtocolVersion: сервер узнаёт подпись клиента v1 в том же буфере.
+const LegacyProtocolVersion = "goro-auth-v1"
 
 // Максимальные размеры
 const (
 	MaxMessageSize  = 65536        // 64KB
 	MaxMsgIDLen     = 256
 	MaxErrorMsgLen  = 1024
+	MaxPresenceKeys = 256
+	MaxTokenSize    = 8192
+	MaxGoAwayAddr   = 255
+
+	// MaxPolicyListKeys — ключей в блок-листе или списке контактов:
+	// кадр помещается в MaxMessageSize.
+	MaxPolicyListKeys = 1000
 )
@@ -0,0 +1,310 @@
+// Package proxyproto читает заголовок PROXY protocol v1 и v2 (HAProxy), которым
+// балансировщик передаёт адрес клиента перед данными TCP соединения.
+// Заголовку можно верить только от своего балансировщика: listener с PROXY
+// protocol не должен быть доступен клиентам напрямую.
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoHeader — соединение начинается не с заголовка PROXY protocol.
+var ErrNoHeader = errors.New("proxyproto: no PROXY header")
+
+// Сигнатура заголовка v2 и начало заголовка v1.
+var (
+	signatureV2 = []byte("\r\n\r\n\x00\r\nQUIT\n")
+	prefixV1    = []byte("PROXY ")
+)
+
+const (
+	// maxHeaderV1 — максимальная длина заголовка v1 вместе с CRLF.
+	maxHeaderV1 = 107
+
+	commandLocal = 0x0
+	commandProxy = 0x1
+
+	familyTCP4 = 0x11
+	familyUDP4 = 0x12
+	familyTCP6 = 0x21
+	familyUDP6 = 0x22
+)
+
+// Header — адреса соединения из заголовка. Source и Destination равны nil,
+// если балансировщик не передал адреса (LOCAL в v2, UNKNOWN в v1) —
+// например, для собственных проверок здоровья.
+type Header struct {
+	Version     int
+	Source      net.Addr
+	Destination net.Addr
+}
+
+// ReadHeader читает заголовок из r, не захватывая следующие за ним данные.
+func ReadHeader(r io.Reader) (*Header, error) {
+	// 12 байт — сигнатура v2; самый короткий заголовок v1 ("PROXY UNKNOWN\r\n") длиннее
+	buf := make([]byte, len(signatureV2), maxHeaderV1)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("proxyproto: read header: %w", err)
+	}
+
+	switch {
+	case bytes.Equal(buf, signatureV2):
+		return readV2(r)
+	case bytes.HasPrefix(buf, prefixV1):
+		return readV1(r, buf)
+	default:
+		return nil, ErrNoHeader
+	}
+}
+
+// readV1 дочитывает текстовый заголовок до CRLF и разбирает его.
+func readV1(r io.Reader, buf []byte) (*Header, error) {
+	var b [1]byte
+	for !bytes.HasSuffix(buf, []byte("\r\n")) {
+		if len(buf) == maxHeaderV1 {
+			return nil, errors.New("proxyproto: v1 header too long")
+		}
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, fmt.Errorf("proxyproto: read v1 header: %w", err)
+		}
+		buf = append(buf, b[0])
+	}
+
+	fields := strings.Split(string(buf[len(prefixV1):len(buf)-2]), " ")
+	switch fields[0] {
+	case "UNKNOWN":
+		return &Header{Version: 1}, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("proxyproto: v1 unknown protocol %q", fields[0])
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("proxyproto: v1 malformed header %q", buf)
+	}
+
+	src, err := parseAddrV1(fields[1], fields[3], fields[0] == "TCP6")
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: v1 source: %w", err)
+	}
+	dst, err := parseAddrV1(fields[2], fields[4], fields[0] == "TCP6")
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: v1 destination: %w", err)
+	}
+	return &Header{Version: 1, Source: src, Destination: dst}, nil
+}
+
+func parseAddrV1(ip, port string, v6 bool) (*net.TCPAddr, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+	if addr.Is6() != v6 {
+		return nil, fmt.Errorf("address %s does not match protocol", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("port %q: %w", port, err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(p))), nil
+}
+
+// readV2 читает бинарный заголовок после сигнатуры.
+func readV2(r io.Reader) (*Header, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("proxyproto: read v2 header: %w", err)
+	}
+	if version := hdr[0] >> 4; version != 2 {
+		return nil, fmt.Errorf("proxyproto: v2 unsupported version %d", version)
+	}
+	command, family := hdr[0]&0x0f, hdr[1]
+	length := int(binary.BigEndian.Uint16(hdr[2:]))
+
+	var addrLen int
+	switch family {
+	case familyTCP4, familyUDP4:
+		addrLen = 2*4 + 2*2
+	case familyTCP6, familyUDP6:
+		addrLen = 2*16 + 2*2
+	}
+	if command != commandProxy || addrLen == 0 {
+		// LOCAL и семейства без IP адресов (UNSPEC, Unix): адреса не используются
+		if command != commandLocal && command != commandProxy {
+			return nil, fmt.Errorf("proxyproto: v2 unknown command %#x", command)
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return nil, fmt.Errorf("proxyproto: read v2 addresses: %w", err)
+		}
+		return &Header{Version: 2}, nil
+	}
+	if length < addrLen {
+		return nil, fmt.Errorf("proxyproto: v2 address block too short: %d < %d", length, addrLen)
+	}
+
+	addrs := make([]byte, addrLen)
+	if _, err := io.ReadFull(r, addrs); err != nil {
+		return nil, fmt.Errorf("proxyproto: read v2 addresses: %w", err)
+	}
+	// TLV расширения не используются
+	if _, err := io.CopyN(io.Discard, r, int64(length-addrLen)); err != nil {
+		return nil, fmt.Errorf("proxyproto: read v2 extensions: %w", err)
+	}
+
+	ipLen := (addrLen - 4) / 2
+	srcIP, _ := netip.AddrFromSlice(addrs[:ipLen])
+	dstIP, _ := netip.AddrFromSlice(addrs[ipLen : 2*ipLen])
+	srcPort := binary.BigEndian.Uint16(addrs[2*ipLen:])
+	dstPort := binary.BigEndian.Uint16(addrs[2*ipLen+2:])
+
+	h := &Header{Version: 2}
+	if family == familyUDP4 || family == familyUDP6 {
+		h.Source = net.UDPAddrFromAddrPort(netip.AddrPortFrom(srcIP, srcPort))
+		h.Destination = net.UDPAddrFromAddrPort(netip.AddrPortFrom(dstIP, dstPort))
+	} else {
+		h.Source = net.TCPAddrFromAddrPort(netip.AddrPortFrom(srcIP, srcPort))
+		h.Destination = net.TCPAddrFromAddrPort(netip.AddrPortFrom(dstIP, dstPort))
+	}
+	return h, nil
+}
+
+// Conn — соединение после заголовка PROXY protocol. RemoteAddr и LocalAddr
+// возвращают адреса из заголовка, если балансировщик их передал.
+type Conn struct {
+	net.Conn
+	header *Header
+}
+
+// Header возвращает прочитанный заголовок.
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+// RemoteAddr возвращает адрес клиента из заголовка.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr возвращает адрес, к которому подключился клиент, из заголовка.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// NetConn возвращает соединение с балансировщиком.
+func (c *Conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Listener возвращает соединения (*Conn), заголовок которых прочитан.
+// Заголовки читаются вне Accept: медленный клиент не задерживает остальных.
+type Listener struct {
+	net.Listener
+	timeout time.Duration
+	onError func(net.Conn, error)
+
+	conns     chan *Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+	acceptErr error // ошибка Accept исходного listener, читается после closed
+}
+
+// NewListener оборачивает lis. Соединение, не приславшее заголовок
+// за timeout или приславшее некорректный, закрывается; onError (может быть nil)
+// вызывается до закрытия.
+func NewListener(lis net.Listener, timeout time.Duration, onError func(net.Conn, error)) *Listener {
+	l := &Listener{
+		Listener: lis,
+		timeout:  timeout,
+		onError:  onError,
+		conns:    make(chan *Conn),
+		closed:   make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			l.closeOnce.Do(func() {
+				l.acceptErr = err
+				close(l.closed)
+			})
+			return
+		}
+		go l.serve(conn)
+	}
+}
+
+// serve читает заголовок соединения и передаёт соединение в Accept.
+func (l *Listener) serve(conn net.Conn) {
+	header, err := readHeaderTimeout(conn, l.timeout)
+	if err != nil {
+		if l.onError != nil {
+			l.onError(conn, err)
+		}
+		_ = conn.Close()
+		return
+	}
+
+	select {
+	case l.conns <- &Conn{Conn: conn, header: header}:
+	case <-l.closed:
+		_ = conn.Close()
+	}
+}
+
+// readHeaderTimeout читает заголовок, который должен прийти за timeout.
+func readHeaderTimeout(conn net.Conn, timeout time.Duration) (*Header, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	header, err := ReadHeader(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// Accept возвращает следующее соединение с прочитанным заголовком.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		if l.acceptErr != nil {
+			return nil, l.acceptErr
+		}
+		return nil, net.ErrClosed
+	}
+}
+
+// Close закрывает listener. Соединения, заголовок которых ещё читается, закрываются.
+func (l *Listener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.closed) })
+	return err
+}
@@ -0,0 +1,152 @@
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// headerV2 собирает заголовок v2 с адресами IPv4 и дополнительными байтами TLV.
+func headerV2(command, family byte, addrs []byte, tlv []byte) []byte {
+	var b bytes.Buffer
+	b.Write(signatureV2)
+	b.WriteByte(0x20 | command)
+	b.WriteByte(family)
+	_ = binary.Write(&b, binary.BigEndian, uint16(len(addrs)+len(tlv)))
+	b.Write(addrs)
+	b.Write(tlv)
+	return b.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	tcp4 := []byte{203, 0, 113, 7, 10, 0, 0, 1, 0x15, 0xb3, 0x01, 0xbb} // 203.0.113.7:5555 -> 10.0.0.1:443
+
+	tests := []struct {
+		name    string
+		input   []byte
+		version int
+		source  string // пустой — адреса не переданы
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 5555 443\r\n"), 1, "203.0.113.7:5555"},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 5555 443\r\n"), 1, "[2001:db8::7]:5555"},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), 1, ""},
+		{"v2 tcp4", headerV2(commandProxy, familyTCP4, tcp4, nil), 2, "203.0.113.7:5555"},
+		{"v2 tcp4 with tlv", headerV2(commandProxy, familyTCP4, tcp4, []byte{0x04, 0x00, 0x01, 0xff}), 2, "203.0.113.7:5555"},
+		{"v2 local", headerV2(commandLocal, 0x00, nil, nil), 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Данные после заголовка остаются в соединении
+			r := bytes.NewReader(append(tt.input, "payload"...))
+			h, err := ReadHeader(r)
+			if err != nil {
+				t.Fatalf("ReadHeader: %v", err)
+			}
+			if h.Version != tt.version {
+				t.Errorf("version = %d, want %d", h.Version, tt.version)
+			}
+			switch {
+			case tt.source == "" && h.Source != nil:
+				t.Errorf("source = %v, want none", h.Source)
+			case tt.source != "" && (h.Source == nil || h.Source.String() != tt.source):
+				t.Errorf("source = %v, want %s", h.Source, tt.source)
+			}
+			rest, _ := io.ReadAll(r)
+			if string(rest) != "payload" {
+				t.Errorf("data after header = %q, want %q", rest, "payload")
+			}
+		})
+	}
+}
+
+func TestReadHeaderInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"tls client hello", []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc, 0x03, 0x03, 0x00, 0x00}},
+		{"v1 no crlf", append([]byte("PROXY TCP4 "), bytes.Repeat([]byte("1"), 200)...)},
+		{"v1 bad address", []byte("PROXY TCP4 2001:db8::7 10.0.0.1 5555 443\r\n")},
+		{"v1 bad port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 70000 443\r\n")},
+		{"v1 unknown protocol", []byte("PROXY UDP4 203.0.113.7 10.0.0.1 5555 443\r\n")},
+		{"v2 short addresses", headerV2(commandProxy, familyTCP4, []byte{1, 2, 3}, nil)},
+		{"v2 truncated", headerV2(commandProxy, familyTCP4, []byte{203, 0, 113, 7}, nil)[:18]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadHeader(bytes.NewReader(tt.input)); err == nil {
+				t.Fatal("ReadHeader succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := ReadHeader(bytes.NewReader([]byte("GET / HTTP/1.1\r\n"))); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("plain request: got %v, want ErrNoHeader", err)
+	}
+}
+
+func TestListener(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	rejected := make(chan error, 1)
+	lis := NewListener(raw, 200*time.Millisecond, func(_ net.Conn, err error) { rejected <- err })
+	defer lis.Close()
+
+	dial := func(data string) net.Conn {
+		conn, err := net.Dial("tcp", raw.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		if data != "" {
+			if _, err := conn.Write([]byte(data)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+		return conn
+	}
+
+	// Соединение без заголовка не задерживает следующее и закрывается по таймауту
+	dial("")
+	dial("PROXY TCP4 203.0.113.7 10.0.0.1 5555 443\r\nhello")
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer conn.Close()
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:5555" {
+		t.Errorf("remote addr = %s, want 203.0.113.7:5555", got)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("read after header: %q, %v", buf, err)
+	}
+
+	select {
+	case err := <-rejected:
+		if !isTimeout(err) {
+			t.Errorf("rejected with %v, want timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection without header not rejected")
+	}
+
+	if err := lis.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := lis.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("accept after close: got %v, want net.ErrClosed", err)
+	}
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
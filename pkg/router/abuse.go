@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+)
+
+// abuseTracker считает нарушения источников (адресов и ключей)
+// в фиксированном окне, начинающемся с первого нарушения.
+type abuseTracker struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	counts map[string]abuseCount
+}
+
+type abuseCount struct {
+	n     int
+	start time.Time
+}
+
+func newAbuseTracker(window time.Duration) *abuseTracker {
+	return &abuseTracker{window: window, counts: make(map[string]abuseCount)}
+}
+
+// hit учитывает нарушение source и возвращает число нарушений в текущем окне.
+func (t *abuseTracker) hit(source string, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counts[source]
+	if now.Sub(c.start) >= t.window {
+		c = abuseCount{start: now}
+	}
+	c.n++
+	t.counts[source] = c
+	return c.n
+}
+
+// reset забывает нарушения source.
+func (t *abuseTracker) reset(source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.counts, source)
+}
+
+// prune удаляет счётчики с истёкшим окном.
+func (t *abuseTracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for source, c := range t.counts {
+		if now.Sub(c.start) >= t.window {
+			delete(t.counts, source)
+		}
+	}
+}
+
+// recordAuthFailure учитывает неудачную аутентификацию с адреса addr
+// (reason — значение authFailureReason). Ключ при неудачной аутентификации
+// не подтверждён, поэтому блокируется только адрес.
+func (s *server) recordAuthFailure(addr netip.Addr, reason string) {
+	threshold := s.cfg.Bans.Auto.AuthFailures
+	if threshold == 0 || !isAuthAbuse(reason) || !s.bannableAddr(addr) {
+		return
+	}
+	s.autoBan("auth:"+addr.String(), threshold, "auth_failures",
+		admin.Ban{CIDR: addr.String(), Reason: fmt.Sprintf("%d failed authentications", threshold)})
+}
+
+// isAuthAbuse сообщает, считается ли отказ в аутентификации нарушением.
+// Таймаут, истёкший токен или challenge и неподдерживаемый способ случаются
+// у честных клиентов (медленная мобильная сеть, старая версия), обрыв
+// соединения и заблокированный ключ — не повод блокировать адрес.
+func isAuthAbuse(reason string) bool {
+	switch reason {
+	case "invalid_signature", "replay", "rejected":
+		return true
+	default:
+		return false
+	}
+}
+
+// bannableAddr сообщает, можно ли автоматически блокировать адрес. Адрес
+// доверенного прокси (limits.trusted_proxies) — это балансировщик или NAT,
+// за которым много клиентов: его блокировка отключила бы их всех.
+func (s *server) bannableAddr(addr netip.Addr) bool {
+	return addr.IsValid() && !s.sources.trusts(addr)
+}
+
+// recordRateLimited учитывает отключение клиента key с адреса addr за превышение rate limit.
+func (s *server) recordRateLimited(addr netip.Addr, key string) {
+	threshold := s.cfg.Bans.Auto.RateLimitDisconnects
+	if threshold == 0 {
+		return
+	}
+	reason := fmt.Sprintf("%d rate limit disconnects", threshold)
+	if s.bannableAddr(addr) {
+		s.autoBan("rate:"+addr.String(), threshold, "rate_limit", admin.Ban{CIDR: addr.String(), Reason: reason})
+	}
+	s.autoBan("rate:"+key, threshold, "rate_limit", admin.Ban{Key: key, Reason: reason})
+}
+
+// autoBan блокирует источник на bans.auto.duration, если число его нарушений
+// за окно достигло threshold.
+func (s *server) autoBan(source string, threshold int, metricReason string, ban admin.Ban) {
+	now := time.Now()
+	if s.abuse.hit(source, now) < threshold {
+		return
+	}
+	s.abuse.reset(source)
+
+	ban.Until = now.Add(s.cfg.Bans.Auto.Duration)
+	ban.Auto = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), banStoreTimeout)
+	defer cancel()
+	saved, err := s.ban(ctx, ban)
+	if err != nil {
+		slog.Error("bans: auto ban failed", "target", ban.Target(), "error", err)
+		return
+	}
+	ban = saved
+
+	metricAutoBans.WithLabelValues(metricReason).Inc()
+	slog.Warn("bans: auto ban", "target", ban.Target(), "until", ban.Until, "reason", ban.Reason)
+}
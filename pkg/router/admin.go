@@ -0,0 +1,173 @@
+package router
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// maxAdminRequestSize — ограничение тела запроса к admin API.
+const maxAdminRequestSize = 4096
+
+// loadAdminToken читает токен admin API из файла.
+func loadAdminToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// adminHandler возвращает обработчик admin API, доступный по Bearer токену.
+func (s *server) adminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+admin.PathPeers, s.handleListPeers)
+	mux.HandleFunc("POST "+admin.PathPeers+"/{key}/kick", s.handleKick)
+	mux.HandleFunc("GET "+admin.PathBans, s.handleListBans)
+	mux.HandleFunc("POST "+admin.PathBans, s.handleBan)
+	mux.HandleFunc("DELETE "+admin.PathBans+"/{target...}", s.handleUnban)
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			slog.Warn("admin: unauthorized request", "remote", r.RemoteAddr, "method", r.Method, "path", r.URL.Path)
+			writeAdminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// handleListPeers возвращает подключённых клиентов, отсортированных по ключу.
+func (s *server) handleListPeers(w http.ResponseWriter, _ *http.Request) {
+	peers := []admin.Peer{}
+	s.peers.Range(func(_, v any) bool {
+		peers = append(peers, v.(*Peer).Info())
+		return true
+	})
+	slices.SortFunc(peers, func(a, b admin.Peer) int { return strings.Compare(a.Key, b.Key) })
+
+	writeAdminJSON(w, http.StatusOK, peers)
+}
+
+// handleKick отключает клиента кадром ServerError с кодом ErrorCodeKicked.
+func (s *server) handleKick(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var req admin.KickRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "disconnected by operator"
+	}
+	if !s.kick(key, reason) {
+		writeAdminError(w, http.StatusNotFound, "peer is not connected")
+		return
+	}
+
+	slog.Info("admin: peer kicked", "client", key, "reason", req.Reason, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListBans возвращает действующие блокировки.
+func (s *server) handleListBans(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, s.bans.list(time.Now()))
+}
+
+// handleBan блокирует ключ или диапазон адресов на указанное время
+// и отключает попадающих под блокировку клиентов.
+func (s *server) handleBan(w http.ResponseWriter, r *http.Request) {
+	var req admin.BanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		writeAdminError(w, http.StatusBadRequest, "duration must be a positive duration like 24h")
+		return
+	}
+
+	ban := admin.Ban{Key: req.Key, CIDR: req.CIDR, Reason: req.Reason, Until: time.Now().Add(d)}
+	if _, _, err := normalizeBan(ban); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ban, err = s.ban(r.Context(), ban)
+	if err != nil {
+		slog.Error("admin: save ban failed", "target", req.Key+req.CIDR, "error", err)
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	slog.Info("admin: banned", "target", ban.Target(), "until", ban.Until, "reason", ban.Reason, "remote", r.RemoteAddr)
+	writeAdminJSON(w, http.StatusCreated, ban)
+}
+
+// handleUnban снимает блокировку ключа или диапазона адресов.
+func (s *server) handleUnban(w http.ResponseWriter, r *http.Request) {
+	target := r.PathValue("target")
+	ok, err := s.bans.remove(r.Context(), target)
+	if err != nil {
+		slog.Error("admin: delete ban failed", "target", target, "error", err)
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "not banned")
+		return
+	}
+
+	slog.Info("admin: unbanned", "target", target, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kick отключает клиента с ключом key. Возвращает false, если клиент не подключён.
+func (s *server) kick(key, reason string) bool {
+	if !isValidHexPubKey(key) {
+		return false
+	}
+	var id PeerID
+	if _, err := hex.Decode(id[:], []byte(key)); err != nil {
+		return false
+	}
+
+	v, ok := s.peers.Load(id)
+	if !ok {
+		return false
+	}
+	v.(*Peer).CloseWithError(protocol.ErrorCodeKicked, reason)
+	return true
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("admin: write response failed", "error", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	writeAdminJSON(w, status, admin.ErrorResponse{Error: msg})
+}
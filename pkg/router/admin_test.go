@@ -0,0 +1,168 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// newAdminTestPeer создаёт клиента на net.Pipe и регистрирует его в s.peers.
+func newAdminTestPeer(t *testing.T, s *server, id PeerID) (*Peer, net.Conn) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() { clientConn.Close() })
+
+	p := &Peer{
+		id:           id,
+		pubKeyHex:    strings.Repeat("ab", 32),
+		conn:         serverConn,
+		connectedAt:  time.Now(),
+		writeCh:      make(chan outbound, 10),
+		ctrlCh:       make(chan outbound, 10),
+		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
+		writeTimeout: time.Second,
+	}
+	t.Cleanup(p.Close)
+	go p.writeLoop()
+
+	s.peers.Store(id, p)
+	return p, clientConn
+}
+
+func newAdminTestServer(t *testing.T) (*server, *admin.Client) {
+	t.Helper()
+
+	s := &server{bans: newBanList()}
+	ts := httptest.NewServer(s.adminHandler("secret"))
+	t.Cleanup(ts.Close)
+
+	return s, admin.NewClient(strings.TrimPrefix(ts.URL, "http://"), "secret")
+}
+
+func TestAdminUnauthorized(t *testing.T) {
+	s := &server{bans: newBanList()}
+	ts := httptest.NewServer(s.adminHandler("secret"))
+	defer ts.Close()
+
+	for _, token := range []string{"", "wrong"} {
+		c := admin.NewClient(strings.TrimPrefix(ts.URL, "http://"), token)
+		_, err := c.Peers(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+			t.Errorf("token %q: err = %v, want unauthorized", token, err)
+		}
+	}
+}
+
+func TestAdminPeersAndKick(t *testing.T) {
+	s, c := newAdminTestServer(t)
+	ctx := context.Background()
+
+	var id PeerID
+	for i := range id {
+		id[i] = 0xab
+	}
+	p, clientConn := newAdminTestPeer(t, s, id)
+	p.messagesIn.Add(3)
+	p.messagesOut.Add(2)
+
+	peers, err := c.Peers(ctx)
+	if err != nil {
+		t.Fatalf("Peers: %v", err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("peers = %d, want 1", len(peers))
+	}
+	if peers[0].Key != p.pubKeyHex || peers[0].MessagesIn != 3 || peers[0].MessagesOut != 2 {
+		t.Errorf("peer = %+v", peers[0])
+	}
+
+	frames := make(chan *protocol.ServerError, 1)
+	go func() {
+		r := bufio.NewReader(clientConn)
+		if frameType, err := protocol.ReadMessageType(r); err != nil || frameType != protocol.TypeServerError {
+			close(frames)
+			return
+		}
+		e, err := protocol.DecodeServerError(r)
+		if err != nil {
+			close(frames)
+			return
+		}
+		frames <- e
+	}()
+
+	if err := c.Kick(ctx, strings.ToUpper(p.pubKeyHex), "maintenance"); err != nil {
+		t.Fatalf("Kick: %v", err)
+	}
+
+	select {
+	case e, ok := <-frames:
+		if !ok {
+			t.Fatal("error frame not received")
+		}
+		if e.Code != protocol.ErrorCodeKicked || e.Message != "maintenance" {
+			t.Errorf("error frame = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for error frame")
+	}
+
+	if err := c.Kick(ctx, strings.Repeat("cd", 32), ""); !errors.Is(err, admin.ErrNotFound) {
+		t.Errorf("kick unknown: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdminBan(t *testing.T) {
+	s, c := newAdminTestServer(t)
+	ctx := context.Background()
+	key := strings.Repeat("ef", 32)
+
+	ban, err := c.Ban(ctx, strings.ToUpper(key), time.Hour, "spam")
+	if err != nil {
+		t.Fatalf("Ban: %v", err)
+	}
+	if ban.Key != key || ban.Reason != "spam" {
+		t.Errorf("ban = %+v", ban)
+	}
+
+	var id PeerID
+	for i := range id {
+		id[i] = 0xef
+	}
+	if err := s.admit(id); !errors.Is(err, errBanned) {
+		t.Errorf("admit banned: err = %v, want errBanned", err)
+	}
+
+	bans, err := c.Bans(ctx)
+	if err != nil {
+		t.Fatalf("Bans: %v", err)
+	}
+	if len(bans) != 1 || bans[0].Key != key {
+		t.Errorf("bans = %+v", bans)
+	}
+
+	if err := c.Unban(ctx, key); err != nil {
+		t.Fatalf("Unban: %v", err)
+	}
+	if err := s.admit(id); err != nil {
+		t.Errorf("admit after unban: %v", err)
+	}
+	if err := c.Unban(ctx, key); !errors.Is(err, admin.ErrNotFound) {
+		t.Errorf("unban twice: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := c.Ban(ctx, key, 0, ""); err == nil {
+		t.Error("ban with zero duration: want error")
+	}
+}
@@ -0,0 +1,176 @@
+package router
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// jwtLeeway — допустимое расхождение часов при проверке exp и nbf.
+const jwtLeeway = 30 * time.Second
+
+var (
+	errTokenMalformed = errors.New("malformed token")
+	errTokenExpired   = errors.New("token expired")
+)
+
+// JWTAuthenticator — аутентификация по JWT, подписанному доверенным
+// издателем (protocol.TypeClientToken). Предназначен для серверных ботов,
+// которым выдаётся долгоживущий токен вместо хранения ключа клиента.
+// Поддерживается только alg EdDSA (ed25519), exp обязателен.
+type JWTAuthenticator struct {
+	keys     []ed25519.PublicKey
+	issuer   string
+	audience string
+}
+
+// NewJWTAuthenticator создаёт JWTAuthenticator.
+func NewJWTAuthenticator(cfg config.JWTAuthConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{issuer: cfg.Issuer, audience: cfg.Audience}
+	for _, key := range cfg.PublicKeys {
+		b, err := hex.DecodeString(key)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid jwt public key %q", key)
+		}
+		a.keys = append(a.keys, ed25519.PublicKey(b))
+	}
+	if len(a.keys) == 0 {
+		return nil, fmt.Errorf("jwt public keys are required")
+	}
+	return a, nil
+}
+
+// Type возвращает protocol.TypeClientToken.
+func (a *JWTAuthenticator) Type() byte {
+	return protocol.TypeClientToken
+}
+
+// Name возвращает название способа.
+func (a *JWTAuthenticator) Name() string {
+	return config.AuthMethodJWT
+}
+
+// Authenticate читает токен и проверяет его. PeerID — claim sub.
+func (a *JWTAuthenticator) Authenticate(conn net.Conn, _ []byte) (PeerID, error) {
+	msg, err := protocol.DecodeClientToken(conn)
+	if err != nil {
+		return PeerID{}, fmt.Errorf("read token: %w", err)
+	}
+
+	id, err := a.verify(msg.Token, time.Now())
+	if err != nil {
+		slog.Warn("auth: invalid token", "remote", conn.RemoteAddr().String(), "error", err)
+		if errors.Is(err, protocol.ErrInvalidSignature) {
+			return PeerID{}, authFailure(protocol.AuthStatusInvalidSig, err)
+		}
+		return PeerID{}, authFailure(protocol.AuthStatusFailed, err)
+	}
+	return id, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Sub string      `json:"sub"`
+	Iss string      `json:"iss"`
+	Aud jwtAudience `json:"aud"`
+	Exp *float64    `json:"exp"`
+	Nbf *float64    `json:"nbf"`
+}
+
+// jwtAudience — claim aud: строка или массив строк.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte{'['}) {
+		return json.Unmarshal(data, (*[]string)(a))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*a = jwtAudience{s}
+	return nil
+}
+
+// verify проверяет подпись и claims токена.
+func (a *JWTAuthenticator) verify(token string, now time.Time) (PeerID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return PeerID{}, errTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return PeerID{}, fmt.Errorf("%w: header: %w", errTokenMalformed, err)
+	}
+	if header.Alg != "EdDSA" {
+		return PeerID{}, fmt.Errorf("%w: unsupported alg %q", errTokenMalformed, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return PeerID{}, fmt.Errorf("%w: signature: %w", errTokenMalformed, err)
+	}
+	signed := []byte(token[:len(parts[0])+1+len(parts[1])])
+	if !slices.ContainsFunc(a.keys, func(key ed25519.PublicKey) bool {
+		return ed25519.Verify(key, signed, signature)
+	}) {
+		return PeerID{}, protocol.ErrInvalidSignature
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return PeerID{}, fmt.Errorf("%w: claims: %w", errTokenMalformed, err)
+	}
+
+	if claims.Exp == nil {
+		return PeerID{}, fmt.Errorf("%w: exp is required", errTokenMalformed)
+	}
+	if now.After(unixTime(*claims.Exp).Add(jwtLeeway)) {
+		return PeerID{}, errTokenExpired
+	}
+	if claims.Nbf != nil && now.Add(jwtLeeway).Before(unixTime(*claims.Nbf)) {
+		return PeerID{}, fmt.Errorf("token not yet valid")
+	}
+	if a.issuer != "" && claims.Iss != a.issuer {
+		return PeerID{}, fmt.Errorf("unexpected issuer %q", claims.Iss)
+	}
+	if a.audience != "" && !slices.Contains(claims.Aud, a.audience) {
+		return PeerID{}, fmt.Errorf("unexpected audience")
+	}
+
+	var id PeerID
+	sub, err := hex.DecodeString(claims.Sub)
+	if err != nil || len(sub) != len(id) {
+		return PeerID{}, fmt.Errorf("%w: sub must be a hex public key", errTokenMalformed)
+	}
+	copy(id[:], sub)
+	return id, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(int64(sec), 0)
+}
@@ -0,0 +1,154 @@
+package router
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func signTestJWT(t *testing.T, priv ed25519.PrivateKey, alg string, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(priv, []byte(signed)))
+}
+
+func TestJWTAuthenticatorVerify(t *testing.T) {
+	issuerPub, issuerPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	a, err := NewJWTAuthenticator(config.JWTAuthConfig{
+		PublicKeys: []string{hex.EncodeToString(issuerPub)},
+		Issuer:     "sprut-issuer",
+		Audience:   "sprut",
+	})
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+
+	var peer PeerID
+	for i := range peer {
+		peer[i] = byte(i)
+	}
+	sub := hex.EncodeToString(peer[:])
+	now := time.Now()
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": sub,
+			"iss": "sprut-issuer",
+			"aud": []string{"other", "sprut"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		valid   bool
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: signTestJWT(t, issuerPriv, "EdDSA", claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "audience as string",
+			token: signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"aud": "sprut"})),
+			valid: true,
+		},
+		{
+			name:    "unknown issuer key",
+			token:   signTestJWT(t, otherPriv, "EdDSA", claims(nil)),
+			wantErr: protocol.ErrInvalidSignature,
+		},
+		{
+			name:    "unsupported alg",
+			token:   signTestJWT(t, issuerPriv, "none", claims(nil)),
+			wantErr: errTokenMalformed,
+		},
+		{
+			name:    "expired",
+			token:   signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})),
+			wantErr: errTokenExpired,
+		},
+		{
+			name:    "missing exp",
+			token:   signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"exp": nil})),
+			wantErr: errTokenMalformed,
+		},
+		{
+			name:  "not yet valid",
+			token: signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})),
+		},
+		{
+			name:  "wrong issuer",
+			token: signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"iss": "someone"})),
+		},
+		{
+			name:  "wrong audience",
+			token: signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"aud": "other"})),
+		},
+		{
+			name:    "invalid sub",
+			token:   signTestJWT(t, issuerPriv, "EdDSA", claims(map[string]any{"sub": "bot-1"})),
+			wantErr: errTokenMalformed,
+		},
+		{
+			name:    "not a jwt",
+			token:   "abc",
+			wantErr: errTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.verify(tt.token, now)
+			switch {
+			case tt.valid:
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				if id != peer {
+					t.Errorf("peer id mismatch")
+				}
+			case err == nil:
+				t.Fatal("expected error")
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("error: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+	"github.com/udisondev/sprut/pkg/transport"
+)
+
+// peerURIScheme — схема URI SAN с PeerID в клиентском сертификате: sprut:<hex>.
+const peerURIScheme = "sprut"
+
+var errNoClientCert = errors.New("client certificate required")
+
+// MTLSAuthenticator — аутентификация по клиентскому TLS сертификату
+// (protocol.TypeClientCertHello). Цепочку проверяет TLS рукопожатие
+// (см. configureClientAuth), PeerID берётся из сертификата:
+// ed25519 ключ сертификата или URI SAN sprut:<hex>.
+type MTLSAuthenticator struct{}
+
+// NewMTLSAuthenticator создаёт MTLSAuthenticator.
+func NewMTLSAuthenticator() *MTLSAuthenticator {
+	return &MTLSAuthenticator{}
+}
+
+// Type возвращает protocol.TypeClientCertHello.
+func (a *MTLSAuthenticator) Type() byte {
+	return protocol.TypeClientCertHello
+}
+
+// Name возвращает название способа.
+func (a *MTLSAuthenticator) Name() string {
+	return config.AuthMethodMTLS
+}
+
+// Authenticate сопоставляет проверенный клиентский сертификат с PeerID.
+func (a *MTLSAuthenticator) Authenticate(conn net.Conn, _ []byte) (PeerID, error) {
+	tlsConn, ok := conn.(transport.Conn)
+	if !ok {
+		return PeerID{}, fmt.Errorf("not a TLS connection")
+	}
+
+	chains := tlsConn.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		slog.Warn("auth: no verified client certificate", "remote", conn.RemoteAddr().String())
+		return PeerID{}, authFailure(protocol.AuthStatusFailed, errNoClientCert)
+	}
+
+	id, err := certPeerID(chains[0][0])
+	if err != nil {
+		slog.Warn("auth: client certificate not mapped", "remote", conn.RemoteAddr().String(), "error", err)
+		return PeerID{}, authFailure(protocol.AuthStatusFailed, err)
+	}
+	return id, nil
+}
+
+// certPeerID возвращает PeerID клиентского сертификата.
+func certPeerID(cert *x509.Certificate) (PeerID, error) {
+	var id PeerID
+
+	if key, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		copy(id[:], key)
+		return id, nil
+	}
+
+	for _, uri := range cert.URIs {
+		if uri.Scheme != peerURIScheme {
+			continue
+		}
+		b, err := hex.DecodeString(uri.Opaque)
+		if err != nil || len(b) != len(id) {
+			return PeerID{}, fmt.Errorf("invalid peer id in certificate URI %q", uri.String())
+		}
+		copy(id[:], b)
+		return id, nil
+	}
+
+	return PeerID{}, fmt.Errorf("certificate has no ed25519 key or %s: URI", peerURIScheme)
+}
+
+// configureClientAuth включает запрос клиентских сертификатов.
+// Сертификат необязателен: клиенты с другими способами аутентификации
+// подключаются без него.
+func configureClientAuth(tlsCfg *tls.Config, cfg config.MTLSAuthConfig) error {
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates in client CA file %s", cfg.ClientCAFile)
+	}
+
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	slog.Info("tls: client certificate authentication enabled", "client_ca_file", cfg.ClientCAFile)
+	return nil
+}
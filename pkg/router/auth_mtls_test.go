@@ -0,0 +1,80 @@
+package router
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"net/url"
+	"testing"
+)
+
+func TestCertPeerID(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ecdsa key: %v", err)
+	}
+
+	var peer PeerID
+	for i := range peer {
+		peer[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		want    PeerID
+		wantErr bool
+	}{
+		{
+			name: "ed25519 key",
+			cert: &x509.Certificate{PublicKey: edPub},
+			want: PeerID(edPub),
+		},
+		{
+			name: "uri san",
+			cert: &x509.Certificate{
+				PublicKey: &ecKey.PublicKey,
+				URIs:      []*url.URL{{Scheme: "https", Host: "example.com"}, {Scheme: peerURIScheme, Opaque: hex.EncodeToString(peer[:])}},
+			},
+			want: peer,
+		},
+		{
+			name: "invalid uri san",
+			cert: &x509.Certificate{
+				PublicKey: &ecKey.PublicKey,
+				URIs:      []*url.URL{{Scheme: peerURIScheme, Opaque: "bot-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no mapping",
+			cert:    &x509.Certificate{PublicKey: &ecKey.PublicKey},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := certPeerID(tt.cert)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("certPeerID: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("peer id mismatch")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	var challenge [protocol.ChallengeSize]byte
+	var serverID [protocol.ServerIDSize]byte
+	var binding [protocol.ChannelBindingSize]byte
+	signedData := func() []byte {
+		return protocol.BuildSignedData(challenge, 1706000000, serverID, [protocol.PublicKeySize]byte(pub), binding)
+	}
+
+	legacy := signedData()
+	copy(legacy, protocol.LegacyProtocolVersion)
+
+	tests := []struct {
+		name       string
+		signature  []byte
+		wantStatus byte
+		wantErr    error
+	}{
+		{"current version", ed25519.Sign(priv, signedData()), 0, nil},
+		{"legacy version", ed25519.Sign(priv, legacy), protocol.AuthStatusFailed, protocol.ErrUnsupportedVersion},
+		{"forged", make([]byte, protocol.SignatureSize), protocol.AuthStatusInvalidSig, protocol.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(pub, signedData(), tt.signature)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var authErr *AuthError
+			if !errors.As(err, &authErr) || authErr.Status != tt.wantStatus || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want status %d and %v", err, tt.wantStatus, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// Authenticator — способ аутентификации клиента.
+// Клиент выбирает способ первым байтом рукопожатия (protocol.TypeClientHello,
+// protocol.TypeClientToken, ...).
+type Authenticator interface {
+	// Type возвращает байт типа рукопожатия, который обрабатывает Authenticator.
+	Type() byte
+
+	// Name возвращает название способа для логов.
+	Name() string
+
+	// Authenticate проводит рукопожатие после прочитанного байта типа
+	// и возвращает PeerID клиента. Дедлайн соединения уже установлен,
+	// AuthResult при успехе отправляет вызывающий код.
+	// buf — рабочий буфер размера AuthBufSize, принадлежащий соединению.
+	// При отказе возвращает *AuthError: статус будет отправлен клиенту.
+	Authenticate(conn net.Conn, buf []byte) (PeerID, error)
+}
+
+// errUnsupportedHandshake — клиент выбрал способ аутентификации, не включённый на сервере.
+var errUnsupportedHandshake = errors.New("unsupported handshake type")
+
+// newAuthenticators создаёт включённые в конфигурации способы аутентификации.
+func newAuthenticators(cfg *config.Config) (map[byte]Authenticator, error) {
+	auths := make(map[byte]Authenticator)
+
+	if cfg.Auth.Enabled(config.AuthMethodEd25519) {
+		a, err := NewEd25519Authenticator(cfg.Server.ServerID, cfg.Limits.ChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		auths[a.Type()] = a
+	}
+
+	if cfg.Auth.Enabled(config.AuthMethodJWT) {
+		a, err := NewJWTAuthenticator(cfg.Auth.JWT)
+		if err != nil {
+			return nil, err
+		}
+		auths[a.Type()] = a
+	}
+
+	if cfg.Auth.Enabled(config.AuthMethodMTLS) {
+		a := NewMTLSAuthenticator()
+		auths[a.Type()] = a
+	}
+
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("no authentication methods enabled")
+	}
+	return auths, nil
+}
+
+// authenticate читает тип рукопожатия и передаёт соединение соответствующему
+// Authenticator. admit (может быть nil) решает, допускается ли аутентифицированный
+// клиент: отказ отправляется клиенту со статусом AuthStatusFailed.
+// При успехе отправляет клиенту AuthResult со статусом OK
+// и возвращает PeerID и название способа.
+func authenticate(conn net.Conn, auths map[byte]Authenticator, timeout time.Duration, buf []byte, admit func(PeerID) error) (PeerID, string, error) {
+	remote := conn.RemoteAddr().String()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return PeerID{}, "", fmt.Errorf("set deadline: %w", err)
+	}
+	defer func() {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			slog.Error("auth: reset deadline failed", "error", err, "remote", remote)
+		}
+	}()
+
+	// Тип рукопожатия (1 byte)
+	if _, err := io.ReadFull(conn, buf[offWork:offWork+1]); err != nil {
+		return PeerID{}, "", fmt.Errorf("read handshake type: %w", err)
+	}
+	auth, ok := auths[buf[offWork]]
+	if !ok {
+		slog.Warn("auth: unsupported handshake type", "remote", remote, "got", buf[offWork])
+		return PeerID{}, "", sendAuthFailure(conn, protocol.AuthStatusFailed, fmt.Errorf("%w: %d", errUnsupportedHandshake, buf[offWork]))
+	}
+
+	id, err := auth.Authenticate(conn, buf)
+	if err != nil {
+		var authErr *AuthError
+		if errors.As(err, &authErr) {
+			sendAuthFailure(conn, authErr.Status, authErr.Err)
+		}
+		return PeerID{}, auth.Name(), err
+	}
+
+	if admit != nil {
+		if err := admit(id); err != nil {
+			return PeerID{}, auth.Name(), sendAuthFailure(conn, protocol.AuthStatusFailed, authFailure(protocol.AuthStatusFailed, err))
+		}
+	}
+
+	// Отправляем успешный результат (синхронизация с клиентом)
+	buf[offWork] = protocol.TypeAuthResult
+	buf[offWork+1] = protocol.AuthStatusOK
+	if _, err := conn.Write(buf[offWork : offWork+2]); err != nil {
+		return PeerID{}, auth.Name(), fmt.Errorf("send auth result: %w", err)
+	}
+	slog.Debug("auth: auth result sent", "remote", remote, "method", auth.Name())
+
+	return id, auth.Name(), nil
+}
+
+// sendAuthFailure сообщает клиенту причину отказа в аутентификации
+// и возвращает исходную ошибку. Ошибка отправки только логируется:
+// соединение в любом случае будет закрыто.
+func sendAuthFailure(conn net.Conn, status byte, err error) error {
+	result := &protocol.AuthResult{Status: status, ErrorMsg: err.Error()}
+	if werr := result.Encode(conn); werr != nil {
+		slog.Debug("auth: send failure result failed", "error", werr, "remote", conn.RemoteAddr().String())
+	}
+	return err
+}
@@ -0,0 +1,466 @@
+package router
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+const (
+	// banSweepInterval — как часто удалять истёкшие блокировки из хранилища.
+	banSweepInterval = time.Minute
+
+	// banStoreTimeout — таймаут записи блокировки в хранилище.
+	banStoreTimeout = 5 * time.Second
+)
+
+// errBanned — ключ клиента заблокирован.
+var errBanned = errors.New("key is banned")
+
+// banStore — постоянное хранилище блокировок: файл или NATS KV (broker.Bans).
+// Значение — блокировка в JSON, id — banID.
+type banStore interface {
+	Put(ctx context.Context, id string, value []byte) error
+	Delete(ctx context.Context, id string) error
+}
+
+// banList — заблокированные ключи и диапазоны адресов с временем окончания
+// блокировки. Проверяется на каждое соединение, поэтому держится в памяти;
+// store (если задан) сохраняет изменения и доставляет изменения с других серверов.
+type banList struct {
+	mu    sync.RWMutex
+	keys  map[string]admin.Ban // ключ в нижнем регистре
+	cidrs map[netip.Prefix]admin.Ban
+
+	store banStore // nil — блокировки только в памяти
+
+	// onBan вызывается для блокировок, полученных из store (опционально).
+	onBan func(admin.Ban)
+}
+
+func newBanList() *banList {
+	return &banList{
+		keys:  make(map[string]admin.Ban),
+		cidrs: make(map[netip.Prefix]admin.Ban),
+	}
+}
+
+// add проверяет и сохраняет блокировку. Ключ приводится к нижнему регистру,
+// диапазон — к каноническому виду (см. admin.ParseCIDR).
+func (b *banList) add(ctx context.Context, ban admin.Ban) (admin.Ban, error) {
+	ban, prefix, err := normalizeBan(ban)
+	if err != nil {
+		return admin.Ban{}, err
+	}
+
+	if b.store != nil {
+		data, err := json.Marshal(ban)
+		if err != nil {
+			return admin.Ban{}, fmt.Errorf("marshal ban: %w", err)
+		}
+		if err := b.store.Put(ctx, banID(ban), data); err != nil {
+			return admin.Ban{}, err
+		}
+	}
+
+	b.set(ban, prefix)
+	return ban, nil
+}
+
+// remove снимает блокировку ключа или диапазона.
+// Возвращает false, если блокировки нет.
+func (b *banList) remove(ctx context.Context, target string) (bool, error) {
+	ban := admin.Ban{Key: target}
+	if _, ok := admin.ParseCIDR(target); ok {
+		ban = admin.Ban{CIDR: target}
+	}
+	ban, prefix, err := normalizeBan(ban)
+	if err != nil {
+		return false, nil
+	}
+
+	b.mu.RLock()
+	_, ok := b.keys[ban.Key]
+	if ban.CIDR != "" {
+		_, ok = b.cidrs[prefix]
+	}
+	b.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	if b.store != nil {
+		if err := b.store.Delete(ctx, banID(ban)); err != nil {
+			return false, err
+		}
+	}
+
+	b.unset(banID(ban))
+	return true, nil
+}
+
+// checkKey возвращает действующую блокировку ключа.
+func (b *banList) checkKey(key string, now time.Time) (admin.Ban, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ban, ok := b.keys[strings.ToLower(key)]
+	if !ok || !now.Before(ban.Until) {
+		return admin.Ban{}, false
+	}
+	return ban, true
+}
+
+// checkAddr возвращает действующую блокировку диапазона, содержащего addr.
+// Диапазоны перебираются целиком: их число ограничено решениями оператора
+// и автоматическими блокировками.
+func (b *banList) checkAddr(addr netip.Addr, now time.Time) (admin.Ban, bool) {
+	if !addr.IsValid() {
+		return admin.Ban{}, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for prefix, ban := range b.cidrs {
+		if prefix.Contains(addr) && now.Before(ban.Until) {
+			return ban, true
+		}
+	}
+	return admin.Ban{}, false
+}
+
+// list возвращает действующие блокировки, отсортированные по ключу или диапазону.
+func (b *banList) list(now time.Time) []admin.Ban {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	bans := make([]admin.Ban, 0, len(b.keys)+len(b.cidrs))
+	for _, ban := range b.keys {
+		if now.Before(ban.Until) {
+			bans = append(bans, ban)
+		}
+	}
+	for _, ban := range b.cidrs {
+		if now.Before(ban.Until) {
+			bans = append(bans, ban)
+		}
+	}
+	slices.SortFunc(bans, func(a, b admin.Ban) int { return strings.Compare(a.Target(), b.Target()) })
+	return bans
+}
+
+// sweep удаляет истёкшие блокировки из памяти и хранилища.
+func (b *banList) sweep(ctx context.Context, now time.Time) {
+	var expired []string
+
+	b.mu.Lock()
+	for key, ban := range b.keys {
+		if !now.Before(ban.Until) {
+			delete(b.keys, key)
+			expired = append(expired, banID(ban))
+		}
+	}
+	for prefix, ban := range b.cidrs {
+		if !now.Before(ban.Until) {
+			delete(b.cidrs, prefix)
+			expired = append(expired, banID(ban))
+		}
+	}
+	b.mu.Unlock()
+
+	if b.store == nil {
+		return
+	}
+	// С NATS KV истёкшую запись может удалить каждый сервер — повторное удаление безвредно
+	for _, id := range expired {
+		if err := b.store.Delete(ctx, id); err != nil {
+			slog.Warn("bans: delete expired failed", "id", id, "error", err)
+		}
+	}
+}
+
+// apply применяет изменение из хранилища; value == nil означает удаление.
+func (b *banList) apply(id string, value []byte) {
+	if value == nil {
+		b.unset(id)
+		return
+	}
+
+	var ban admin.Ban
+	if err := json.Unmarshal(value, &ban); err != nil {
+		slog.Error("bans: unmarshal failed", "id", id, "error", err)
+		return
+	}
+	ban, prefix, err := normalizeBan(ban)
+	if err != nil {
+		slog.Error("bans: invalid entry", "id", id, "error", err)
+		return
+	}
+
+	b.set(ban, prefix)
+	if b.onBan != nil && time.Now().Before(ban.Until) {
+		b.onBan(ban)
+	}
+}
+
+func (b *banList) set(ban admin.Ban, prefix netip.Prefix) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ban.CIDR != "" {
+		b.cidrs[prefix] = ban
+		return
+	}
+	b.keys[ban.Key] = ban
+}
+
+func (b *banList) unset(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if key, ok := strings.CutPrefix(id, "key."); ok {
+		delete(b.keys, key)
+		return
+	}
+	if cidr, ok := strings.CutPrefix(id, "cidr."); ok {
+		if prefix, err := netip.ParsePrefix(strings.ReplaceAll(cidr, "_", ":")); err == nil {
+			delete(b.cidrs, prefix)
+		}
+	}
+}
+
+// normalizeBan проверяет, что блокировка задаёт ровно одно из Key и CIDR,
+// и приводит их к каноническому виду.
+func normalizeBan(ban admin.Ban) (admin.Ban, netip.Prefix, error) {
+	switch {
+	case ban.Key != "" && ban.CIDR != "":
+		return admin.Ban{}, netip.Prefix{}, errors.New("ban must have either key or cidr")
+	case ban.CIDR != "":
+		prefix, ok := admin.ParseCIDR(ban.CIDR)
+		if !ok {
+			return admin.Ban{}, netip.Prefix{}, fmt.Errorf("invalid cidr %q", ban.CIDR)
+		}
+		ban.CIDR = prefix.String()
+		return ban, prefix, nil
+	case isValidHexPubKey(ban.Key):
+		ban.Key = strings.ToLower(ban.Key)
+		return ban, netip.Prefix{}, nil
+	default:
+		return admin.Ban{}, netip.Prefix{}, errors.New("key must be a hex public key")
+	}
+}
+
+// banID возвращает идентификатор блокировки в хранилище: "key.<hex>" или
+// "cidr.<prefix>" (":" заменяется на "_" — недопустимый символ ключа NATS KV).
+func banID(ban admin.Ban) string {
+	if ban.CIDR != "" {
+		return "cidr." + strings.ReplaceAll(ban.CIDR, ":", "_")
+	}
+	return "key." + ban.Key
+}
+
+// fileBanStore хранит блокировки в JSON файле {"<id>": <ban>, ...}.
+// Файл перезаписывается целиком через временный файл и rename.
+type fileBanStore struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]json.RawMessage
+}
+
+// openFileBanStore читает блокировки из файла и передаёт их в apply.
+// Отсутствующий файл — пустой список.
+func openFileBanStore(path string, apply func(id string, value []byte)) (*fileBanStore, error) {
+	s := &fileBanStore{path: path, entries: make(map[string]json.RawMessage)}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read bans file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.entries); err != nil {
+			return nil, fmt.Errorf("parse bans file %s: %w", path, err)
+		}
+	}
+
+	for id, value := range s.entries {
+		apply(id, value)
+	}
+	slog.Info("bans: loaded", "file", path, "entries", len(s.entries))
+
+	return s, nil
+}
+
+// Put сохраняет блокировку id.
+func (s *fileBanStore) Put(_ context.Context, id string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, had := s.entries[id]
+	s.entries[id] = value
+	if err := s.save(); err != nil {
+		if had {
+			s.entries[id] = prev
+		} else {
+			delete(s.entries, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет блокировку id.
+func (s *fileBanStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, had := s.entries[id]
+	if !had {
+		return nil
+	}
+	delete(s.entries, id)
+	if err := s.save(); err != nil {
+		s.entries[id] = prev
+		return err
+	}
+	return nil
+}
+
+// save записывает entries в файл. Вызывается под mu.
+func (s *fileBanStore) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal bans: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("save bans: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // после rename файла уже нет
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save bans: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save bans: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("save bans: %w", err)
+	}
+	return nil
+}
+
+// openBans подключает хранилище блокировок, выбранное bans.store.
+// Возвращает функцию закрытия хранилища.
+func (s *server) openBans(cfg config.BansConfig) (func(), error) {
+	s.bans.onBan = s.kickBanned
+
+	switch cfg.Store {
+	case config.BanStoreFile:
+		store, err := openFileBanStore(cfg.File, s.bans.apply)
+		if err != nil {
+			return nil, err
+		}
+		s.bans.store = store
+	case config.BanStoreNATS:
+		store, err := broker.NewBans(s.brk, s.bans.apply)
+		if err != nil {
+			return nil, err
+		}
+		s.bans.store = store
+		return func() {
+			if err := store.Stop(); err != nil {
+				slog.Error("stop bans", "error", err)
+			}
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// sweepBans периодически удаляет истёкшие блокировки и счётчики нарушений.
+func (s *server) sweepBans(ctx context.Context) {
+	ticker := time.NewTicker(banSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.bans.sweep(ctx, now)
+			s.abuse.prune(now)
+		}
+	}
+}
+
+// ban сохраняет блокировку и отключает попадающих под неё клиентов.
+func (s *server) ban(ctx context.Context, ban admin.Ban) (admin.Ban, error) {
+	ban, err := s.bans.add(ctx, ban)
+	if err != nil {
+		return admin.Ban{}, err
+	}
+	s.kickBanned(ban)
+	return ban, nil
+}
+
+// kickBanned отключает клиентов с заблокированным ключом или адресом.
+func (s *server) kickBanned(ban admin.Ban) {
+	reason := "banned"
+	if ban.Reason != "" {
+		reason += ": " + ban.Reason
+	}
+
+	if ban.Key != "" {
+		s.kick(ban.Key, reason)
+		return
+	}
+
+	prefix, ok := admin.ParseCIDR(ban.CIDR)
+	if !ok {
+		return
+	}
+	s.peers.Range(func(_, v any) bool {
+		p := v.(*Peer)
+		if prefix.Contains(remoteIP(p.conn)) {
+			p.CloseWithError(protocol.ErrorCodeKicked, reason)
+		}
+		return true
+	})
+}
+
+// admit проверяет, допускается ли аутентифицированный клиент.
+func (s *server) admit(id PeerID) error {
+	if ban, ok := s.bans.checkKey(hex.EncodeToString(id[:]), time.Now()); ok {
+		return fmt.Errorf("%w until %s: %s", errBanned, ban.Until.UTC().Format(time.RFC3339), ban.Reason)
+	}
+	return nil
+}
+
+// remoteIP возвращает IP адрес клиента; для не-IP соединений — нулевой netip.Addr.
+func remoteIP(conn net.Conn) netip.Addr {
+	addrPort, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addrPort.Addr().Unmap()
+}
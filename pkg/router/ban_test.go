@@ -0,0 +1,219 @@
+package router
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+func TestBanListCheck(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	key := strings.Repeat("ab", 32)
+
+	b := newBanList()
+	if _, err := b.add(ctx, admin.Ban{Key: strings.ToUpper(key), Reason: "spam", Until: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	if _, err := b.add(ctx, admin.Ban{CIDR: "10.1.2.3/16", Until: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("add cidr: %v", err)
+	}
+	if _, err := b.add(ctx, admin.Ban{CIDR: "2001:db8::1", Until: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("add address: %v", err)
+	}
+	if _, err := b.add(ctx, admin.Ban{Key: key, CIDR: "10.0.0.0/8"}); err == nil {
+		t.Error("add with key and cidr: want error")
+	}
+
+	if ban, ok := b.checkKey(key, now); !ok || ban.Reason != "spam" {
+		t.Errorf("checkKey = %+v, %v", ban, ok)
+	}
+	if _, ok := b.checkKey(key, now.Add(2*time.Hour)); ok {
+		t.Error("checkKey after expiry: want not banned")
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.1.200.7", true},
+		{"10.2.0.1", false},
+		{"::ffff:10.1.0.1", false}, // remoteIP снимает IPv4-mapped префикс
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+	}
+	for _, tt := range tests {
+		if _, ok := b.checkAddr(netip.MustParseAddr(tt.addr), now); ok != tt.want {
+			t.Errorf("checkAddr(%s) = %v, want %v", tt.addr, ok, tt.want)
+		}
+	}
+
+	bans := b.list(now)
+	if len(bans) != 3 || bans[0].CIDR != "10.1.0.0/16" {
+		t.Errorf("list = %+v", bans)
+	}
+
+	ok, err := b.remove(ctx, "10.1.0.0/16")
+	if err != nil || !ok {
+		t.Fatalf("remove cidr = %v, %v", ok, err)
+	}
+	if _, ok := b.checkAddr(netip.MustParseAddr("10.1.200.7"), now); ok {
+		t.Error("checkAddr after remove: want not banned")
+	}
+
+	b.sweep(ctx, now.Add(2*time.Hour))
+	if n := len(b.keys) + len(b.cidrs); n != 0 {
+		t.Errorf("entries after sweep = %d, want 0", n)
+	}
+}
+
+func TestFileBanStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bans.json")
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	key := strings.Repeat("cd", 32)
+
+	b := newBanList()
+	store, err := openFileBanStore(path, b.apply)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	b.store = store
+
+	if _, err := b.add(ctx, admin.Ban{Key: key, Reason: "abuse", Until: until}); err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	if _, err := b.add(ctx, admin.Ban{CIDR: "2001:db8::/32", Until: until, Auto: true}); err != nil {
+		t.Fatalf("add cidr: %v", err)
+	}
+	if _, err := b.add(ctx, admin.Ban{CIDR: "192.0.2.0/24", Until: until}); err != nil {
+		t.Fatalf("add cidr: %v", err)
+	}
+	if ok, err := b.remove(ctx, "192.0.2.0/24"); err != nil || !ok {
+		t.Fatalf("remove = %v, %v", ok, err)
+	}
+
+	// Блокировки переживают перезапуск
+	reloaded := newBanList()
+	if _, err := openFileBanStore(path, reloaded.apply); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	now := time.Now()
+	if ban, ok := reloaded.checkKey(key, now); !ok || ban.Reason != "abuse" || !ban.Until.Equal(until) {
+		t.Errorf("checkKey after reload = %+v, %v", ban, ok)
+	}
+	if ban, ok := reloaded.checkAddr(netip.MustParseAddr("2001:db8::7"), now); !ok || !ban.Auto {
+		t.Errorf("checkAddr after reload = %+v, %v", ban, ok)
+	}
+	if _, ok := reloaded.checkAddr(netip.MustParseAddr("192.0.2.1"), now); ok {
+		t.Error("removed ban restored after reload")
+	}
+}
+
+func TestAbuseTracker(t *testing.T) {
+	now := time.Now()
+	tr := newAbuseTracker(time.Minute)
+
+	for i := 1; i <= 3; i++ {
+		if n := tr.hit("auth:192.0.2.1", now.Add(time.Duration(i)*time.Second)); n != i {
+			t.Fatalf("hit %d = %d", i, n)
+		}
+	}
+	// Новое окно начинается с первого нарушения после истечения предыдущего
+	if n := tr.hit("auth:192.0.2.1", now.Add(time.Minute+time.Second)); n != 1 {
+		t.Errorf("hit after window = %d, want 1", n)
+	}
+
+	tr.hit("rate:192.0.2.2", now)
+	tr.prune(now.Add(3 * time.Minute))
+	if n := len(tr.counts); n != 0 {
+		t.Errorf("counts after prune = %d, want 0", n)
+	}
+}
+
+func TestAutoBan(t *testing.T) {
+	s := newAutoBanServer(nil)
+
+	addr := netip.MustParseAddr("192.0.2.10")
+	for range 2 {
+		s.recordAuthFailure(addr, "invalid_signature")
+	}
+	if _, ok := s.bans.checkAddr(addr, time.Now()); ok {
+		t.Fatal("banned before threshold")
+	}
+	s.recordAuthFailure(addr, "replay")
+	ban, ok := s.bans.checkAddr(addr, time.Now())
+	if !ok || !ban.Auto || ban.CIDR != "192.0.2.10/32" {
+		t.Fatalf("ban after threshold = %+v, %v", ban, ok)
+	}
+
+	key := strings.Repeat("ef", 32)
+	other := netip.MustParseAddr("198.51.100.1")
+	s.recordRateLimited(other, key)
+	s.recordRateLimited(netip.MustParseAddr("198.51.100.2"), key)
+	if _, ok := s.bans.checkKey(key, time.Now()); !ok {
+		t.Error("key not banned after rate limit disconnects")
+	}
+	if _, ok := s.bans.checkAddr(other, time.Now()); ok {
+		t.Error("address banned after one rate limit disconnect")
+	}
+}
+
+func TestAutoBanSkipsTrustedAndHonestFailures(t *testing.T) {
+	s := newAutoBanServer([]string{"10.0.0.0/8"})
+
+	// Балансировщик: за ним все клиенты, адрес не блокируется
+	proxy := netip.MustParseAddr("10.0.0.5")
+	for range 10 {
+		s.recordAuthFailure(proxy, "invalid_signature")
+	}
+	if _, ok := s.bans.checkAddr(proxy, time.Now()); ok {
+		t.Error("trusted proxy banned after auth failures")
+	}
+
+	// Ключ клиента за балансировщиком блокируется, адрес — нет
+	key := strings.Repeat("ef", 32)
+	for range 2 {
+		s.recordRateLimited(proxy, key)
+	}
+	if _, ok := s.bans.checkAddr(proxy, time.Now()); ok {
+		t.Error("trusted proxy banned after rate limit disconnects")
+	}
+	if _, ok := s.bans.checkKey(key, time.Now()); !ok {
+		t.Error("key behind trusted proxy not banned")
+	}
+
+	// Таймауты и истёкшие токены случаются у честных клиентов
+	client := netip.MustParseAddr("192.0.2.20")
+	for _, reason := range []string{"timeout", "expired", "unsupported_method", "closed", "io", "banned"} {
+		for range 5 {
+			s.recordAuthFailure(client, reason)
+		}
+	}
+	if _, ok := s.bans.checkAddr(client, time.Now()); ok {
+		t.Error("address banned for failures that are not abuse")
+	}
+}
+
+// newAutoBanServer возвращает server с автоматическими блокировками:
+// 3 неудачные аутентификации или 2 отключения за rate limit.
+func newAutoBanServer(trustedProxies []string) *server {
+	cfg := config.Default()
+	cfg.Bans.Auto.AuthFailures = 3
+	cfg.Bans.Auto.RateLimitDisconnects = 2
+	cfg.Bans.Auto.Duration = time.Hour
+	cfg.Limits.TrustedProxies = trustedProxies
+
+	return &server{
+		cfg:     cfg,
+		bans:    newBanList(),
+		abuse:   newAbuseTracker(time.Minute),
+		sources: newSourceLimiter(cfg.Limits),
+	}
+}
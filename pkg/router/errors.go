@@ -0,0 +1,43 @@
+package router
+
+import "fmt"
+
+// violationError — нарушение протокола клиентом.
+// Код отправляется клиенту в кадре ServerError перед отключением.
+type violationError struct {
+	code byte
+	err  error
+}
+
+func (e *violationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *violationError) Unwrap() error {
+	return e.err
+}
+
+// violation создаёт violationError с указанным кодом ошибки.
+func violation(code byte, format string, args ...any) error {
+	return &violationError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// AuthError — отказ в аутентификации.
+// Status и текст ошибки отправляются клиенту в AuthResult.
+type AuthError struct {
+	Status byte
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// authFailure создаёт AuthError с указанным статусом.
+func authFailure(status byte, err error) error {
+	return &AuthError{Status: status, Err: err}
+}
@@ -0,0 +1,214 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/group"
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+const (
+	// groupOpTimeout — таймаут операций с хранилищем групп.
+	groupOpTimeout = 5 * time.Second
+
+	// groupChangeTTL — допустимое расхождение времени подписанного
+	// изменения группы со временем сервера.
+	groupChangeTTL = 5 * time.Minute
+
+	// groupUpdateRetries — попытки применить изменение при конкурентной записи.
+	groupUpdateRetries = 3
+)
+
+// handleGroupFrame обрабатывает сообщение группе или изменение её состава
+// и рассылает его участникам группы через broker.Publisher.
+// Результат сообщается клиенту через ServerAck: при доставке части участников —
+// AckStatusPartial, чтобы клиент не повторял отправку вслепую.
+func (p *Peer) handleGroupFrame(kind message.Kind, groupID, msgID string, payload []byte) {
+	if p.groups == nil {
+		p.sendAck(protocol.AckStatusRejected, msgID, "groups require JetStream")
+		return
+	}
+
+	// Ключи KV и состав групп хранятся в нижнем регистре
+	groupID = strings.ToLower(groupID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), groupOpTimeout)
+	defer cancel()
+
+	var (
+		recipients []string
+		err        error
+	)
+	if kind == message.Kind_KIND_GROUP_CONTROL {
+		recipients, err = p.applyGroupControl(ctx, groupID, payload)
+	} else {
+		recipients, err = p.groupMembers(ctx, groupID)
+	}
+	if err != nil {
+		status, reason := groupAckStatus(err)
+		slog.Warn("group: rejected", "client", p.pubKeyHex, "group", groupID, "kind", kind, "error", err)
+		p.sendAck(status, msgID, reason)
+		return
+	}
+
+	msg := &message.Message{
+		From:         p.pubKeyHex,
+		Id:           msgID,
+		Payload:      payload,
+		UnixDateTime: time.Now().Unix(),
+		Kind:         kind,
+		GroupId:      groupID,
+	}
+
+	// Публикации участникам идут параллельно: чтение кадров клиента ждёт
+	// подтверждений всех участников не дольше одного таймаута публикации
+	var (
+		pending           []*broker.PublishFuture
+		failed, forbidden int
+	)
+	for _, member := range recipients {
+		if member == p.pubKeyHex {
+			continue
+		}
+		// Участник, не принимающий сообщения от отправителя, пропускается
+		if d := p.authorize(member); !d.Allowed {
+			forbidden++
+			continue
+		}
+		msg.To = member
+
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			slog.Error("group: marshal failed", "client", p.pubKeyHex, "group", groupID, "error", err)
+			failed++
+			continue
+		}
+		pending = append(pending, p.publisher.PublishAsync(member, data))
+	}
+
+	var sent int
+	for _, pub := range pending {
+		if err := pub.Wait(); err != nil {
+			metricPublishErrors.Inc()
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	slog.Debug("group: fan-out", "client", p.pubKeyHex, "group", groupID, "kind", kind, "sent", sent, "failed", failed, "forbidden", forbidden)
+
+	switch {
+	case failed > 0 && sent > 0:
+		// Повтор продублирует сообщение участникам, которые его уже получили
+		p.sendAck(protocol.AckStatusPartial, msgID, fmt.Sprintf("delivered to %d of %d members", sent, sent+failed))
+	case failed > 0:
+		p.sendAck(protocol.AckStatusRejected, msgID, fmt.Sprintf("delivery failed for %d members", failed))
+	default:
+		p.sendAck(protocol.AckStatusAccepted, msgID, "")
+	}
+}
+
+// groupMembers возвращает участников группы, если отправитель в ней состоит.
+func (p *Peer) groupMembers(ctx context.Context, groupID string) ([]string, error) {
+	g, _, err := p.groups.Get(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.IsMember(g, p.pubKeyHex) {
+		return nil, group.ErrNotMember
+	}
+	return g.Members, nil
+}
+
+// applyGroupControl проверяет подписанное изменение состава группы
+// и сохраняет его. Возвращает участников до и после изменения:
+// удалённые тоже должны узнать об изменении.
+func (p *Peer) applyGroupControl(ctx context.Context, groupID string, payload []byte) ([]string, error) {
+	ctl := &message.GroupControl{}
+	if err := proto.Unmarshal(payload, ctl); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal control: %w", group.ErrInvalidChange, err)
+	}
+
+	change, err := group.Verify(ctl)
+	if err != nil {
+		return nil, err
+	}
+	if change.Actor != p.pubKeyHex {
+		return nil, fmt.Errorf("%w: actor is not the sender", group.ErrInvalidChange)
+	}
+	if change.GroupId != groupID {
+		return nil, fmt.Errorf("%w: group id mismatch", group.ErrInvalidChange)
+	}
+	if age := time.Since(time.Unix(change.UnixDateTime, 0)); age > groupChangeTTL || age < -groupChangeTTL {
+		return nil, fmt.Errorf("%w: change expired", group.ErrInvalidChange)
+	}
+
+	for range groupUpdateRetries {
+		current, revision, err := p.groups.Get(ctx, groupID)
+		switch {
+		case errors.Is(err, broker.ErrGroupNotFound):
+			if change.Op != message.GroupOp_GROUP_OP_CREATE {
+				return nil, err
+			}
+			current = nil
+		case err != nil:
+			return nil, err
+		}
+
+		next, err := group.Apply(current, change)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.groups.Put(ctx, next, revision)
+		if errors.Is(err, broker.ErrGroupConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Info("group: changed", "client", p.pubKeyHex, "group", groupID, "op", change.Op, "version", next.Version, "members", len(next.Members))
+
+		recipients := slices.Clone(next.Members)
+		if current != nil {
+			for _, member := range current.Members {
+				if !group.IsMember(next, member) {
+					recipients = append(recipients, member)
+				}
+			}
+		}
+		return recipients, nil
+	}
+
+	return nil, broker.ErrGroupConflict
+}
+
+// groupAckStatus возвращает статус и причину отказа для ServerAck.
+func groupAckStatus(err error) (byte, string) {
+	switch {
+	case errors.Is(err, broker.ErrGroupNotFound):
+		return protocol.AckStatusRecipientUnknown, err.Error()
+	case errors.Is(err, group.ErrInvalidSignature),
+		errors.Is(err, group.ErrInvalidChange),
+		errors.Is(err, group.ErrGroupExists),
+		errors.Is(err, group.ErrNotOwner),
+		errors.Is(err, group.ErrNotMember),
+		errors.Is(err, group.ErrTooManyMembers),
+		errors.Is(err, broker.ErrGroupConflict):
+		return protocol.AckStatusRejected, err.Error()
+	default:
+		return protocol.AckStatusRejected, "group unavailable"
+	}
+}
@@ -0,0 +1,54 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Причины неготовности сервера принимать клиентов.
+var (
+	errNotListening = errors.New("listener is not accepting connections")
+	errNATSNotReady = errors.New("NATS is not connected")
+	errDraining     = errors.New("server is draining")
+)
+
+// health — состояние сервера для проверок оркестратора.
+type health struct {
+	connected func() bool // соединение с NATS установлено (broker.Broker.Connected)
+	listening atomic.Bool // TLS listener принимает соединения
+	draining  atomic.Bool // сервер завершает работу и не ждёт новых клиентов
+}
+
+// ready возвращает nil, если сервер готов принимать новых клиентов.
+func (h *health) ready() error {
+	switch {
+	case h.draining.Load():
+		return errDraining
+	case !h.listening.Load():
+		return errNotListening
+	case !h.connected():
+		return errNATSNotReady
+	default:
+		return nil
+	}
+}
+
+// handleHealthz отвечает 200, пока процесс обслуживает HTTP (liveness).
+func (h *health) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz отвечает 200, если сервер готов принимать клиентов,
+// иначе 503 с причиной.
+func (h *health) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := h.ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthReadyz(t *testing.T) {
+	tests := []struct {
+		name      string
+		listening bool
+		connected bool
+		draining  bool
+		wantCode  int
+		wantBody  string
+	}{
+		{"ready", true, true, false, http.StatusOK, "ready"},
+		{"not listening", false, true, false, http.StatusServiceUnavailable, errNotListening.Error()},
+		{"NATS reconnecting", true, false, false, http.StatusServiceUnavailable, errNATSNotReady.Error()},
+		{"draining", true, true, true, http.StatusServiceUnavailable, errDraining.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &health{connected: func() bool { return tt.connected }}
+			hc.listening.Store(tt.listening)
+			hc.draining.Store(tt.draining)
+
+			rec := httptest.NewRecorder()
+			hc.handleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+
+			// Liveness не зависит от готовности
+			rec = httptest.NewRecorder()
+			hc.handleHealthz(rec, httptest.NewRequest("GET", "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("healthz code = %d, want %d", rec.Code, http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/admin"
+)
+
+// httpShutdownTimeout — время на завершение запросов к служебному HTTP серверу.
+const httpShutdownTimeout = 5 * time.Second
+
+// serveHTTP запускает HTTP сервер name на addr (host:port или unix:/path)
+// и останавливает его при отмене ctx. Ошибка возвращается, только если не удалось занять адрес.
+func (s *server) serveHTTP(ctx context.Context, name, addr string, handler http.Handler) error {
+	lis, err := s.listenHTTP(name, addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http: serve failed", "error", err, "server", name, "addr", addr)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("http: shutdown failed", "error", err, "server", name)
+		}
+	}()
+
+	slog.Info("http: started", "server", name, "addr", lis.Addr().String())
+	return nil
+}
+
+// listenHTTP занимает TCP адрес или unix-сокет (admin.UnixPrefix).
+// Сокет доступен только владельцу процесса; оставшийся от прошлого запуска файл удаляется.
+func (s *server) listenHTTP(name, addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, admin.UnixPrefix)
+	if !ok {
+		return s.sockets.Listen(name, "tcp", addr)
+	}
+
+	lis, err := s.sockets.Listen(name, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return lis, nil
+}
+
+// handler возвращает обработчик служебного HTTP сервера:
+// метрики и проверки оркестратора.
+func (h *health) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", MetricsHandler())
+	mux.HandleFunc("GET /healthz", h.handleHealthz)
+	mux.HandleFunc("GET /readyz", h.handleReadyz)
+	return mux
+}
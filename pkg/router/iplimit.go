@@ -0,0 +1,207 @@
+package router
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/udisondev/sprut/pkg/admin"
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+const (
+	// subnetBitsIPv4, subnetBitsIPv6 — размер подсети для лимитов на подсеть.
+	subnetBitsIPv4 = 24
+	subnetBitsIPv6 = 64
+
+	// sourceIdleTTL — через сколько после последнего соединения забывать источник.
+	sourceIdleTTL = 5 * time.Minute
+)
+
+// Причины отказа sourceLimiter (метка reason метрики sprut_connections_rejected_total).
+const (
+	rejectIPConns     = "ip_connections"
+	rejectSubnetConns = "subnet_connections"
+	rejectIPRate      = "ip_handshake_rate"
+	rejectSubnetRate  = "subnet_handshake_rate"
+)
+
+// sourceLimiter ограничивает число одновременных соединений и частоту новых
+// соединений с одного адреса и одной подсети, чтобы один источник не мог
+// занять все слоты connSlots. Проверка выполняется сразу после Accept,
+// до TLS рукопожатия. Адреса доверенных прокси не ограничиваются.
+type sourceLimiter struct {
+	mu      sync.Mutex
+	limits  config.LimitsConfig
+	trusted []netip.Prefix
+	sources map[netip.Prefix]*sourceState // адрес (/32, /128) и подсеть (/24, /64)
+}
+
+// sourceState — соединения и лимит рукопожатий одного адреса или подсети.
+type sourceState struct {
+	conns    int
+	limiter  *rate.Limiter // nil, если частота не ограничена
+	lastSeen time.Time
+}
+
+func newSourceLimiter(limits config.LimitsConfig) *sourceLimiter {
+	l := &sourceLimiter{sources: make(map[netip.Prefix]*sourceState)}
+	l.setLimits(limits)
+	return l
+}
+
+// setLimits меняет лимиты. Установленные соединения не разрываются;
+// частота рукопожатий известных источников меняется при их следующем соединении.
+func (l *sourceLimiter) setLimits(limits config.LimitsConfig) {
+	trusted := make([]netip.Prefix, 0, len(limits.TrustedProxies))
+	for _, proxy := range limits.TrustedProxies {
+		if prefix, ok := admin.ParseCIDR(proxy); ok {
+			trusted = append(trusted, prefix)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	l.trusted = trusted
+}
+
+// acquire учитывает новое соединение с адреса addr. При отказе возвращает
+// причину; при успехе — функцию, которую нужно вызвать при закрытии соединения.
+func (l *sourceLimiter) acquire(addr netip.Addr, now time.Time) (release func(), reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !addr.IsValid() || l.isTrusted(addr) {
+		return func() {}, ""
+	}
+
+	bits := subnetBitsIPv4
+	if addr.Is6() {
+		bits = subnetBitsIPv6
+	}
+	ipKey := netip.PrefixFrom(addr, addr.BitLen())
+	subnetKey, _ := addr.Prefix(bits)
+
+	ip := l.source(ipKey, l.limits.HandshakeRatePerIP, l.limits.HandshakeBurstPerIP)
+	subnet := l.source(subnetKey, l.limits.HandshakeRatePerSubnet, l.limits.HandshakeBurstPerSubnet)
+	ip.lastSeen, subnet.lastSeen = now, now
+
+	switch {
+	case l.limits.MaxConnsPerIP > 0 && ip.conns >= l.limits.MaxConnsPerIP:
+		return nil, rejectIPConns
+	case l.limits.MaxConnsPerSubnet > 0 && subnet.conns >= l.limits.MaxConnsPerSubnet:
+		return nil, rejectSubnetConns
+	}
+
+	// Токен адреса возвращается, если отказала подсеть: иначе соседи по подсети
+	// расходовали бы лимит адреса, которому соединиться так и не дали
+	ipToken, ok := reserveHandshake(ip.limiter, now)
+	if !ok {
+		return nil, rejectIPRate
+	}
+	if _, ok := reserveHandshake(subnet.limiter, now); !ok {
+		if ipToken != nil {
+			ipToken.CancelAt(now)
+		}
+		return nil, rejectSubnetRate
+	}
+
+	ip.conns++
+	subnet.conns++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			now := time.Now()
+			ip.conns--
+			subnet.conns--
+			ip.lastSeen, subnet.lastSeen = now, now
+		})
+	}, ""
+}
+
+// reserveHandshake берёт у limiter токен на рукопожатие в момент now.
+// Возвращает false, если токена нет; резерв при этом отменён. Для nil
+// limiter (частота не ограничена) возвращает nil резерв и true.
+func reserveHandshake(limiter *rate.Limiter, now time.Time) (*rate.Reservation, bool) {
+	if limiter == nil {
+		return nil, true
+	}
+	r := limiter.ReserveN(now, 1)
+	if !r.OK() || r.DelayFrom(now) > 0 {
+		r.CancelAt(now)
+		return nil, false
+	}
+	return r, true
+}
+
+// source возвращает состояние источника key, приводя его лимит частоты к текущему.
+// Вызывается под mu.
+func (l *sourceLimiter) source(key netip.Prefix, perSec float64, burst int) *sourceState {
+	st, ok := l.sources[key]
+	if !ok {
+		st = &sourceState{}
+		l.sources[key] = st
+	}
+
+	switch {
+	case perSec <= 0:
+		st.limiter = nil
+	case st.limiter == nil:
+		st.limiter = rate.NewLimiter(rate.Limit(perSec), burst)
+	case st.limiter.Limit() != rate.Limit(perSec) || st.limiter.Burst() != burst:
+		st.limiter.SetLimit(rate.Limit(perSec))
+		st.limiter.SetBurst(burst)
+	}
+	return st
+}
+
+// trusts сообщает, принадлежит ли addr доверенному прокси.
+func (l *sourceLimiter) trusts(addr netip.Addr) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.isTrusted(addr)
+}
+
+// isTrusted сообщает, принадлежит ли addr доверенному прокси. Вызывается под mu.
+func (l *sourceLimiter) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range l.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune забывает источники без соединений, не появлявшиеся дольше sourceIdleTTL.
+func (l *sourceLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, st := range l.sources {
+		if st.conns == 0 && now.Sub(st.lastSeen) > sourceIdleTTL {
+			delete(l.sources, key)
+		}
+	}
+}
+
+// watch периодически вызывает prune до отмены ctx.
+func (l *sourceLimiter) watch(ctx context.Context) {
+	ticker := time.NewTicker(sourceIdleTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.prune(now)
+		}
+	}
+}
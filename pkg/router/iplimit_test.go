@@ -0,0 +1,135 @@
+package router
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+func TestSourceLimiterConns(t *testing.T) {
+	l := newSourceLimiter(config.LimitsConfig{
+		MaxConnsPerIP:     2,
+		MaxConnsPerSubnet: 3,
+		TrustedProxies:    []string{"10.0.0.0/8"},
+	})
+	now := time.Now()
+
+	tests := []struct {
+		addr   string
+		reason string
+	}{
+		{"192.0.2.1", ""},
+		{"192.0.2.1", ""},
+		{"192.0.2.1", rejectIPConns},
+		{"192.0.2.2", ""},
+		{"192.0.2.3", rejectSubnetConns}, // та же /24
+		{"198.51.100.1", ""},
+		{"2001:db8::1", ""},
+		{"2001:db8::2", ""},
+		{"2001:db8::3", ""},
+		{"2001:db8::4", rejectSubnetConns}, // та же /64
+		{"2001:db8:0:1::1", ""},
+	}
+
+	var releases []func()
+	for _, tt := range tests {
+		release, reason := l.acquire(netip.MustParseAddr(tt.addr), now)
+		if reason != tt.reason {
+			t.Errorf("acquire(%s) reason = %q, want %q", tt.addr, reason, tt.reason)
+		}
+		if (release == nil) != (tt.reason != "") {
+			t.Errorf("acquire(%s) release = %v, want nil only on rejection", tt.addr, release != nil)
+		}
+		if release != nil {
+			releases = append(releases, release)
+		}
+	}
+
+	// Доверенный прокси не ограничивается
+	for range 10 {
+		if release, reason := l.acquire(netip.MustParseAddr("10.1.2.3"), now); release == nil {
+			t.Fatalf("trusted proxy rejected: %s", reason)
+		}
+	}
+
+	// Освобождённый слот снова доступен; повторный release ничего не меняет
+	releases[0]()
+	releases[0]()
+	if release, reason := l.acquire(netip.MustParseAddr("192.0.2.1"), now); release == nil {
+		t.Errorf("acquire after release rejected: %s", reason)
+	}
+	if _, reason := l.acquire(netip.MustParseAddr("192.0.2.1"), now); reason != rejectIPConns {
+		t.Errorf("acquire over limit after double release: reason = %q, want %q", reason, rejectIPConns)
+	}
+}
+
+func TestSourceLimiterHandshakeRate(t *testing.T) {
+	l := newSourceLimiter(config.LimitsConfig{
+		HandshakeRatePerIP:      1,
+		HandshakeBurstPerIP:     2,
+		HandshakeRatePerSubnet:  1,
+		HandshakeBurstPerSubnet: 3,
+	})
+	now := time.Now()
+	addr := netip.MustParseAddr("192.0.2.1")
+
+	for i := range 2 {
+		release, reason := l.acquire(addr, now)
+		if release == nil {
+			t.Fatalf("handshake %d rejected: %s", i, reason)
+		}
+		release()
+	}
+	if _, reason := l.acquire(addr, now); reason != rejectIPRate {
+		t.Errorf("reason = %q, want %q", reason, rejectIPRate)
+	}
+	other, _ := l.acquire(netip.MustParseAddr("192.0.2.2"), now)
+	if other == nil {
+		t.Fatal("other address in subnet rejected within subnet burst")
+	}
+	other()
+	if _, reason := l.acquire(netip.MustParseAddr("192.0.2.3"), now); reason != rejectSubnetRate {
+		t.Errorf("reason = %q, want %q", reason, rejectSubnetRate)
+	}
+
+	// Через секунду адрес получает новый токен
+	release, reason := l.acquire(addr, now.Add(time.Second))
+	if release == nil {
+		t.Fatalf("handshake after refill rejected: %s", reason)
+	}
+	release()
+
+	l.prune(time.Now().Add(sourceIdleTTL + time.Second))
+	if n := len(l.sources); n != 0 {
+		t.Errorf("sources after prune = %d, want 0", n)
+	}
+}
+
+func TestSourceLimiterSubnetRejectKeepsIPToken(t *testing.T) {
+	l := newSourceLimiter(config.LimitsConfig{
+		HandshakeRatePerIP:      0.1,
+		HandshakeBurstPerIP:     2,
+		HandshakeRatePerSubnet:  1,
+		HandshakeBurstPerSubnet: 1,
+	})
+	now := time.Now()
+	addr := netip.MustParseAddr("192.0.2.1")
+
+	release, reason := l.acquire(addr, now)
+	if release == nil {
+		t.Fatalf("first handshake rejected: %s", reason)
+	}
+	release()
+	if _, reason := l.acquire(addr, now); reason != rejectSubnetRate {
+		t.Fatalf("reason = %q, want %q", reason, rejectSubnetRate)
+	}
+
+	// Отказ подсети не израсходовал второй токен адреса
+	release, reason = l.acquire(addr, now.Add(time.Second))
+	if release == nil {
+		t.Fatalf("handshake after subnet refill rejected: %s", reason)
+	}
+	release()
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/proxyproto"
+)
+
+// listener — listener, принявший соединение: имя для логов и лимиты его клиентов.
+type listener struct {
+	name   string
+	limits config.ListenerLimits
+}
+
+// Listener без собственных лимитов.
+var (
+	mainListener      = &listener{name: "main"} // server.host:port
+	webSocketListener = &listener{name: "websocket"}
+	quicListener      = &listener{name: "quic"}
+)
+
+// apply возвращает лимиты для клиента listener. nil — общие лимиты.
+func (l *listener) apply(limits config.LimitsConfig) config.LimitsConfig {
+	if l == nil {
+		return limits
+	}
+	return l.limits.Apply(limits)
+}
+
+// serveListener запускает дополнительный listener (config.ListenerConfig).
+// Без собственного tls используется tlsConfig основного listener.
+// Listener закрывается с отменой ctx; установленные соединения завершаются
+// в drain вместе с остальными.
+func (s *server) serveListener(ctx context.Context, cfg config.ListenerConfig, tlsConfig *tls.Config) error {
+	l := &listener{name: cfg.DisplayName(), limits: cfg.Limits}
+
+	if cfg.TLS != nil {
+		var err error
+		tlsConfig, err = s.listenerTLS(ctx, *cfg.TLS)
+		if err != nil {
+			return err
+		}
+	}
+
+	network := cfg.Network
+	if network == "" {
+		network = config.NetworkTCP
+	}
+	lis, err := s.sockets.Listen(listenerSocketName(cfg), network, cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	addr := lis.Addr().String()
+	if cfg.ProxyProtocol {
+		lis = s.proxyListener(lis, l.name)
+	}
+	tlsLis := tls.NewListener(lis, tlsConfig)
+
+	go func() {
+		<-ctx.Done()
+		if err := tlsLis.Close(); err != nil {
+			slog.Error("listener: close failed", "error", err, "listener", l.name)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := tlsLis.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				slog.Error("listener: accept failed", "error", err, "listener", l.name)
+				continue
+			}
+			s.serveConn(conn, l)
+		}
+	}()
+
+	slog.Info("listener: started", "listener", l.name, "network", network, "addr", addr, "proxy_protocol", cfg.ProxyProtocol)
+	return nil
+}
+
+// proxyListener читает заголовок PROXY protocol соединений lis до TLS рукопожатия:
+// RemoteAddr принятых соединений — адрес клиента за балансировщиком, он же
+// используется в логах, лимитах на источник, блокировках и admin API.
+// Заголовок должен прийти за limits.auth_timeout на момент запуска.
+func (s *server) proxyListener(lis net.Listener, name string) net.Listener {
+	return proxyproto.NewListener(lis, s.cfg.Limits.AuthTimeout, func(conn net.Conn, err error) {
+		metricProxyHeaderErrors.Inc()
+		slog.Warn("listener: invalid PROXY header, connection closed", "error", err, "listener", name, "remote", conn.RemoteAddr())
+	})
+}
+
+// listenerTLS создаёт TLS конфигурацию listener с собственным сертификатом.
+// Сертификат перечитывается так же, как основной: по SIGHUP и при изменении файлов.
+func (s *server) listenerTLS(ctx context.Context, cfg config.TLSConfig) (*tls.Config, error) {
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	tlsConfig := buildTLSConfig(cfg, certs)
+	if s.cfg.Auth.Enabled(config.AuthMethodMTLS) {
+		if err := configureClientAuth(tlsConfig, s.cfg.Auth.MTLS); err != nil {
+			return nil, fmt.Errorf("configure client auth: %w", err)
+		}
+	}
+
+	s.listenerCerts = append(s.listenerCerts, certs)
+	if cfg.ReloadInterval > 0 {
+		go certs.watch(ctx, cfg.ReloadInterval)
+	}
+	return tlsConfig, nil
+}
+
+// listenerSocketName возвращает имя сокета дополнительного listener для передачи
+// новому процессу и socket activation. Сокет listener без name ищется по адресу.
+func listenerSocketName(cfg config.ListenerConfig) string {
+	if cfg.Name == "" {
+		return ""
+	}
+	return "listener/" + cfg.Name
+}
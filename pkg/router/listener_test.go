@@ -0,0 +1,97 @@
+package router
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+func TestListenerApply(t *testing.T) {
+	limits := config.Default().Limits
+
+	var none *listener
+	if got := none.apply(limits); got.RateLimitPerSec != limits.RateLimitPerSec {
+		t.Errorf("nil listener: rate = %v, want %v", got.RateLimitPerSec, limits.RateLimitPerSec)
+	}
+
+	l := &listener{name: "bots", limits: config.ListenerLimits{
+		RateLimitPerSec: 1000,
+		RateLimitMode:   config.RateLimitModeThrottle,
+	}}
+	got := l.apply(limits)
+	if got.RateLimitPerSec != 1000 {
+		t.Errorf("rate = %v, want 1000", got.RateLimitPerSec)
+	}
+	if got.RateLimitMode != config.RateLimitModeThrottle {
+		t.Errorf("rate limit mode = %q, want %q", got.RateLimitMode, config.RateLimitModeThrottle)
+	}
+	// Не заданные listener значения — общие
+	if got.RateLimitBurst != limits.RateLimitBurst || got.IdleTimeout != limits.IdleTimeout {
+		t.Errorf("burst = %d, idle = %v; want %d, %v", got.RateLimitBurst, got.IdleTimeout, limits.RateLimitBurst, limits.IdleTimeout)
+	}
+}
+
+func TestApplyLimitsKeepsListenerLimits(t *testing.T) {
+	limits := config.Default().Limits
+	s := &server{
+		auths:   map[byte]Authenticator{},
+		slots:   newConnSlots(limits.MaxConnections),
+		sources: newSourceLimiter(limits),
+	}
+	s.limits.Store(newLimitsState(limits))
+
+	bots := &listener{name: "bots", limits: config.ListenerLimits{RateLimitPerSec: 1000}}
+	bot := &Peer{listener: bots, limiter: rate.NewLimiter(1000, limits.RateLimitBurst)}
+	user := &Peer{limiter: rate.NewLimiter(rate.Limit(limits.RateLimitPerSec), limits.RateLimitBurst)}
+	s.peers.Store(PeerID{1}, bot)
+	s.peers.Store(PeerID{2}, user)
+
+	next := limits
+	next.RateLimitPerSec = 5
+	next.RateLimitBurst = 2
+	s.applyLimits(next)
+
+	if got := bot.limiter.Limit(); got != 1000 {
+		t.Errorf("listener peer rate = %v, want 1000", got)
+	}
+	if got := bot.limiter.Burst(); got != 2 {
+		t.Errorf("listener peer burst = %d, want 2", got)
+	}
+	if got := user.limiter.Limit(); got != 5 {
+		t.Errorf("peer rate = %v, want 5", got)
+	}
+}
+
+func TestProxyListenerRemoteAddr(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &server{cfg: config.Default()}
+	lis := tls.NewListener(s.proxyListener(raw, "test"), &tls.Config{})
+	defer lis.Close()
+
+	client, err := net.Dial("tcp", raw.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 5555 443\r\n")); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer conn.Close()
+
+	// Адрес клиента из заголовка — для лимитов, блокировок и логов
+	if got := remoteIP(conn).String(); got != "203.0.113.7" {
+		t.Errorf("remote IP = %s, want 203.0.113.7", got)
+	}
+}
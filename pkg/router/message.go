@@ -15,11 +15,11 @@ import (
 	"github.com/udisondev/sprut/pkg/protocol"
 )
 
-// minMessageSize — минимальный размер сообщения:
+// minMessageSize — минимальный размер сообщения (и квитанции):
 // To (64 hex chars) + MsgIDLen (2 bytes) = 66 bytes.
 const minMessageSize = protocol.PublicKeySize*2 + 2
 
-// errInvalidRecipient возвращается при невалидном формате адресата.
+// errInvalidRecipient сообщается клиенту в ServerAck при невалидном формате адресата.
 var errInvalidRecipient = errors.New("invalid recipient pubkey format")
 
 // messagePool — пул для переиспользования protobuf Message объектов.
@@ -47,56 +47,99 @@ func isValidHexPubKey(s string) bool {
 	return true
 }
 
-
-// handleMessage читает и обрабатывает одно сообщение от клиента.
-func handleMessage(peer *Peer, pool *sync.Pool, maxMessageSize int) error {
+// handleMessage читает и обрабатывает один кадр от клиента.
+// Ошибка означает нарушение протокола (violationError) или обрыв соединения — клиент отключается.
+// Ошибки маршрутизации отдельного сообщения сообщаются клиенту через ServerAck.
+//
+// admit списывает прочитанный кадр из лимита частоты клиента; nil — без лимита.
+// Лимит расходуют сообщения, кадры групп и квитанции, не отвечающие на доставку
+// (pendingReceipts). Ping и списки ключей его не расходуют: клиент отправляет их
+// без участия приложения, и они обрабатываются в read loop по одному.
+func handleMessage(peer *Peer, pool *sync.Pool, maxMessageSize int, admit func() error) error {
 	bufPtr := pool.Get().(*[]byte)
 	defer pool.Put(bufPtr)
 	buf := *bufPtr
 
-	// 1. Читаем длину сообщения (4 bytes)
-	if _, err := io.ReadFull(peer.conn, buf[:4]); err != nil {
-		return fmt.Errorf("read length: %w", err)
+	// 1. Читаем заголовок кадра: Type(1) + Len(4)
+	if _, err := io.ReadFull(peer.conn, buf[:protocol.FrameHeaderSize]); err != nil {
+		return fmt.Errorf("read frame header: %w", err)
 	}
 
-	totalLen := binary.BigEndian.Uint32(buf[:4])
+	frameType := buf[0]
+	totalLen := binary.BigEndian.Uint32(buf[1:protocol.FrameHeaderSize])
 	if totalLen > uint32(maxMessageSize) {
-		slog.Warn("message: too large", "client", peer.pubKeyHex, "size", totalLen, "max", maxMessageSize)
-		return fmt.Errorf("message too large: %d bytes", totalLen)
+		slog.Warn("message: too large", "client", peer.pubKeyHex, "type", frameType, "size", totalLen, "max", maxMessageSize)
+		return violation(protocol.ErrorCodeMessageTooLarge, "message too large: %d bytes", totalLen)
+	}
+
+	// Квитанции о доставке имеют формат ClientMessage без payload
+	// и публикуются без ServerAck.
+	var kind message.Kind
+	var toGroup bool
+	switch frameType {
+	case protocol.TypeClientMessage:
+		kind = message.Kind_KIND_MESSAGE
+	case protocol.TypeClientReceipt:
+		kind = message.Kind_KIND_RECEIPT
+	case protocol.TypeClientGroupMessage:
+		kind, toGroup = message.Kind_KIND_MESSAGE, true
+	case protocol.TypeClientGroupControl:
+		kind, toGroup = message.Kind_KIND_GROUP_CONTROL, true
+	case protocol.TypeClientPresenceWatch, protocol.TypeClientPresenceAllow,
+		protocol.TypeClientBlockList, protocol.TypeClientContactList:
+		if int(totalLen) > len(buf) {
+			return violation(protocol.ErrorCodeMessageTooLarge, "key list too large for buffer: %d", totalLen)
+		}
+		if _, err := io.ReadFull(peer.conn, buf[:totalLen]); err != nil {
+			return fmt.Errorf("read key list: %w", err)
+		}
+		if frameType == protocol.TypeClientBlockList || frameType == protocol.TypeClientContactList {
+			return peer.handlePolicyList(frameType, buf[:totalLen])
+		}
+		return peer.handlePresenceList(frameType, buf[:totalLen])
+	case protocol.TypeClientPing:
+		if totalLen != protocol.PingSeqSize {
+			return violation(protocol.ErrorCodeProtocol, "invalid ping length: %d", totalLen)
+		}
+		if _, err := io.ReadFull(peer.conn, buf[:totalLen]); err != nil {
+			return fmt.Errorf("read ping: %w", err)
+		}
+		// Pong идёт через writeLoop: клиент видит, что запись ему не стоит
+		peer.enqueue(outbound{
+			frameType: protocol.TypeServerPong,
+			pong:      protocol.ServerPong{Seq: binary.BigEndian.Uint64(buf[:totalLen])},
+		})
+		return nil
+	default:
+		slog.Warn("message: unexpected frame type", "client", peer.pubKeyHex, "type", frameType)
+		return violation(protocol.ErrorCodeProtocol, "unexpected frame type: %d", frameType)
 	}
 
 	// Проверка минимальной длины для предотвращения buffer underflow
 	if totalLen < minMessageSize {
 		slog.Warn("message: too small", "client", peer.pubKeyHex, "size", totalLen, "min", minMessageSize)
-		return fmt.Errorf("message too small: %d bytes, minimum %d", totalLen, minMessageSize)
+		return violation(protocol.ErrorCodeProtocol, "message too small: %d bytes, minimum %d", totalLen, minMessageSize)
 	}
 
 	slog.Debug("message: received", "client", peer.pubKeyHex, "size", totalLen)
 
-	// 2. Читаем остаток сообщения
+	// 2. Читаем тело кадра
 	// totalLen = To(64) + MsgIDLen(2) + MsgID + Payload
 	if int(totalLen) > len(buf) {
-		return fmt.Errorf("message too large for buffer: %d", totalLen)
+		return violation(protocol.ErrorCodeMessageTooLarge, "message too large for buffer: %d", totalLen)
 	}
 
 	if _, err := io.ReadFull(peer.conn, buf[:totalLen]); err != nil {
 		return fmt.Errorf("read message body: %w", err)
 	}
+	metricMessagesIn.Inc()
+	peer.messagesIn.Add(1)
 
 	// 3. Парсим заголовок
-	// To - 64 hex символа pubkey получателя
-	to := string(buf[:protocol.PublicKeySize*2])
-
-	// Валидация hex для предотвращения NATS subject injection
-	if !isValidHexPubKey(to) {
-		slog.Warn("message: invalid recipient", "client", peer.pubKeyHex, "to_raw", to)
-		return errInvalidRecipient
-	}
-
 	msgIDLen := binary.BigEndian.Uint16(buf[protocol.PublicKeySize*2 : protocol.PublicKeySize*2+2])
 	if int(msgIDLen) > protocol.MaxMsgIDLen {
 		slog.Warn("message: msgID too long", "client", peer.pubKeyHex, "len", msgIDLen, "max", protocol.MaxMsgIDLen)
-		return fmt.Errorf("msgID too long: %d", msgIDLen)
+		return violation(protocol.ErrorCodeProtocol, "msgID too long: %d", msgIDLen)
 	}
 
 	// 4. Вычисляем позиции MsgID и Payload
@@ -104,13 +147,61 @@ func handleMessage(peer *Peer, pool *sync.Pool, maxMessageSize int) error {
 	msgIDEnd := msgIDStart + int(msgIDLen)
 
 	if msgIDEnd > int(totalLen) {
-		return fmt.Errorf("invalid message structure: msgID exceeds total length")
+		return violation(protocol.ErrorCodeProtocol, "invalid message structure: msgID exceeds total length")
 	}
 
 	msgID := string(buf[msgIDStart:msgIDEnd])
 	payload := buf[msgIDEnd:totalLen]
 
-	slog.Debug("message: parsed", "client", peer.pubKeyHex, "to", to, "msg_id", msgID, "payload_size", len(payload))
+	if kind == message.Kind_KIND_RECEIPT && len(payload) != 0 {
+		slog.Warn("message: receipt with payload", "client", peer.pubKeyHex, "payload_size", len(payload))
+		return violation(protocol.ErrorCodeProtocol, "receipt with payload: %d bytes", len(payload))
+	}
+
+	// To - 64 hex символа pubkey получателя или id группы
+	to := string(buf[:protocol.PublicKeySize*2])
+
+	// Квитанция на доставленное клиенту сообщение — ответ на доставку
+	answered := kind == message.Kind_KIND_RECEIPT && peer.receipts.take(to, msgID)
+
+	// Ответ на доставку лимит частоты не расходует:
+	// иначе приём backlog отключал бы получателя за его же квитанции
+	if admit != nil && !answered {
+		if err := admit(); err != nil {
+			return err
+		}
+	}
+
+	// Валидация hex для предотвращения NATS subject injection
+	if !isValidHexPubKey(to) {
+		slog.Warn("message: invalid recipient", "client", peer.pubKeyHex, "to_raw", to)
+		if kind != message.Kind_KIND_RECEIPT {
+			peer.sendAck(protocol.AckStatusRecipientUnknown, msgID, errInvalidRecipient.Error())
+		}
+		return nil
+	}
+
+	if toGroup {
+		peer.handleGroupFrame(kind, to, msgID, payload)
+		return nil
+	}
+
+	slog.Debug("message: parsed", "client", peer.pubKeyHex, "to", to, "msg_id", msgID, "kind", kind, "payload_size", len(payload))
+
+	// Авторизация: квитанции проверяются так же, иначе через них можно писать
+	// заблокировавшему отправителя. Ответ на доставку разрешён всегда: адресат
+	// сам только что написал клиенту, и в режиме "только контакты" квитанция
+	// не должна требовать, чтобы он был в контактах клиента.
+	// Отклонённая квитанция отбрасывается без ServerAck.
+	if !answered {
+		if d := peer.authorize(to); !d.Allowed {
+			slog.Info("message: forbidden by policy", "client", peer.pubKeyHex, "to", to, "kind", kind, "reason", d.Reason)
+			if kind == message.Kind_KIND_MESSAGE {
+				peer.sendAck(protocol.AckStatusForbidden, msgID, d.Reason)
+			}
+			return nil
+		}
+	}
 
 	// 5. Получаем Message из пула (zero-allocation hot path)
 	msg := messagePool.Get().(*message.Message)
@@ -124,21 +215,30 @@ func handleMessage(peer *Peer, pool *sync.Pool, maxMessageSize int) error {
 	msg.Id = msgID
 	msg.Payload = payload
 	msg.UnixDateTime = time.Now().Unix()
+	msg.Kind = kind
 
 	// 6. Сериализуем
 	data, err := proto.Marshal(msg)
 	if err != nil {
 		slog.Error("message: marshal failed", "client", peer.pubKeyHex, "error", err)
-		return fmt.Errorf("marshal message: %w", err)
+		return violation(protocol.ErrorCodeInternal, "marshal message: %w", err)
 	}
 
 	// 7. Публикуем в NATS
 	if err := peer.publisher.Publish(to, data); err != nil {
+		metricPublishErrors.Inc()
 		slog.Error("message: publish failed", "client", peer.pubKeyHex, "to", to, "error", err)
-		return fmt.Errorf("publish to NATS: %w", err)
+		if kind == message.Kind_KIND_MESSAGE {
+			peer.sendAck(protocol.AckStatusRejected, msgID, "publish failed")
+		}
+		return nil
 	}
 
 	slog.Debug("message: published", "client", peer.pubKeyHex, "to", to, "subject", "goro.msg."+to)
 
+	if kind == message.Kind_KIND_MESSAGE {
+		peer.sendAck(protocol.AckStatusAccepted, msgID, "")
+	}
+
 	return nil
 }
@@ -1,6 +1,9 @@
 package router
 
 import (
+	"net"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/udisondev/sprut/pkg/protocol"
@@ -91,3 +94,35 @@ func TestIsValidHexPubKey_Length(t *testing.T) {
 		t.Errorf("expected key length to be 64, got %d", expectedLen)
 	}
 }
+
+func TestHandleMessageForbiddenReceipt(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:      serverConn,
+		pubKeyHex: strings.Repeat("ab", protocol.PublicKeySize),
+		policy:    denyPolicy("blocked"),
+		writeCh:   make(chan outbound, 1),
+		ctrlCh:    make(chan outbound, 1),
+		closeCh:   make(chan struct{}),
+	}
+	defer p.Close()
+
+	go func() {
+		receipt := &protocol.ClientReceipt{To: strings.Repeat("cd", protocol.PublicKeySize), MsgID: "m1"}
+		_ = receipt.Encode(clientConn)
+	}()
+
+	// Пропущенная политикой квитанция дошла бы до publisher, которого у пира нет
+	pool := &sync.Pool{New: func() any {
+		buf := make([]byte, 1024)
+		return &buf
+	}}
+	if err := handleMessage(p, pool, 1024, nil); err != nil {
+		t.Fatalf("handleMessage: %v", err)
+	}
+	if n := len(p.writeCh) + len(p.ctrlCh); n != 0 {
+		t.Errorf("queued %d frames for forbidden receipt, want none", n)
+	}
+}
@@ -0,0 +1,261 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// registry — метрики роутера, отдаются по HTTP (см. config.HTTPConfig).
+var registry = newRegistry()
+
+// factory регистрирует метрики роутера в registry.
+var factory = promauto.With(registry)
+
+var (
+	metricPeersActive = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "sprut_peers_active",
+		Help: "Number of authenticated clients connected to this server.",
+	})
+	metricAuthAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_auth_attempts_total",
+		Help: "Authentication attempts by handshake method.",
+	}, []string{"method"})
+	metricAuthFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_auth_failures_total",
+		Help: "Failed authentication attempts by reason.",
+	}, []string{"reason"})
+	metricHandshakeDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sprut_handshake_duration_seconds",
+		Help:    "Time from accepting a connection to successful authentication, including TLS handshake.",
+		Buckets: prometheus.DefBuckets,
+	})
+	metricAuthSlotsExhausted = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_auth_slots_exhausted_total",
+		Help: "Connections rejected because limits.max_connections was reached.",
+	})
+
+	metricMessagesIn = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_messages_in_total",
+		Help: "Messages, receipts and group frames received from clients.",
+	})
+	metricMessagesOut = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_messages_out_total",
+		Help: "Messages delivered to clients.",
+	})
+	metricBytesIn = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_bytes_in_total",
+		Help: "Bytes read from authenticated clients.",
+	})
+	metricBytesOut = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_bytes_out_total",
+		Help: "Bytes written to authenticated clients.",
+	})
+	metricPublishErrors = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_publish_errors_total",
+		Help: "Failed publications to NATS.",
+	})
+	metricFramesDropped = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_frames_dropped_total",
+		Help: "Frames dropped because a client's write queue was full, by limits.overflow_policy.",
+	}, []string{"policy"})
+	metricMessagesSpilled = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_messages_spilled_total",
+		Help: "Messages left in JetStream for redelivery because a client's write queue was full.",
+	})
+	metricInboundThrottled = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_inbound_throttled_total",
+		Help: "Frames whose reading was delayed by the client rate limit (limits.rate_limit_mode: throttle).",
+	})
+	metricIdleTimeouts = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_idle_timeouts_total",
+		Help: "Clients disconnected because no frame arrived within limits.idle_timeout.",
+	})
+	metricDisconnects = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_disconnects_total",
+		Help: "Clients disconnected by the server with an error frame, by reason.",
+	}, []string{"reason"})
+
+	metricConnsRejected = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_connections_rejected_total",
+		Help: "Connections closed before the TLS handshake by per-source limits, by reason.",
+	}, []string{"reason"})
+	metricBannedConns = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_banned_connections_total",
+		Help: "Connections from banned addresses closed before authentication.",
+	})
+	metricProxyHeaderErrors = factory.NewCounter(prometheus.CounterOpts{
+		Name: "sprut_proxy_header_errors_total",
+		Help: "Connections on PROXY protocol listeners closed for a missing or invalid header.",
+	})
+	metricAutoBans = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "sprut_auto_bans_total",
+		Help: "Temporary bans issued automatically, by trigger.",
+	}, []string{"reason"})
+
+	metricTLSCertExpiry = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sprut_tls_cert_expiry_timestamp_seconds",
+		Help: "Expiry time of the active server TLS certificate, Unix seconds, by certificate file.",
+	}, []string{"cert_file"})
+)
+
+// metricNATS — состояние соединения с NATS, см. observeBroker.
+var metricNATS = &natsCollector{
+	connected: prometheus.NewDesc("sprut_nats_connected",
+		"Whether the NATS connection is established (1) or not (0).", nil, nil),
+	reconnects: prometheus.NewDesc("sprut_nats_reconnects_total",
+		"NATS reconnections since the server started.", nil, nil),
+}
+
+// newRegistry создаёт registry с метриками процесса, Go runtime и NATS.
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metricNATS,
+	)
+	return r
+}
+
+// MetricsHandler возвращает http.Handler с метриками роутера
+// в формате Prometheus.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// natsCollector отдаёт состояние соединения брокера с NATS.
+// Пока брокер не наблюдается, метрики не экспортируются.
+type natsCollector struct {
+	broker     atomic.Pointer[broker.Broker]
+	connected  *prometheus.Desc
+	reconnects *prometheus.Desc
+}
+
+func (c *natsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connected
+	ch <- c.reconnects
+}
+
+func (c *natsCollector) Collect(ch chan<- prometheus.Metric) {
+	brk := c.broker.Load()
+	if brk == nil {
+		return
+	}
+	var connected float64
+	if brk.Connected() {
+		connected = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, connected)
+	ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, float64(brk.Conn().Stats().Reconnects))
+}
+
+// observeBroker начинает отдавать состояние соединения брокера с NATS.
+// Возвращает функцию, прекращающую наблюдение.
+func observeBroker(brk *broker.Broker) func() {
+	metricNATS.broker.Store(brk)
+	return func() {
+		metricNATS.broker.CompareAndSwap(brk, nil)
+	}
+}
+
+// observeAuth учитывает результат аутентификации, начатой в accepted.
+func observeAuth(method string, accepted time.Time, err error) {
+	if method == "" {
+		method = "unknown"
+	}
+	metricAuthAttempts.WithLabelValues(method).Inc()
+	if err != nil {
+		metricAuthFailures.WithLabelValues(authFailureReason(err)).Inc()
+		return
+	}
+	metricHandshakeDuration.Observe(time.Since(accepted).Seconds())
+}
+
+// authFailureReason возвращает значение метки reason для ошибки аутентификации.
+func authFailureReason(err error) string {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		switch {
+		case errors.Is(err, errBanned):
+			return "banned"
+		case errors.Is(err, errTokenExpired), errors.Is(err, protocol.ErrChallengeExpired):
+			return "expired"
+		case errors.Is(err, protocol.ErrUnsupportedVersion):
+			return "unsupported_version"
+		case authErr.Status == protocol.AuthStatusInvalidSig:
+			return "invalid_signature"
+		case authErr.Status == protocol.AuthStatusReplay:
+			return "replay"
+		default:
+			return "rejected"
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUnsupportedHandshake):
+		return "unsupported_method"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "closed"
+	default:
+		return "io"
+	}
+}
+
+// disconnectReason возвращает значение метки reason для кода ServerError.
+func disconnectReason(code byte) string {
+	switch code {
+	case protocol.ErrorCodeInternal:
+		return "internal"
+	case protocol.ErrorCodeProtocol:
+		return "protocol"
+	case protocol.ErrorCodeMessageTooLarge:
+		return "message_too_large"
+	case protocol.ErrorCodeRateLimited:
+		return "rate_limited"
+	case protocol.ErrorCodeSlowConsumer:
+		return "slow_consumer"
+	case protocol.ErrorCodeReplaced:
+		return "replaced"
+	case protocol.ErrorCodeKicked:
+		return "kicked"
+	default:
+		return "unknown"
+	}
+}
+
+// meteredConn считает байты, прочитанные и записанные через соединение.
+type meteredConn struct {
+	net.Conn
+	in, out prometheus.Counter
+}
+
+func newMeteredConn(conn net.Conn) *meteredConn {
+	return &meteredConn{Conn: conn, in: metricBytesIn, out: metricBytesOut}
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.in.Add(float64(n))
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.out.Add(float64(n))
+	return n, err
+}
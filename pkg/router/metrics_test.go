@@ -0,0 +1,96 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func TestAuthFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"invalid signature", authFailure(protocol.AuthStatusInvalidSig, errors.New("bad sig")), "invalid_signature"},
+		{"replay", authFailure(protocol.AuthStatusReplay, errors.New("timestamp in future")), "replay"},
+		{"challenge expired", authFailure(protocol.AuthStatusReplay, protocol.ErrChallengeExpired), "expired"},
+		{"token expired", authFailure(protocol.AuthStatusFailed, errTokenExpired), "expired"},
+		{"unsupported version", authFailure(protocol.AuthStatusFailed, protocol.ErrUnsupportedVersion), "unsupported_version"},
+		{"rejected", authFailure(protocol.AuthStatusFailed, errTokenMalformed), "rejected"},
+		{"banned", authFailure(protocol.AuthStatusFailed, fmt.Errorf("%w until tomorrow", errBanned)), "banned"},
+		{"unsupported method", fmt.Errorf("%w: %d", errUnsupportedHandshake, 9), "unsupported_method"},
+		{"timeout", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), "timeout"},
+		{"closed", fmt.Errorf("read handshake type: %w", io.EOF), "closed"},
+		{"io", errors.New("connection reset"), "io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authFailureReason(tt.err); got != tt.want {
+				t.Errorf("authFailureReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeteredConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	conn := &meteredConn{
+		Conn: server,
+		in:   prometheus.NewCounter(prometheus.CounterOpts{Name: "in", Help: "In."}),
+		out:  prometheus.NewCounter(prometheus.CounterOpts{Name: "out", Help: "Out."}),
+	}
+	defer conn.Close()
+
+	go func() {
+		_, _ = client.Write([]byte("hello"))
+		_, _ = io.ReadFull(client, make([]byte, 3))
+	}()
+
+	if _, err := io.ReadFull(conn, make([]byte, 5)); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if _, err := conn.Write([]byte("abc")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := testutil.ToFloat64(conn.in); got != 5 {
+		t.Errorf("bytes in = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(conn.out); got != 3 {
+		t.Errorf("bytes out = %v, want 3", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metricDisconnects.WithLabelValues(disconnectReason(protocol.ErrorCodeSlowConsumer)).Inc()
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE sprut_peers_active gauge",
+		"# TYPE sprut_handshake_duration_seconds histogram",
+		"# TYPE sprut_auth_slots_exhausted_total counter",
+		`sprut_disconnects_total{reason="slow_consumer"}`,
+		"# TYPE go_goroutines gauge",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
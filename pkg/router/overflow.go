@@ -0,0 +1,194 @@
+package router
+
+import (
+	"log/slog"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+const (
+	// spillRedeliveryDelay — через сколько JetStream повторит доставку
+	// сообщения, не поместившегося в очередь записи (OverflowSpill).
+	spillRedeliveryDelay = 5 * time.Second
+
+	// spillAckPendingFactor — во сколько раз MaxAckPending consumer'а больше
+	// очереди записи при OverflowSpill (см. mailboxAckPending).
+	spillAckPendingFactor = 2
+
+	// dropOldestRetries — попытки освободить место в очереди при OverflowDropOldest:
+	// очередь одновременно пополняют обработчик NATS и read loop.
+	dropOldestRetries = 3
+)
+
+// overflowPolicy — политика переполнения очереди записи (config.Overflow*).
+type overflowPolicy uint32
+
+const (
+	overflowDisconnect overflowPolicy = iota
+	overflowDropOldest
+	overflowDropNewest
+	overflowSpill
+)
+
+// parseOverflowPolicy возвращает политику по значению limits.overflow_policy.
+// Неизвестное и пустое значение — overflowDisconnect.
+func parseOverflowPolicy(s string) overflowPolicy {
+	switch s {
+	case config.OverflowDropOldest:
+		return overflowDropOldest
+	case config.OverflowDropNewest:
+		return overflowDropNewest
+	case config.OverflowSpill:
+		return overflowSpill
+	default:
+		return overflowDisconnect
+	}
+}
+
+func (o overflowPolicy) String() string {
+	switch o {
+	case overflowDropOldest:
+		return config.OverflowDropOldest
+	case overflowDropNewest:
+		return config.OverflowDropNewest
+	case overflowSpill:
+		return config.OverflowSpill
+	default:
+		return config.OverflowDisconnect
+	}
+}
+
+// SetOverflowPolicy меняет политику переполнения очереди записи (config.Overflow*).
+// MaxAckPending consumer'а JetStream (см. mailboxAckPending) задаётся при
+// подключении: после смены политики на spill или обратно он меняется
+// со следующего подключения клиента.
+func (p *Peer) SetOverflowPolicy(policy string) {
+	p.overflow.Store(uint32(parseOverflowPolicy(policy)))
+}
+
+func (p *Peer) overflowPolicy() overflowPolicy {
+	return overflowPolicy(p.overflow.Load())
+}
+
+// mailboxAckPending возвращает MaxAckPending consumer'а JetStream для очереди
+// записи на writeBufferSize сообщений. Обычно JetStream не выдаёт больше, чем
+// помещается в очередь, и backlog не переполняет её. При spill consumer выдаёт
+// больше: не поместившееся сообщение возвращается в JetStream (NakWithDelay),
+// а не держит доставку остальных до подтверждения.
+func mailboxAckPending(writeBufferSize int, policy overflowPolicy) int {
+	if policy == overflowSpill {
+		return writeBufferSize * spillAckPendingFactor
+	}
+	return writeBufferSize
+}
+
+// handleOverflow применяет политику переполнения к сообщению, не поместившемуся
+// в очередь. Служебные кадры идут через ctrlCh и политикой не отбрасываются.
+func (p *Peer) handleOverflow(out outbound) {
+	policy := p.overflowPolicy()
+
+	switch policy {
+	case overflowDropOldest:
+		for range dropOldestRetries {
+			select {
+			case old := <-p.writeCh:
+				p.drop(old, policy)
+			default:
+			}
+			select {
+			case p.writeCh <- out:
+				return
+			default:
+			}
+		}
+		p.drop(out, policy)
+
+	case overflowDropNewest:
+		p.drop(out, policy)
+
+	case overflowSpill:
+		// Сообщение остаётся в JetStream и будет доставлено повторно.
+		// Без JetStream spill отклоняет config.Validate; сообщение, которое
+		// сохранить негде, отбрасывается
+		if out.jsMsg == nil {
+			p.drop(out, policy)
+			return
+		}
+		if err := out.jsMsg.NakWithDelay(spillRedeliveryDelay); err != nil {
+			slog.Warn("peer: JetStream nak failed", "error", err, "client", p.pubKeyHex)
+		}
+		metricMessagesSpilled.Inc()
+		slog.Debug("peer: write buffer full, message left in JetStream", "client", p.pubKeyHex)
+
+	default:
+		p.disconnectSlow()
+	}
+}
+
+// disconnectSlow отключает клиента, не успевающего читать (slow consumer).
+func (p *Peer) disconnectSlow() {
+	slog.Warn("peer: write buffer full, disconnecting slow client", "client", p.pubKeyHex)
+	// enqueue вызывается из обработчика NATS и из read loop — не блокируем их
+	go p.CloseWithError(protocol.ErrorCodeSlowConsumer, "write buffer full")
+}
+
+// drop отбрасывает сообщение, не поместившееся в очередь записи.
+// Сообщение JetStream удаляется из очереди получателя (Term),
+// автору пользовательского сообщения отправляется квитанция message.ReceiptDropped.
+func (p *Peer) drop(out outbound, policy overflowPolicy) {
+	metricFramesDropped.WithLabelValues(policy.String()).Inc()
+	slog.Debug("peer: write buffer full, frame dropped", "client", p.pubKeyHex, "type", out.frameType, "policy", policy)
+
+	if out.jsMsg != nil {
+		if err := out.jsMsg.Term(); err != nil {
+			slog.Warn("peer: JetStream term failed", "error", err, "client", p.pubKeyHex)
+		}
+	}
+	p.notifyDropped(out.data)
+}
+
+// notifyDropped сообщает автору, что сообщение не доставлено получателю.
+// Вызывается из обработчика NATS и read loop, поэтому подтверждение JetStream
+// ждётся в отдельной горутине: не дольше таймаута публикации, а число
+// неподтверждённых публикаций ограничивает клиент JetStream.
+func (p *Peer) notifyDropped(data []byte) {
+	if p.publisher == nil {
+		return
+	}
+
+	msg := &message.Message{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		slog.Error("peer: unmarshal dropped message failed", "error", err, "client", p.pubKeyHex)
+		return
+	}
+	// Квитанции и изменения групп не подтверждаются
+	if msg.Kind != message.Kind_KIND_MESSAGE || msg.From == "" {
+		return
+	}
+
+	receipt, err := proto.Marshal(&message.Message{
+		From:         p.pubKeyHex,
+		To:           msg.From,
+		Id:           msg.Id,
+		Payload:      []byte(message.ReceiptDropped),
+		UnixDateTime: time.Now().Unix(),
+		Kind:         message.Kind_KIND_RECEIPT,
+		GroupId:      msg.GroupId,
+	})
+	if err != nil {
+		slog.Error("peer: marshal dropped receipt failed", "error", err, "client", p.pubKeyHex)
+		return
+	}
+	pub := p.publisher.PublishAsync(msg.From, receipt)
+	go func() {
+		if err := pub.Wait(); err != nil {
+			metricPublishErrors.Inc()
+			slog.Warn("peer: publish dropped receipt failed", "error", err, "client", p.pubKeyHex, "to", msg.From)
+		}
+	}()
+}
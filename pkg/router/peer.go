@@ -1,17 +1,21 @@
 package router
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"golang.org/x/time/rate"
 
+	"github.com/udisondev/sprut/pkg/admin"
 	"github.com/udisondev/sprut/pkg/broker"
 	"github.com/udisondev/sprut/pkg/protocol"
 )
@@ -27,11 +31,38 @@ type Peer struct {
 
 	publisher  *broker.Publisher
 	subscriber *broker.Subscriber
+	mailbox    *broker.Mailbox
+	groups     *broker.Groups // nil без JetStream
 
-	writeCh   chan []byte
+	policy Policy              // nil = без ограничений
+	lists  *broker.PolicyLists // nil без JetStream
+
+	listener *listener // принявший соединение listener; nil = общие лимиты
+
+	presence      *broker.Presence
+	presenceQuery *broker.Subscriber
+	presenceWatch *broker.Subscriber
+	presenceState presenceState
+	connectedAt   time.Time
+
+	// writeCh — очередь сообщений (TypeServerMessage), к ней применяется overflow;
+	// ctrlCh — очередь служебных кадров (ack, presence, GoAway, pong): они
+	// не отбрасываются, при её переполнении клиент отключается.
+	writeCh   chan outbound
+	ctrlCh    chan outbound
 	closeCh   chan struct{}
 	closeOnce sync.Once
 
+	// inboundOnce останавливает доставку из NATS один раз: при GoAway или Close.
+	inboundOnce sync.Once
+	goAwayOnce  sync.Once
+
+	// stopWriteCh останавливает writeLoop перед отправкой кадра ошибки,
+	// writeDone закрывается при выходе из writeLoop.
+	stopWriteCh   chan struct{}
+	stopWriteOnce sync.Once
+	writeDone     chan struct{}
+
 	writeTimeout time.Duration
 	// lastDeadline используется для batch deadline updates -
 	// обновляем deadline только каждые writeTimeout/2.
@@ -39,6 +70,29 @@ type Peer struct {
 
 	// limiter ограничивает количество сообщений от клиента для защиты от DoS.
 	limiter *rate.Limiter
+	// receipts — доставленные сообщения, квитанции на которые не расходуют limiter.
+	receipts pendingReceipts
+
+	// overflow — overflowPolicy при переполнении writeCh.
+	overflow atomic.Uint32
+
+	// Счётчики для admin API.
+	messagesIn  atomic.Uint64
+	messagesOut atomic.Uint64
+}
+
+// outbound — элемент очереди записи клиенту.
+type outbound struct {
+	// frameType — тип кадра: protocol.TypeServerMessage, TypeServerAck, TypeServerPresence,
+	// TypeServerGoAway или TypeServerPong.
+	frameType byte
+	data      []byte
+	ack       protocol.ServerAck
+	presence  protocol.ServerPresence
+	goAway    protocol.ServerGoAway
+	pong      protocol.ServerPong
+	// jsMsg подтверждается после успешной записи клиенту (только в режиме JetStream).
+	jsMsg jetstream.Msg
 }
 
 // newPeer создаёт нового пира.
@@ -50,6 +104,7 @@ func newPeer(
 	writeTimeout time.Duration,
 	rateLimitPerSec float64,
 	rateLimitBurst int,
+	overflowPolicy string,
 ) (*Peer, error) {
 	pubKeyHex := hex.EncodeToString(id[:])
 
@@ -58,10 +113,38 @@ func newPeer(
 		conn:         conn,
 		pubKeyHex:    pubKeyHex,
 		publisher:    broker.NewPublisher(brk),
-		writeCh:      make(chan []byte, writeBufferSize),
+		writeCh:      make(chan outbound, writeBufferSize),
+		ctrlCh:       make(chan outbound, writeBufferSize),
 		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
 		writeTimeout: writeTimeout,
 		limiter:      rate.NewLimiter(rate.Limit(rateLimitPerSec), rateLimitBurst),
+		connectedAt:  time.Now(),
+	}
+	peer.SetOverflowPolicy(overflowPolicy)
+
+	// В режиме JetStream получаем backlog и новые сообщения через durable consumer.
+	// Ack, presence и pong идут через ctrlCh и места в writeCh не занимают, поэтому
+	// backlog JetStream не вытесняет их и не отключает клиента как slow consumer.
+	if brk.JetStreamEnabled() {
+		maxAckPending := mailboxAckPending(writeBufferSize, peer.overflowPolicy())
+		mailbox, err := broker.NewMailbox(brk, pubKeyHex, maxAckPending, peer.handleMailboxMessage)
+		if err != nil {
+			return nil, fmt.Errorf("create mailbox: %w", err)
+		}
+		peer.mailbox = mailbox
+
+		groups, err := broker.NewGroups(brk)
+		if err != nil {
+			mailbox.Stop()
+			return nil, fmt.Errorf("create groups: %w", err)
+		}
+		peer.groups = groups
+
+		slog.Debug("peer: JetStream mailbox created", "client", pubKeyHex)
+
+		return peer, nil
 	}
 
 	// Подписываемся на топик "goro.msg.{pubKeyHex}" для получения входящих сообщений
@@ -81,48 +164,189 @@ func (p *Peer) PubKeyHex() string {
 	return p.pubKeyHex
 }
 
+// Info возвращает сведения о клиенте для admin API.
+func (p *Peer) Info() admin.Peer {
+	return admin.Peer{
+		Key:         p.pubKeyHex,
+		RemoteAddr:  p.conn.RemoteAddr().String(),
+		ConnectedAt: p.connectedAt,
+		QueueDepth:  len(p.writeCh) + len(p.ctrlCh),
+		MessagesIn:  p.messagesIn.Load(),
+		MessagesOut: p.messagesOut.Load(),
+	}
+}
+
 // AllowMessage проверяет, разрешено ли клиенту отправить сообщение (rate limiting).
 // Возвращает true если разрешено, false если лимит превышен.
 func (p *Peer) AllowMessage() bool {
 	return p.limiter.Allow()
 }
 
+// WaitMessage ждёт, пока лимит частоты разрешит клиенту следующее сообщение.
+// Возвращает ошибку, если соединение закрыто раньше.
+func (p *Peer) WaitMessage() error {
+	if p.limiter.Allow() {
+		return nil
+	}
+
+	metricInboundThrottled.Inc()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return p.limiter.Wait(ctx)
+}
+
+// SetRateLimit меняет лимит частоты сообщений клиента.
+func (p *Peer) SetRateLimit(perSec float64, burst int) {
+	p.limiter.SetLimit(rate.Limit(perSec))
+	p.limiter.SetBurst(burst)
+}
+
 // Close закрывает соединение с пиром.
 func (p *Peer) Close() {
 	p.closeOnce.Do(func() {
 		slog.Debug("peer: closing", "client", p.pubKeyHex)
 		close(p.closeCh)
+		p.stopInbound()
+		if err := p.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			slog.Error("peer: close connection failed", "error", err, "client", p.pubKeyHex)
+		}
+	})
+}
+
+// stopInbound прекращает доставку клиенту новых сообщений из NATS.
+// Сообщения, уже стоящие в очереди записи, будут отправлены.
+func (p *Peer) stopInbound() {
+	p.inboundOnce.Do(func() {
 		if p.subscriber != nil {
 			if err := p.subscriber.Unsubscribe(); err != nil {
 				slog.Error("peer: unsubscribe failed", "error", err, "client", p.pubKeyHex)
 			}
 		}
-		if err := p.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
-			slog.Error("peer: close connection failed", "error", err, "client", p.pubKeyHex)
+		if p.mailbox != nil {
+			p.mailbox.Stop()
 		}
 	})
 }
 
-// writeLoop обрабатывает исходящие сообщения.
-func (p *Peer) writeLoop() {
-	for {
+// GoAway просит клиента переподключиться, при непустом addr — к другому серверу.
+// Новые сообщения из NATS клиенту больше не доставляются, кадр GoAway
+// отправляется после уже поставленных в очередь. Соединение закрывает клиент.
+func (p *Peer) GoAway(addr string) {
+	p.goAwayOnce.Do(func() {
+		p.stopInbound()
+		p.enqueue(outbound{frameType: protocol.TypeServerGoAway, goAway: protocol.ServerGoAway{Addr: addr}})
+		slog.Debug("peer: go away queued", "client", p.pubKeyHex, "addr", addr)
+	})
+}
+
+// CloseWithError отправляет клиенту кадр ServerError и закрывает соединение.
+// Ожидает завершения текущей записи не дольше ErrorWriteTimeout:
+// если writeLoop завис на медленном клиенте, кадр ошибки не отправляется.
+func (p *Peer) CloseWithError(code byte, message string) {
+	p.stopWriteOnce.Do(func() {
+		metricDisconnects.WithLabelValues(disconnectReason(code)).Inc()
+		close(p.stopWriteCh)
+
+		timer := time.NewTimer(ErrorWriteTimeout)
+		defer timer.Stop()
+
 		select {
 		case <-p.closeCh:
 			return
-		case data := <-p.writeCh:
-			if err := p.writeMessage(data); err != nil {
-				slog.Error("peer: write message failed", "error", err, "client", p.pubKeyHex)
-				p.Close()
+		case <-timer.C:
+			slog.Warn("peer: write loop busy, error frame skipped", "client", p.pubKeyHex, "code", code)
+			return
+		case <-p.writeDone:
+		}
+
+		// writeLoop завершён — пишем напрямую
+		if err := p.conn.SetWriteDeadline(time.Now().Add(ErrorWriteTimeout)); err != nil {
+			slog.Debug("peer: set error deadline failed", "error", err, "client", p.pubKeyHex)
+			return
+		}
+		serverErr := &protocol.ServerError{Code: code, Message: message}
+		if err := serverErr.Encode(p.conn); err != nil {
+			slog.Debug("peer: write error frame failed", "error", err, "client", p.pubKeyHex)
+			return
+		}
+		slog.Debug("peer: error frame sent", "client", p.pubKeyHex, "code", code)
+	})
+	p.Close()
+}
+
+// writeLoop обрабатывает исходящие сообщения. Служебные кадры отправляются
+// раньше стоящих в очереди сообщений: ack и pong не ждут, пока клиент
+// дочитает backlog.
+func (p *Peer) writeLoop() {
+	defer close(p.writeDone)
+	for {
+		var out outbound
+		select {
+		case out = <-p.ctrlCh:
+		default:
+			select {
+			case <-p.closeCh:
+				return
+			case <-p.stopWriteCh:
 				return
+			case out = <-p.ctrlCh:
+			case out = <-p.writeCh:
+			}
+		}
+
+		// GoAway — последний кадр: до него клиент получает уже поставленные сообщения
+		if out.frameType == protocol.TypeServerGoAway && !p.flushMessages() {
+			return
+		}
+		if !p.write(&out) {
+			return
+		}
+	}
+}
+
+// flushMessages отправляет сообщения, стоящие в очереди. Возвращает false,
+// если запись не удалась и соединение закрыто.
+func (p *Peer) flushMessages() bool {
+	for {
+		select {
+		case out := <-p.writeCh:
+			if !p.write(&out) {
+				return false
 			}
-			slog.Debug("peer: message sent", "client", p.pubKeyHex, "size", len(data))
+		default:
+			return true
 		}
 	}
 }
 
-// writeMessage отправляет сообщение клиенту.
+// write отправляет кадр и подтверждает сообщение JetStream. Возвращает false,
+// если запись не удалась и соединение закрыто.
+func (p *Peer) write(out *outbound) bool {
+	if err := p.writeMessage(out); err != nil {
+		slog.Error("peer: write message failed", "error", err, "client", p.pubKeyHex)
+		p.Close()
+		return false
+	}
+	slog.Debug("peer: frame sent", "client", p.pubKeyHex, "type", out.frameType, "size", len(out.data))
+
+	if out.jsMsg != nil {
+		if err := out.jsMsg.Ack(); err != nil {
+			slog.Warn("peer: JetStream ack failed", "error", err, "client", p.pubKeyHex)
+		}
+	}
+	return true
+}
+
+// writeMessage отправляет кадр клиенту.
 // Вызывается только из writeLoop, поэтому mutex не нужен.
-func (p *Peer) writeMessage(data []byte) error {
+func (p *Peer) writeMessage(out *outbound) error {
 	now := time.Now()
 	// Batch deadline updates: обновляем только каждые writeTimeout/2
 	// Это снижает количество syscall с 2 на сообщение до ~0.07 на сообщение
@@ -133,22 +357,68 @@ func (p *Peer) writeMessage(data []byte) error {
 		p.lastDeadline = now
 	}
 
-	// ServerMessage: Len(4) + Data
-	serverMsg := &protocol.ServerMessage{Data: data}
-	if err := serverMsg.Encode(p.conn); err != nil {
-		return fmt.Errorf("encode server message: %w", err)
+	switch out.frameType {
+	case protocol.TypeServerAck:
+		if err := out.ack.Encode(p.conn); err != nil {
+			return fmt.Errorf("encode server ack: %w", err)
+		}
+	case protocol.TypeServerPresence:
+		if err := out.presence.Encode(p.conn); err != nil {
+			return fmt.Errorf("encode server presence: %w", err)
+		}
+	case protocol.TypeServerGoAway:
+		if err := out.goAway.Encode(p.conn); err != nil {
+			return fmt.Errorf("encode server go away: %w", err)
+		}
+	case protocol.TypeServerPong:
+		if err := out.pong.Encode(p.conn); err != nil {
+			return fmt.Errorf("encode server pong: %w", err)
+		}
+	default:
+		// ServerMessage: Type(1) + Len(4) + Data
+		serverMsg := &protocol.ServerMessage{Data: out.data}
+		if err := serverMsg.Encode(p.conn); err != nil {
+			return fmt.Errorf("encode server message: %w", err)
+		}
+		metricMessagesOut.Inc()
+		p.messagesOut.Add(1)
+		p.receipts.delivered(out.data)
 	}
 
 	return nil
 }
 
+// sendAck ставит в очередь подтверждение сообщения с указанным msgID.
+func (p *Peer) sendAck(status byte, msgID, reason string) {
+	p.enqueue(outbound{
+		frameType: protocol.TypeServerAck,
+		ack:       protocol.ServerAck{Status: status, MsgID: msgID, Reason: reason},
+	})
+}
+
 // handleNATSMessage обрабатывает входящие сообщения из NATS.
 func (p *Peer) handleNATSMessage(msg *nats.Msg) {
+	p.enqueue(outbound{frameType: protocol.TypeServerMessage, data: msg.Data})
+}
+
+// handleMailboxMessage обрабатывает сообщения из JetStream.
+// Ack отправляется из writeLoop после записи клиенту.
+func (p *Peer) handleMailboxMessage(msg jetstream.Msg) {
+	p.enqueue(outbound{frameType: protocol.TypeServerMessage, data: msg.Data(), jsMsg: msg})
+}
+
+// enqueue ставит кадр в очередь записи: сообщение — в writeCh, служебный кадр — в ctrlCh.
+func (p *Peer) enqueue(out outbound) {
+	queue := p.writeCh
+	if out.frameType != protocol.TypeServerMessage {
+		queue = p.ctrlCh
+	}
+
 	select {
 	case <-p.closeCh:
 		return
-	case p.writeCh <- msg.Data:
-		slog.Debug("peer: message queued", "client", p.pubKeyHex, "queue_size", len(p.writeCh))
+	case queue <- out:
+		slog.Debug("peer: frame queued", "client", p.pubKeyHex, "type", out.frameType, "queue_size", len(queue))
 	default:
 		// Буфер переполнен - клиент не успевает обрабатывать (slow consumer)
 		// Проверяем ещё раз closeCh для предотвращения race condition
@@ -156,8 +426,12 @@ func (p *Peer) handleNATSMessage(msg *nats.Msg) {
 		case <-p.closeCh:
 			return
 		default:
-			slog.Warn("peer: write buffer full, disconnecting slow client", "client", p.pubKeyHex)
-			p.Close()
 		}
+		if out.frameType == protocol.TypeServerMessage {
+			p.handleOverflow(out)
+			return
+		}
+		// Служебный кадр нельзя потерять: без ack клиент не узнает результат отправки
+		p.disconnectSlow()
 	}
 }
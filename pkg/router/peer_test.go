@@ -0,0 +1,418 @@
+package router
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"golang.org/x/time/rate"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func TestPeerGoAwayAfterQueued(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:         serverConn,
+		writeCh:      make(chan outbound, 10),
+		ctrlCh:       make(chan outbound, 10),
+		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
+		writeTimeout: time.Second,
+	}
+	defer p.Close()
+
+	p.enqueue(outbound{frameType: protocol.TypeServerMessage, data: []byte("queued")})
+	p.GoAway("sprut-2:8443")
+	p.GoAway("ignored") // повторный вызов не ставит второй кадр
+	go p.writeLoop()
+
+	r := bufio.NewReader(clientConn)
+
+	frameType, err := protocol.ReadMessageType(r)
+	if err != nil {
+		t.Fatalf("read type: %v", err)
+	}
+	if frameType != protocol.TypeServerMessage {
+		t.Fatalf("first frame type = %d, want %d", frameType, protocol.TypeServerMessage)
+	}
+	msg, err := protocol.DecodeServerMessage(r)
+	if err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	if string(msg.Data) != "queued" {
+		t.Errorf("message data = %q, want %q", msg.Data, "queued")
+	}
+
+	frameType, err = protocol.ReadMessageType(r)
+	if err != nil {
+		t.Fatalf("read type: %v", err)
+	}
+	if frameType != protocol.TypeServerGoAway {
+		t.Fatalf("second frame type = %d, want %d", frameType, protocol.TypeServerGoAway)
+	}
+	goAway, err := protocol.DecodeServerGoAway(r)
+	if err != nil {
+		t.Fatalf("decode go away: %v", err)
+	}
+	if goAway.Addr != "sprut-2:8443" {
+		t.Errorf("go away addr = %q, want %q", goAway.Addr, "sprut-2:8443")
+	}
+	if n := len(p.writeCh); n != 0 {
+		t.Errorf("write queue length = %d, want 0", n)
+	}
+}
+
+func TestPeerPingPong(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:         serverConn,
+		writeCh:      make(chan outbound, 10),
+		ctrlCh:       make(chan outbound, 10),
+		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
+		writeTimeout: time.Second,
+	}
+	defer p.Close()
+	go p.writeLoop()
+
+	pool := &sync.Pool{New: func() any {
+		buf := make([]byte, 1024)
+		return &buf
+	}}
+	go func() {
+		_ = (&protocol.ClientPing{Seq: 7}).Encode(clientConn)
+	}()
+	// Ping не расходует лимит частоты
+	admit := func() error {
+		return violation(protocol.ErrorCodeRateLimited, "rate limit exceeded")
+	}
+	if err := handleMessage(p, pool, 1024, admit); err != nil {
+		t.Fatalf("handle ping: %v", err)
+	}
+
+	r := bufio.NewReader(clientConn)
+	frameType, err := protocol.ReadMessageType(r)
+	if err != nil {
+		t.Fatalf("read type: %v", err)
+	}
+	if frameType != protocol.TypeServerPong {
+		t.Fatalf("frame type = %d, want %d", frameType, protocol.TypeServerPong)
+	}
+	pong, err := protocol.DecodeServerPong(r)
+	if err != nil {
+		t.Fatalf("decode pong: %v", err)
+	}
+	if pong.Seq != 7 {
+		t.Errorf("pong seq = %d, want 7", pong.Seq)
+	}
+}
+
+// fakeJSMsg — сообщение JetStream, запоминающее, как его завершили.
+type fakeJSMsg struct {
+	jetstream.Msg
+	data     []byte
+	termed   bool
+	nakDelay time.Duration
+}
+
+func (m *fakeJSMsg) Data() []byte {
+	return m.data
+}
+
+func (m *fakeJSMsg) Term() error {
+	m.termed = true
+	return nil
+}
+
+func (m *fakeJSMsg) NakWithDelay(d time.Duration) error {
+	m.nakDelay = d
+	return nil
+}
+
+func TestPeerOverflowPolicy(t *testing.T) {
+	newTestPeer := func(policy string) *Peer {
+		clientConn, serverConn := net.Pipe()
+		t.Cleanup(func() { clientConn.Close() })
+
+		p := &Peer{
+			conn:         serverConn,
+			writeCh:      make(chan outbound, 2),
+			ctrlCh:       make(chan outbound, 2),
+			closeCh:      make(chan struct{}),
+			stopWriteCh:  make(chan struct{}),
+			writeDone:    make(chan struct{}),
+			writeTimeout: time.Second,
+		}
+		p.SetOverflowPolicy(policy)
+		t.Cleanup(p.Close)
+		return p
+	}
+	queued := func(p *Peer) []string {
+		var data []string
+		for len(p.writeCh) > 0 {
+			data = append(data, string((<-p.writeCh).data))
+		}
+		return data
+	}
+	msg := func(data string, js *fakeJSMsg) outbound {
+		out := outbound{frameType: protocol.TypeServerMessage, data: []byte(data)}
+		if js != nil {
+			out.jsMsg = js
+		}
+		return out
+	}
+
+	t.Run("drop_newest", func(t *testing.T) {
+		p := newTestPeer(config.OverflowDropNewest)
+		js := &fakeJSMsg{}
+		p.enqueue(msg("1", nil))
+		p.enqueue(msg("2", nil))
+		p.enqueue(msg("3", js))
+
+		if got := queued(p); !slices.Equal(got, []string{"1", "2"}) {
+			t.Errorf("queue = %v, want [1 2]", got)
+		}
+		if !js.termed {
+			t.Error("dropped JetStream message not terminated")
+		}
+	})
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		p := newTestPeer(config.OverflowDropOldest)
+		js := &fakeJSMsg{}
+		p.enqueue(msg("1", js))
+		p.enqueue(msg("2", nil))
+		p.enqueue(msg("3", nil))
+
+		if got := queued(p); !slices.Equal(got, []string{"2", "3"}) {
+			t.Errorf("queue = %v, want [2 3]", got)
+		}
+		if !js.termed {
+			t.Error("evicted JetStream message not terminated")
+		}
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		p := newTestPeer(config.OverflowSpill)
+		js := &fakeJSMsg{}
+		p.enqueue(msg("1", nil))
+		p.enqueue(msg("2", nil))
+		p.enqueue(msg("3", js))
+
+		if got := queued(p); !slices.Equal(got, []string{"1", "2"}) {
+			t.Errorf("queue = %v, want [1 2]", got)
+		}
+		if js.termed || js.nakDelay != spillRedeliveryDelay {
+			t.Errorf("spilled message: termed = %v, nak delay = %v, want redelivery after %v", js.termed, js.nakDelay, spillRedeliveryDelay)
+		}
+	})
+
+	t.Run("spill backlog", func(t *testing.T) {
+		p := newTestPeer(config.OverflowSpill)
+
+		// JetStream выдаёт до MaxAckPending сообщений, не дожидаясь записи клиенту
+		backlog := make([]*fakeJSMsg, mailboxAckPending(cap(p.writeCh), p.overflowPolicy()))
+		if len(backlog) <= cap(p.writeCh) {
+			t.Fatalf("MaxAckPending %d does not exceed write queue %d", len(backlog), cap(p.writeCh))
+		}
+		if n := mailboxAckPending(cap(p.writeCh), overflowDisconnect); n != cap(p.writeCh) {
+			t.Errorf("disconnect MaxAckPending = %d, want %d", n, cap(p.writeCh))
+		}
+		for i := range backlog {
+			backlog[i] = &fakeJSMsg{data: []byte{byte('1' + i)}}
+			p.handleMailboxMessage(backlog[i])
+		}
+
+		if got := queued(p); !slices.Equal(got, []string{"1", "2"}) {
+			t.Errorf("queue = %v, want [1 2]", got)
+		}
+		for i, js := range backlog {
+			spilled := i >= cap(p.writeCh)
+			if js.termed || spilled != (js.nakDelay == spillRedeliveryDelay) {
+				t.Errorf("message %d: termed = %v, nak delay = %v, spilled = %v", i+1, js.termed, js.nakDelay, spilled)
+			}
+		}
+	})
+
+	t.Run("control frames", func(t *testing.T) {
+		for _, policy := range []string{config.OverflowDropOldest, config.OverflowDropNewest, config.OverflowSpill} {
+			p := newTestPeer(policy)
+			p.GoAway("")
+			p.enqueue(msg("1", nil))
+			p.enqueue(msg("2", nil))
+			p.enqueue(msg("3", &fakeJSMsg{}))
+			p.sendAck(protocol.AckStatusAccepted, "m1", "")
+
+			// Переполнение очереди сообщений не трогает служебные кадры
+			if n := len(p.ctrlCh); n != 2 {
+				t.Fatalf("%s: control queue length = %d, want 2", policy, n)
+			}
+			if out := <-p.ctrlCh; out.frameType != protocol.TypeServerGoAway {
+				t.Errorf("%s: first control frame type = %d, want GoAway", policy, out.frameType)
+			}
+			if out := <-p.ctrlCh; out.frameType != protocol.TypeServerAck || out.ack.MsgID != "m1" {
+				t.Errorf("%s: second control frame = %+v, want ack m1", policy, out)
+			}
+
+			// Служебный кадр, которому нет места, не отбрасывается молча
+			for range 3 {
+				p.sendAck(protocol.AckStatusAccepted, "m2", "")
+			}
+			select {
+			case <-p.closeCh:
+			case <-time.After(2 * ErrorWriteTimeout):
+				t.Fatalf("%s: client not disconnected on control queue overflow", policy)
+			}
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		p := newTestPeer(config.OverflowDisconnect)
+		p.enqueue(msg("1", nil))
+		p.enqueue(msg("2", nil))
+		p.enqueue(msg("3", nil))
+
+		select {
+		case <-p.closeCh:
+		case <-time.After(2 * ErrorWriteTimeout):
+			t.Fatal("slow consumer not disconnected")
+		}
+	})
+}
+
+func TestPeerControlFramesWithFullMailbox(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:         serverConn,
+		writeCh:      make(chan outbound, 2),
+		ctrlCh:       make(chan outbound, 2),
+		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
+		writeTimeout: time.Second,
+	}
+	p.SetOverflowPolicy(config.OverflowDisconnect)
+	defer p.Close()
+
+	// JetStream выдал MaxAckPending сообщений — очередь сообщений заполнена
+	for range 2 {
+		p.enqueue(outbound{frameType: protocol.TypeServerMessage, data: []byte("backlog"), jsMsg: &fakeJSMsg{}})
+	}
+	p.sendAck(protocol.AckStatusAccepted, "m1", "")
+	p.enqueue(outbound{frameType: protocol.TypeServerPong, pong: protocol.ServerPong{Seq: 1}})
+
+	select {
+	case <-p.closeCh:
+		t.Fatal("client disconnected while JetStream backlog fills the message queue")
+	case <-time.After(2 * ErrorWriteTimeout):
+	}
+	if n := len(p.ctrlCh); n != 2 {
+		t.Errorf("control queue length = %d, want 2", n)
+	}
+}
+
+func TestPeerAcksWithFullMessageQueue(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:         serverConn,
+		writeCh:      make(chan outbound, 2),
+		ctrlCh:       make(chan outbound, 4),
+		closeCh:      make(chan struct{}),
+		stopWriteCh:  make(chan struct{}),
+		writeDone:    make(chan struct{}),
+		writeTimeout: time.Second,
+	}
+	p.SetOverflowPolicy(config.OverflowDisconnect)
+	defer p.Close()
+
+	// Очередь сообщений заполнена: ack не должны ни отключать клиента,
+	// ни ждать, пока он дочитает сообщения
+	for range 2 {
+		p.enqueue(outbound{frameType: protocol.TypeServerMessage, data: []byte("backlog")})
+	}
+	msgIDs := []string{"m1", "m2", "m3"}
+	for _, id := range msgIDs {
+		p.sendAck(protocol.AckStatusAccepted, id, "")
+	}
+	go p.writeLoop()
+
+	r := bufio.NewReader(clientConn)
+	for _, id := range msgIDs {
+		frameType, err := protocol.ReadMessageType(r)
+		if err != nil {
+			t.Fatalf("read type: %v", err)
+		}
+		if frameType != protocol.TypeServerAck {
+			t.Fatalf("frame type = %d, want ack %d", frameType, protocol.TypeServerAck)
+		}
+		ack, err := protocol.DecodeServerAck(r)
+		if err != nil {
+			t.Fatalf("decode ack: %v", err)
+		}
+		if ack.MsgID != id || ack.Status != protocol.AckStatusAccepted {
+			t.Errorf("ack = %s/%d, want %s/%d", ack.MsgID, ack.Status, id, protocol.AckStatusAccepted)
+		}
+	}
+	for range 2 {
+		frameType, err := protocol.ReadMessageType(r)
+		if err != nil {
+			t.Fatalf("read type: %v", err)
+		}
+		if frameType != protocol.TypeServerMessage {
+			t.Fatalf("frame type = %d, want message %d", frameType, protocol.TypeServerMessage)
+		}
+		if _, err := protocol.DecodeServerMessage(r); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+	}
+}
+
+func TestPeerWaitMessage(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	p := &Peer{
+		conn:    serverConn,
+		closeCh: make(chan struct{}),
+		limiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+	}
+
+	if err := p.WaitMessage(); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.WaitMessage() }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("second message not throttled: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	p.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("WaitMessage after close: want error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitMessage not interrupted by Close")
+	}
+}
@@ -0,0 +1,238 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// policyListTimeout — таймаут сохранения блок-листа или списка контактов.
+const policyListTimeout = 5 * time.Second
+
+// anyKey в allowlist означает любой ключ.
+const anyKey = "*"
+
+// Decision — решение политики авторизации.
+type Decision struct {
+	Allowed bool
+	// Reason — причина отказа, сообщается отправителю в ServerAck.
+	Reason string
+}
+
+// Allow разрешает доставку.
+func Allow() Decision {
+	return Decision{Allowed: true}
+}
+
+// Deny запрещает доставку с причиной reason.
+func Deny(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// Policy решает, может ли отправитель from писать получателю to.
+// Вызывается из read loop на каждое сообщение и квитанцию о доставке,
+// поэтому не должна обращаться к сети.
+type Policy interface {
+	Authorize(from, to string) Decision
+}
+
+// PolicyChain разрешает доставку, только если её разрешают все политики.
+// Возвращает первый отказ.
+type PolicyChain []Policy
+
+// Authorize реализует Policy.
+func (c PolicyChain) Authorize(from, to string) Decision {
+	for _, p := range c {
+		if d := p.Authorize(from, to); !d.Allowed {
+			return d
+		}
+	}
+	return Allow()
+}
+
+// AllowlistPolicy разрешает только пары отправитель-получатель из файла.
+// Формат: по паре "<from> <to>" на строку, * — любой ключ,
+// пустые строки и строки с # игнорируются.
+type AllowlistPolicy struct {
+	pairs map[string]map[string]struct{}
+}
+
+// LoadAllowlistPolicy загружает allowlist из файла.
+func LoadAllowlistPolicy(path string) (*AllowlistPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open allowlist: %w", err)
+	}
+	defer f.Close()
+
+	p := &AllowlistPolicy{pairs: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("allowlist %s:%d: expected \"<from> <to>\"", path, line)
+		}
+		for _, key := range fields {
+			if key != anyKey && !isValidHexPubKey(key) {
+				return nil, fmt.Errorf("allowlist %s:%d: invalid key %q", path, line, key)
+			}
+		}
+		p.allow(strings.ToLower(fields[0]), strings.ToLower(fields[1]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read allowlist: %w", err)
+	}
+
+	slog.Info("policy: allowlist loaded", "file", path, "senders", len(p.pairs))
+
+	return p, nil
+}
+
+func (p *AllowlistPolicy) allow(from, to string) {
+	if p.pairs[from] == nil {
+		p.pairs[from] = make(map[string]struct{})
+	}
+	p.pairs[from][to] = struct{}{}
+}
+
+// Authorize реализует Policy.
+func (p *AllowlistPolicy) Authorize(from, to string) Decision {
+	to = strings.ToLower(to)
+	for _, sender := range [...]string{from, anyKey} {
+		recipients := p.pairs[sender]
+		if _, ok := recipients[to]; ok {
+			return Allow()
+		}
+		if _, ok := recipients[anyKey]; ok {
+			return Allow()
+		}
+	}
+	return Deny("not in allowlist")
+}
+
+// BlocklistPolicy запрещает доставку отправителям из блок-листа получателя.
+type BlocklistPolicy struct {
+	lists *broker.PolicyLists
+}
+
+// NewBlocklistPolicy создаёт BlocklistPolicy.
+func NewBlocklistPolicy(lists *broker.PolicyLists) *BlocklistPolicy {
+	return &BlocklistPolicy{lists: lists}
+}
+
+// Authorize реализует Policy.
+func (p *BlocklistPolicy) Authorize(from, to string) Decision {
+	if p.lists.Contains(to, broker.BlockList, from) {
+		return Deny("blocked by recipient")
+	}
+	return Allow()
+}
+
+// ContactsOnlyPolicy разрешает доставку только от контактов получателя.
+type ContactsOnlyPolicy struct {
+	lists *broker.PolicyLists
+}
+
+// NewContactsOnlyPolicy создаёт ContactsOnlyPolicy.
+func NewContactsOnlyPolicy(lists *broker.PolicyLists) *ContactsOnlyPolicy {
+	return &ContactsOnlyPolicy{lists: lists}
+}
+
+// Authorize реализует Policy.
+func (p *ContactsOnlyPolicy) Authorize(from, to string) Decision {
+	if !p.lists.Contains(to, broker.ContactList, from) {
+		return Deny("sender is not in recipient contacts")
+	}
+	return Allow()
+}
+
+// newPolicy создаёт политику авторизации из конфигурации.
+// Возвращает nil, если проверки не включены.
+func newPolicy(cfg config.PolicyConfig, lists *broker.PolicyLists) (Policy, error) {
+	var chain PolicyChain
+
+	if cfg.AllowlistFile != "" {
+		allowlist, err := LoadAllowlistPolicy(cfg.AllowlistFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, allowlist)
+	}
+
+	if cfg.Blocklists || cfg.ContactsOnly {
+		if lists == nil {
+			return nil, fmt.Errorf("blocklists and contacts_only require JetStream")
+		}
+		if cfg.Blocklists {
+			chain = append(chain, NewBlocklistPolicy(lists))
+		}
+		if cfg.ContactsOnly {
+			chain = append(chain, NewContactsOnlyPolicy(lists))
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// authorize проверяет сообщение политикой авторизации.
+func (p *Peer) authorize(to string) Decision {
+	if p.policy == nil {
+		return Allow()
+	}
+	return p.policy.Authorize(p.pubKeyHex, to)
+}
+
+// handlePolicyList сохраняет блок-лист или список контактов клиента
+// и отвечает ack с MsgID списка: отклонённый список клиент отправляет повторно.
+func (p *Peer) handlePolicyList(frameType byte, body []byte) error {
+	keys, err := protocol.ParseKeyList(frameType, body)
+	if err != nil {
+		return violation(protocol.ErrorCodeProtocol, "policy list: %w", err)
+	}
+	for i, key := range keys {
+		if !isValidHexPubKey(key) {
+			return violation(protocol.ErrorCodeProtocol, "policy list: invalid key at %d", i)
+		}
+	}
+
+	list, ackID := broker.BlockList, protocol.BlockListAckID
+	if frameType == protocol.TypeClientContactList {
+		list, ackID = broker.ContactList, protocol.ContactListAckID
+	}
+
+	if p.lists == nil {
+		slog.Warn("policy: lists require JetStream, ignored", "client", p.pubKeyHex, "type", frameType)
+		p.sendAck(protocol.AckStatusRejected, ackID, "policy lists are not supported")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), policyListTimeout)
+	defer cancel()
+
+	if err := p.lists.Set(ctx, p.pubKeyHex, list, keys); err != nil {
+		slog.Error("policy: save list failed", "error", err, "client", p.pubKeyHex, "list", list)
+		p.sendAck(protocol.AckStatusRejected, ackID, "save failed, retry")
+		return nil
+	}
+
+	slog.Debug("policy: list updated", "client", p.pubKeyHex, "list", list, "keys", len(keys))
+	p.sendAck(protocol.AckStatusAccepted, ackID, "")
+
+	return nil
+}
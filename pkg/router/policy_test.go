@@ -0,0 +1,129 @@
+package router
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+func TestAllowlistPolicy(t *testing.T) {
+	alice := strings.Repeat("a", 64)
+	bob := strings.Repeat("b", 64)
+	carol := strings.Repeat("c", 64)
+	support := strings.Repeat("d", 64)
+
+	path := filepath.Join(t.TempDir(), "allowlist")
+	content := "# пары отправитель-получатель\n" +
+		alice + " " + strings.ToUpper(bob) + "\n" +
+		"\n" +
+		"* " + support + "\n" +
+		support + " *\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write allowlist: %v", err)
+	}
+
+	p, err := LoadAllowlistPolicy(path)
+	if err != nil {
+		t.Fatalf("load allowlist: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		allowed bool
+	}{
+		{"listed pair", alice, bob, true},
+		{"reverse pair", bob, alice, false},
+		{"anyone to support", carol, support, true},
+		{"support to anyone", support, carol, true},
+		{"not listed", alice, carol, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.Authorize(tt.from, tt.to)
+			if d.Allowed != tt.allowed {
+				t.Errorf("allowed: got %v, want %v", d.Allowed, tt.allowed)
+			}
+			if !d.Allowed && d.Reason == "" {
+				t.Error("deny without reason")
+			}
+		})
+	}
+}
+
+func TestLoadAllowlistPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"single field", strings.Repeat("a", 64) + "\n"},
+		{"invalid key", strings.Repeat("a", 64) + " bob\n"},
+		{"wildcard subject", strings.Repeat("a", 64) + " goro.>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "allowlist")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("write allowlist: %v", err)
+			}
+			if _, err := LoadAllowlistPolicy(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+type denyPolicy string
+
+func (p denyPolicy) Authorize(_, _ string) Decision {
+	return Deny(string(p))
+}
+
+func TestPolicyChain(t *testing.T) {
+	if d := (PolicyChain{}).Authorize("a", "b"); !d.Allowed {
+		t.Error("empty chain must allow")
+	}
+
+	chain := PolicyChain{PolicyChain{}, denyPolicy("first"), denyPolicy("second")}
+	d := chain.Authorize("a", "b")
+	if d.Allowed {
+		t.Fatal("chain must deny")
+	}
+	if d.Reason != "first" {
+		t.Errorf("reason: got %q, want %q", d.Reason, "first")
+	}
+}
+
+func TestHandlePolicyListWithoutLists(t *testing.T) {
+	p := &Peer{
+		pubKeyHex: strings.Repeat("ab", protocol.PublicKeySize),
+		ctrlCh:    make(chan outbound, 1),
+		closeCh:   make(chan struct{}),
+	}
+
+	var frame bytes.Buffer
+	list := &protocol.KeyList{Type: protocol.TypeClientContactList, Keys: []string{strings.Repeat("cd", protocol.PublicKeySize)}}
+	if err := list.Encode(&frame); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := p.handlePolicyList(list.Type, frame.Bytes()[protocol.FrameHeaderSize:]); err != nil {
+		t.Fatalf("handlePolicyList: %v", err)
+	}
+
+	// Несохранённый список клиент должен отправить повторно
+	select {
+	case out := <-p.ctrlCh:
+		if out.frameType != protocol.TypeServerAck || out.ack.MsgID != protocol.ContactListAckID || out.ack.Status != protocol.AckStatusRejected {
+			t.Errorf("got %+v, want rejected ack %s", out, protocol.ContactListAckID)
+		}
+	default:
+		t.Fatal("list not acknowledged")
+	}
+}
@@ -0,0 +1,222 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// presenceQueryTimeout — таймаут запроса статуса одного ключа.
+const presenceQueryTimeout = 2 * time.Second
+
+// presenceState — настройки presence клиента.
+// По умолчанию статус клиента не виден никому и клиент ни за кем не наблюдает.
+type presenceState struct {
+	mu       sync.Mutex
+	allowed  map[string]struct{} // кто видит статус клиента
+	watching map[string]struct{} // чьи статусы получает клиент
+}
+
+// replace заменяет множество set ключами keys.
+// Возвращает добавленные и удалённые ключи.
+func (s *presenceState) replace(set *map[string]struct{}, keys []string) (added, removed []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		next[key] = struct{}{}
+		if _, ok := (*set)[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	for key := range *set {
+		if _, ok := next[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	*set = next
+
+	return added, removed
+}
+
+// isAllowed сообщает, виден ли статус клиента наблюдателю watcher.
+func (s *presenceState) isAllowed(watcher string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.allowed[watcher]
+	return ok
+}
+
+// isWatching сообщает, получает ли клиент статус ключа key.
+func (s *presenceState) isWatching(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.watching[key]
+	return ok
+}
+
+// allowedKeys возвращает копию списка разрешённых наблюдателей.
+func (s *presenceState) allowedKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.allowed))
+	for key := range s.allowed {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startPresence публикует подключение клиента, начинает отвечать на запросы
+// его статуса и подписывает клиента на изменения статусов контактов.
+func (p *Peer) startPresence(pres *broker.Presence) error {
+	query, err := pres.Serve(p.pubKeyHex, p.connectedAt, p.presenceState.isAllowed)
+	if err != nil {
+		return err
+	}
+
+	watch, err := pres.Watch(p.pubKeyHex, p.handlePresence)
+	if err != nil {
+		if uerr := query.Unsubscribe(); uerr != nil {
+			slog.Error("presence: unsubscribe failed", "error", uerr, "client", p.pubKeyHex)
+		}
+		return err
+	}
+
+	p.presence = pres
+	p.presenceQuery = query
+	p.presenceWatch = watch
+
+	if err := pres.PublishEvent(p.pubKeyHex, true, p.connectedAt); err != nil {
+		slog.Warn("presence: publish connect event failed", "error", err, "client", p.pubKeyHex)
+	}
+
+	return nil
+}
+
+// stopPresence отписывается от presence и публикует отключение клиента.
+// notify=false, если ключ уже занят новым соединением на этом сервере:
+// наблюдатели не должны увидеть ложный offline.
+func (p *Peer) stopPresence(notify bool) {
+	if p.presence == nil {
+		return
+	}
+
+	for _, sub := range []*broker.Subscriber{p.presenceQuery, p.presenceWatch} {
+		if err := sub.Unsubscribe(); err != nil {
+			slog.Error("presence: unsubscribe failed", "error", err, "client", p.pubKeyHex)
+		}
+	}
+
+	now := time.Now()
+	if err := p.presence.PublishEvent(p.pubKeyHex, false, now); err != nil {
+		slog.Warn("presence: publish disconnect event failed", "error", err, "client", p.pubKeyHex)
+	}
+
+	if !notify {
+		return
+	}
+	watchers := p.presenceState.allowedKeys()
+	if len(watchers) == 0 {
+		return
+	}
+
+	// Клиент мог переподключиться к другому серверу кластера
+	ctx, cancel := context.WithTimeout(context.Background(), presenceQueryTimeout)
+	defer cancel()
+	if current, err := p.presence.Query(ctx, p.pubKeyHex, ""); err == nil && current.Online {
+		slog.Debug("presence: client online elsewhere, offline skipped", "client", p.pubKeyHex, "server_id", current.ServerId)
+		return
+	}
+
+	p.notifyWatchers(watchers, false, now)
+}
+
+// notifyWatchers рассылает статус клиента наблюдателям.
+func (p *Peer) notifyWatchers(watchers []string, online bool, at time.Time) {
+	ev := &message.Presence{Key: p.pubKeyHex, Online: online, UnixDateTime: at.Unix()}
+	for _, watcher := range watchers {
+		if err := p.presence.Notify(watcher, ev); err != nil {
+			slog.Warn("presence: notify failed", "error", err, "client", p.pubKeyHex, "watcher", watcher)
+		}
+	}
+}
+
+// handlePresenceList применяет список ключей из кадра KeyList.
+func (p *Peer) handlePresenceList(frameType byte, body []byte) error {
+	keys, err := protocol.ParseKeyList(frameType, body)
+	if err != nil {
+		return violation(protocol.ErrorCodeProtocol, "presence list: %w", err)
+	}
+	for i, key := range keys {
+		if !isValidHexPubKey(key) {
+			return violation(protocol.ErrorCodeProtocol, "presence list: invalid key at %d", i)
+		}
+		// Subject NATS чувствителен к регистру, ключи сервер публикует в нижнем
+		keys[i] = strings.ToLower(key)
+	}
+
+	if p.presence == nil {
+		return nil
+	}
+
+	switch frameType {
+	case protocol.TypeClientPresenceAllow:
+		added, removed := p.presenceState.replace(&p.presenceState.allowed, keys)
+		slog.Debug("presence: allow list updated", "client", p.pubKeyHex, "added", len(added), "removed", len(removed))
+		p.notifyWatchers(added, true, p.connectedAt)
+		p.notifyWatchers(removed, false, time.Now())
+
+	case protocol.TypeClientPresenceWatch:
+		added, removed := p.presenceState.replace(&p.presenceState.watching, keys)
+		slog.Debug("presence: watch list updated", "client", p.pubKeyHex, "added", len(added), "removed", len(removed))
+		if len(added) > 0 {
+			// Текущие статусы запрашиваем вне read loop
+			go p.queryPresence(added)
+		}
+	}
+
+	return nil
+}
+
+// queryPresence отправляет клиенту текущие статусы ключей.
+func (p *Peer) queryPresence(keys []string) {
+	for _, key := range keys {
+		select {
+		case <-p.closeCh:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), presenceQueryTimeout)
+		ev, err := p.presence.Query(ctx, key, p.pubKeyHex)
+		cancel()
+		if err != nil {
+			slog.Warn("presence: query failed", "error", err, "client", p.pubKeyHex, "key", key)
+			continue
+		}
+		p.handlePresence(ev)
+	}
+}
+
+// handlePresence передаёт клиенту статус ключа, если клиент за ним наблюдает.
+func (p *Peer) handlePresence(ev *message.Presence) {
+	if !p.presenceState.isWatching(ev.Key) {
+		return
+	}
+
+	status := protocol.PresenceOffline
+	if ev.Online {
+		status = protocol.PresenceOnline
+	}
+	p.enqueue(outbound{
+		frameType: protocol.TypeServerPresence,
+		presence:  protocol.ServerPresence{Key: ev.Key, Status: status, UnixDateTime: ev.UnixDateTime},
+	})
+}
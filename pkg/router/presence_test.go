@@ -0,0 +1,34 @@
+package router
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPresenceStateReplace(t *testing.T) {
+	var s presenceState
+
+	added, removed := s.replace(&s.allowed, []string{"a", "b"})
+	slices.Sort(added)
+	if !slices.Equal(added, []string{"a", "b"}) || len(removed) != 0 {
+		t.Fatalf("first replace: added %v, removed %v", added, removed)
+	}
+
+	added, removed = s.replace(&s.allowed, []string{"b", "c"})
+	if !slices.Equal(added, []string{"c"}) || !slices.Equal(removed, []string{"a"}) {
+		t.Fatalf("second replace: added %v, removed %v", added, removed)
+	}
+
+	if s.isAllowed("a") || !s.isAllowed("b") || !s.isAllowed("c") {
+		t.Errorf("allowed: got %v", s.allowedKeys())
+	}
+	if s.isWatching("b") {
+		t.Error("watch list must be independent of allow list")
+	}
+
+	_, removed = s.replace(&s.allowed, nil)
+	slices.Sort(removed)
+	if !slices.Equal(removed, []string{"b", "c"}) || len(s.allowedKeys()) != 0 {
+		t.Errorf("clear: removed %v, left %v", removed, s.allowedKeys())
+	}
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/transport"
+)
+
+// quicHandoverGrace — сколько после передачи UDP сокета новому процессу
+// QUIC клиенты получают GoAway и оставшиеся сообщения, прежде чем их
+// соединения будут закрыты.
+const quicHandoverGrace = time.Second
+
+// serveQUIC запускает QUIC listener (config.QUICConfig). Соединения проходят
+// те же лимиты и обрабатываются тем же handleConn, что и соединения TCP listener.
+// Listener закрывается с отменой ctx; установленные соединения завершаются
+// в drain вместе с остальными. Возвращённая функция закрывает UDP сокет
+// и вызывается после drain.
+//
+// UDP сокет после передачи новому процессу (listenfd.Set.HandedOver) читают
+// оба процесса, и пакеты соединений делились бы между ними. Поэтому QUIC
+// клиенты не ждут drain: сразу получают GoAway, через quicHandoverGrace
+// соединения закрываются (CONNECTION_CLOSE) вместе с сокетом, и клиенты
+// переподключаются к новому процессу.
+func (s *server) serveQUIC(ctx context.Context, cfg config.QUICConfig, tlsConfig *tls.Config) (func(), error) {
+	pc, err := s.sockets.ListenPacket("quic", "udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	// Клиент, не начавший рукопожатие за auth_timeout, отключается, как и по TCP
+	lis, err := transport.ListenQUIC(pc, tlsConfig, s.limits.Load().AuthTimeout)
+	if err != nil {
+		_ = pc.Close()
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	closeSocket := sync.OnceFunc(func() {
+		if err := pc.Close(); err != nil {
+			slog.Error("quic: close socket failed", "error", err)
+		}
+	})
+
+	go func() {
+		handedOver := false
+		select {
+		case <-ctx.Done():
+		case <-s.sockets.HandedOver():
+			handedOver = true
+		}
+		if err := lis.Close(); err != nil {
+			slog.Error("quic: close failed", "error", err)
+		}
+		if handedOver {
+			s.handOverQUIC(closeSocket)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					slog.Error("quic: accept failed", "error", err)
+				}
+				return
+			}
+			s.serveConn(conn, quicListener)
+		}
+	}()
+
+	slog.Info("quic: started", "addr", lis.Addr().String())
+	return closeSocket, nil
+}
+
+// handOverQUIC отправляет QUIC клиентам GoAway и через quicHandoverGrace
+// закрывает их соединения и UDP сокет, оставляя его новому процессу.
+func (s *server) handOverQUIC(closeSocket func()) {
+	var peers []*Peer
+	s.peers.Range(func(_, v any) bool {
+		if p := v.(*Peer); p.listener == quicListener {
+			peers = append(peers, p)
+		}
+		return true
+	})
+	for _, p := range peers {
+		p.GoAway(s.cfg.Server.DrainAddr)
+	}
+	slog.Info("quic: socket handed over, closing connections", "peers", len(peers))
+
+	time.Sleep(quicHandoverGrace)
+	for _, p := range peers {
+		p.Close()
+	}
+	// Закрытие сокета завершает и соединения, не дошедшие до регистрации пира
+	closeSocket()
+}
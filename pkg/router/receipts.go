@@ -0,0 +1,116 @@
+package router
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/udisondev/sprut/pkg/message"
+)
+
+// maxPendingReceipts — сколько доставленных клиенту сообщений помнит пир
+// в ожидании квитанций; при переполнении забываются самые старые.
+const maxPendingReceipts = 1024
+
+// Номера полей message.Message, нужные для сопоставления квитанций.
+const (
+	fieldMessageFrom protowire.Number = 1
+	fieldMessageID   protowire.Number = 3
+	fieldMessageKind protowire.Number = 6
+)
+
+// pendingReceipts — сообщения, доставленные клиенту и ещё не подтверждённые
+// квитанцией. Квитанция на такое сообщение — ответ на доставку, а не кадр,
+// отправленный по инициативе клиента: она не расходует лимит частоты.
+// Нулевое значение готово к использованию.
+type pendingReceipts struct {
+	mu    sync.Mutex
+	keys  map[string]struct{}
+	order []string // кольцевой буфер ключей в порядке доставки
+	next  int
+}
+
+// delivered запоминает сообщение data (message.Message), записанное клиенту,
+// если клиент ответит на него квитанцией.
+func (r *pendingReceipts) delivered(data []byte) {
+	from, id, ok := receiptFor(data)
+	if !ok {
+		return
+	}
+	key := receiptKey(from, id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keys == nil {
+		r.keys = make(map[string]struct{}, maxPendingReceipts)
+		r.order = make([]string, maxPendingReceipts)
+	}
+	if old := r.order[r.next]; old != "" {
+		delete(r.keys, old)
+	}
+	r.order[r.next] = key
+	r.next = (r.next + 1) % maxPendingReceipts
+	r.keys[key] = struct{}{}
+}
+
+// take сообщает, доставлялось ли клиенту сообщение id от to, и забывает его:
+// повторная квитанция на то же сообщение уже не ответ на доставку.
+func (r *pendingReceipts) take(to, id string) bool {
+	key := receiptKey(to, id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key]; !ok {
+		return false
+	}
+	delete(r.keys, key)
+	return true
+}
+
+func receiptKey(from, id string) string {
+	return from + "/" + id
+}
+
+// receiptFor возвращает отправителя и id сообщения data, на которое клиент
+// отвечает квитанцией. Квитанции и изменения состава групп не подтверждаются.
+// Разбирает только нужные поля, не создавая message.Message.
+func receiptFor(data []byte) (from, id string, ok bool) {
+	kind := message.Kind_KIND_MESSAGE
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", "", false
+		}
+		data = data[n:]
+
+		switch {
+		case num == fieldMessageFrom && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return "", "", false
+			}
+			from, data = string(v), data[n:]
+		case num == fieldMessageID && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return "", "", false
+			}
+			id, data = string(v), data[n:]
+		case num == fieldMessageKind && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return "", "", false
+			}
+			kind, data = message.Kind(v), data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return "", "", false
+			}
+			data = data[n:]
+		}
+	}
+	return from, id, kind == message.Kind_KIND_MESSAGE && from != ""
+}
@@ -0,0 +1,256 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/udisondev/sprut/pkg/broker"
+	"github.com/udisondev/sprut/pkg/message"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// startFakeNATS запускает минимальный NATS сервер: принимает одно соединение
+// и передаёт в канал subjects опубликованных сообщений.
+func startFakeNATS(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+
+	subjects := make(chan string, 16)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"max_payload\":1048576}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "PING":
+				_, _ = conn.Write([]byte("PONG\r\n"))
+			case "PUB":
+				size, err := strconv.Atoi(fields[len(fields)-1])
+				if err != nil {
+					return
+				}
+				if _, err := r.Discard(size + 2); err != nil {
+					return
+				}
+				subjects <- fields[1]
+			}
+		}
+	}()
+	return "nats://" + lis.Addr().String(), subjects
+}
+
+// newPublishingPeer создаёт пира, публикующего в NATS по адресу url;
+// кадры клиента пишутся в возвращённое соединение.
+func newPublishingPeer(t *testing.T, url string) (*Peer, net.Conn) {
+	t.Helper()
+
+	brk, err := broker.New(broker.Config{URLs: []string{url}, ReconnectWait: time.Second})
+	if err != nil {
+		t.Fatalf("broker: %v", err)
+	}
+	t.Cleanup(func() { _ = brk.Close() })
+
+	clientConn, serverConn := net.Pipe()
+	p := &Peer{
+		conn:      serverConn,
+		pubKeyHex: strings.Repeat("ab", protocol.PublicKeySize),
+		publisher: broker.NewPublisher(brk),
+		writeCh:   make(chan outbound, 10),
+		ctrlCh:    make(chan outbound, 10),
+		closeCh:   make(chan struct{}),
+	}
+	t.Cleanup(func() {
+		p.Close()
+		_ = clientConn.Close()
+	})
+	return p, clientConn
+}
+
+func TestReceiptFor(t *testing.T) {
+	from := strings.Repeat("cd", protocol.PublicKeySize)
+	tests := []struct {
+		name   string
+		msg    *message.Message
+		wantOK bool
+	}{
+		{"message", &message.Message{From: from, Id: "m1", Payload: []byte("hi")}, true},
+		{"group message", &message.Message{From: from, Id: "m1", GroupId: "g1"}, true},
+		{"receipt", &message.Message{From: from, Id: "m1", Kind: message.Kind_KIND_RECEIPT}, false},
+		{"group control", &message.Message{From: from, Id: "m1", Kind: message.Kind_KIND_GROUP_CONTROL}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := proto.Marshal(tt.msg)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			gotFrom, gotID, ok := receiptFor(data)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (gotFrom != from || gotID != "m1") {
+				t.Errorf("got %s/%s, want %s/m1", gotFrom, gotID, from)
+			}
+		})
+	}
+
+	if _, _, ok := receiptFor([]byte{0xff}); ok {
+		t.Error("malformed message accepted")
+	}
+}
+
+func TestPendingReceipts(t *testing.T) {
+	from := strings.Repeat("cd", protocol.PublicKeySize)
+	var r pendingReceipts
+	for i := range maxPendingReceipts + 1 {
+		data, err := proto.Marshal(&message.Message{From: from, Id: strconv.Itoa(i)})
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		r.delivered(data)
+	}
+
+	// Самое старое сообщение забыто при переполнении
+	if r.take(from, "0") {
+		t.Error("oldest delivery kept after overflow")
+	}
+	if !r.take(from, "1") {
+		t.Error("delivery not found")
+	}
+	// Вторая квитанция на то же сообщение — уже не ответ на доставку
+	if r.take(from, "1") {
+		t.Error("delivery taken twice")
+	}
+	if r.take(strings.Repeat("ef", protocol.PublicKeySize), "2") {
+		t.Error("delivery from another sender taken")
+	}
+}
+
+func TestHandleMessageReceiptRateLimit(t *testing.T) {
+	url, subjects := startFakeNATS(t)
+	p, clientConn := newPublishingPeer(t, url)
+
+	sender := strings.Repeat("cd", protocol.PublicKeySize)
+	data, err := proto.Marshal(&message.Message{From: sender, Id: "m1"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	p.receipts.delivered(data)
+
+	var admitted int
+	admit := func() error {
+		admitted++
+		return violation(protocol.ErrorCodeRateLimited, "rate limit exceeded")
+	}
+	pool := &sync.Pool{New: func() any {
+		buf := make([]byte, 1024)
+		return &buf
+	}}
+	sendReceipt := func() error {
+		go func() {
+			_ = (&protocol.ClientReceipt{To: sender, MsgID: "m1"}).Encode(clientConn)
+		}()
+		return handleMessage(p, pool, 1024, admit)
+	}
+
+	// Квитанция на доставленное сообщение публикуется, не расходуя лимит
+	if err := sendReceipt(); err != nil {
+		t.Fatalf("answered receipt: %v", err)
+	}
+	if admitted != 0 {
+		t.Errorf("answered receipt charged rate limit %d times", admitted)
+	}
+	select {
+	case subject := <-subjects:
+		if !strings.HasSuffix(subject, sender) {
+			t.Errorf("receipt published to %s, want sender subject", subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("receipt not published")
+	}
+
+	// Повторная квитанция расходует лимит, как сообщение
+	err = sendReceipt()
+	var v *violationError
+	if !errors.As(err, &v) || v.code != protocol.ErrorCodeRateLimited {
+		t.Fatalf("repeated receipt: got %v, want rate limit violation", err)
+	}
+	if admitted != 1 {
+		t.Errorf("rate limit charged %d times, want 1", admitted)
+	}
+}
+
+func TestHandleMessageContactsOnlyReceipt(t *testing.T) {
+	url, subjects := startFakeNATS(t)
+	p, clientConn := newPublishingPeer(t, url)
+	// Контактов у клиента нет: писать ему и от него никому нельзя
+	p.policy = NewContactsOnlyPolicy(&broker.PolicyLists{})
+
+	sender := strings.Repeat("cd", protocol.PublicKeySize)
+	data, err := proto.Marshal(&message.Message{From: sender, Id: "m1"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	p.receipts.delivered(data)
+
+	pool := &sync.Pool{New: func() any {
+		buf := make([]byte, 1024)
+		return &buf
+	}}
+	sendReceipt := func(msgID string) {
+		t.Helper()
+		go func() {
+			_ = (&protocol.ClientReceipt{To: sender, MsgID: msgID}).Encode(clientConn)
+		}()
+		if err := handleMessage(p, pool, 1024, nil); err != nil {
+			t.Fatalf("receipt %s: %v", msgID, err)
+		}
+	}
+
+	// Квитанция автору доставленного сообщения проходит, хотя его нет в контактах
+	sendReceipt("m1")
+	select {
+	case subject := <-subjects:
+		if !strings.HasSuffix(subject, sender) {
+			t.Errorf("receipt published to %s, want sender subject", subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("receipt to sender not published")
+	}
+
+	// Квитанция на сообщение, которого клиент не получал, проверяется политикой
+	sendReceipt("m2")
+	select {
+	case subject := <-subjects:
+		t.Errorf("unanswered receipt published to %s", subject)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
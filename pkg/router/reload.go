@@ -0,0 +1,121 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// limitsState — лимиты, применяемые к новым соединениям и кадрам.
+// Заменяется целиком при перезагрузке конфигурации, чтобы размер
+// буферов пула всегда соответствовал MaxMessageSize.
+type limitsState struct {
+	config.LimitsConfig
+	msgPool *sync.Pool // буферы размера MaxMessageSize (хранит *[]byte для избежания аллокаций)
+}
+
+func newLimitsState(limits config.LimitsConfig) *limitsState {
+	return &limitsState{
+		LimitsConfig: limits,
+		msgPool: &sync.Pool{New: func() any {
+			buf := make([]byte, limits.MaxMessageSize)
+			return &buf
+		}},
+	}
+}
+
+// watchReload применяет конфигурацию, перечитанную по SIGHUP (config.Config.Reload),
+// до отмены ctx.
+func (s *server) watchReload(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case next, ok := <-s.cfg.Reload:
+			if !ok {
+				return
+			}
+			s.reload(next)
+		}
+	}
+}
+
+// reload применяет новую конфигурацию: перечитывает TLS сертификат и лимиты.
+// Изменения остальных полей требуют перезапуска и не применяются.
+func (s *server) reload(next *config.Config) {
+	slog.Info("router: reloading configuration")
+
+	for _, field := range s.cfg.NonReloadableChanges(next) {
+		slog.Error("router: config field is not reloadable, restart required; change ignored", "field", field)
+	}
+
+	if err := s.certs.Reload(); err != nil {
+		slog.Error("tls: certificate reload failed, keeping current", "error", err, "cert_file", s.certs.certFile)
+	}
+	for _, certs := range s.listenerCerts {
+		if err := certs.Reload(); err != nil {
+			slog.Error("tls: certificate reload failed, keeping current", "error", err, "cert_file", certs.certFile)
+		}
+	}
+
+	s.applyLimits(next.Limits)
+}
+
+// applyLimits применяет лимиты к новым соединениям и подключённым клиентам.
+func (s *server) applyLimits(limits config.LimitsConfig) {
+	prev := s.limits.Load()
+	if reflect.DeepEqual(prev.LimitsConfig, limits) {
+		slog.Debug("router: limits unchanged")
+		return
+	}
+
+	next := newLimitsState(limits)
+	if limits.MaxMessageSize == prev.MaxMessageSize {
+		next.msgPool = prev.msgPool
+	}
+	s.limits.Store(next)
+
+	s.slots.setLimit(limits.MaxConnections)
+	s.sources.setLimits(limits)
+
+	if a, ok := s.auths[protocol.TypeClientHello].(*Ed25519Authenticator); ok {
+		a.SetChallengeTTL(limits.ChallengeTTL)
+	}
+
+	// Значения, заданные listener клиента, перезагрузка limits не меняет
+	if limits.RateLimitPerSec != prev.RateLimitPerSec || limits.RateLimitBurst != prev.RateLimitBurst {
+		s.peers.Range(func(_, v any) bool {
+			p := v.(*Peer)
+			connLimits := p.listener.apply(limits)
+			p.SetRateLimit(connLimits.RateLimitPerSec, connLimits.RateLimitBurst)
+			return true
+		})
+	}
+	if limits.OverflowPolicy != prev.OverflowPolicy {
+		s.peers.Range(func(_, v any) bool {
+			p := v.(*Peer)
+			p.SetOverflowPolicy(p.listener.apply(limits).OverflowPolicy)
+			return true
+		})
+	}
+
+	slog.Info("router: limits reloaded",
+		"max_connections", limits.MaxConnections,
+		"max_message_size", limits.MaxMessageSize,
+		"rate_limit_per_sec", limits.RateLimitPerSec,
+		"rate_limit_burst", limits.RateLimitBurst,
+		"auth_timeout", limits.AuthTimeout,
+		"challenge_ttl", limits.ChallengeTTL,
+		"overflow_policy", limits.OverflowPolicy,
+		"rate_limit_mode", limits.RateLimitMode,
+		"max_conns_per_ip", limits.MaxConnsPerIP,
+		"max_conns_per_subnet", limits.MaxConnsPerSubnet,
+		"handshake_rate_per_ip", limits.HandshakeRatePerIP,
+		"handshake_rate_per_subnet", limits.HandshakeRatePerSubnet,
+		"trusted_proxies", limits.TrustedProxies,
+	)
+}
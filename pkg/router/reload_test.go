@@ -0,0 +1,77 @@
+package router
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+func TestApplyLimits(t *testing.T) {
+	auth, err := NewEd25519Authenticator("test", time.Minute)
+	if err != nil {
+		t.Fatalf("NewEd25519Authenticator: %v", err)
+	}
+
+	limits := config.Default().Limits
+	s := &server{
+		auths:   map[byte]Authenticator{auth.Type(): auth},
+		slots:   newConnSlots(limits.MaxConnections),
+		sources: newSourceLimiter(limits),
+	}
+	s.limits.Store(newLimitsState(limits))
+	oldPool := s.limits.Load().msgPool
+
+	peer := &Peer{limiter: rate.NewLimiter(rate.Limit(limits.RateLimitPerSec), limits.RateLimitBurst)}
+	s.peers.Store(PeerID{1}, peer)
+
+	next := limits
+	next.RateLimitPerSec = 5
+	next.RateLimitBurst = 2
+	next.MaxConnections = 1
+	next.ChallengeTTL = 10 * time.Second
+	s.applyLimits(next)
+
+	cur := s.limits.Load()
+	if !reflect.DeepEqual(cur.LimitsConfig, next) {
+		t.Errorf("limits = %+v, want %+v", cur.LimitsConfig, next)
+	}
+	if cur.msgPool != oldPool {
+		t.Error("message pool replaced although max_message_size did not change")
+	}
+	if got := peer.limiter.Limit(); got != 5 {
+		t.Errorf("peer rate = %v, want 5", got)
+	}
+	if got := peer.limiter.Burst(); got != 2 {
+		t.Errorf("peer burst = %d, want 2", got)
+	}
+	if got := time.Duration(auth.challengeTTL.Load()); got != next.ChallengeTTL {
+		t.Errorf("challenge TTL = %v, want %v", got, next.ChallengeTTL)
+	}
+	if s.slots.limit != 1 {
+		t.Errorf("connection limit = %d, want 1", s.slots.limit)
+	}
+
+	// Лимиты на источник тоже перезагружаются
+	next.MaxConnsPerIP = 1
+	s.applyLimits(next)
+	addr := netip.MustParseAddr("192.0.2.1")
+	if release, _ := s.sources.acquire(addr, time.Now()); release == nil {
+		t.Fatal("first connection rejected")
+	}
+	if _, reason := s.sources.acquire(addr, time.Now()); reason != rejectIPConns {
+		t.Errorf("second connection: reason = %q, want %q", reason, rejectIPConns)
+	}
+
+	// Новый размер сообщения — новый пул с буферами нужного размера
+	next.MaxMessageSize = 1024
+	s.applyLimits(next)
+	buf := s.limits.Load().msgPool.Get().(*[]byte)
+	if len(*buf) != 1024 {
+		t.Errorf("message buffer size = %d, want 1024", len(*buf))
+	}
+}
@@ -9,11 +9,14 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/udisondev/sprut/pkg/broker"
 	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/listenfd"
 	"github.com/udisondev/sprut/pkg/protocol"
 )
 
@@ -25,13 +28,17 @@ const (
 
 	// WriteTimeout - таймаут записи сообщения.
 	WriteTimeout = 30 * time.Second
+
+	// ErrorWriteTimeout - таймаут отправки кадра ошибки перед отключением.
+	ErrorWriteTimeout = time.Second
 )
 
 // Run создаёт TCP listener и запускает роутер с TLS.
-// Аналог http.ListenAndServeTLS.
+// Аналог http.ListenAndServeTLS. Сокет, переданный в cfg.Sockets, используется
+// вместо нового.
 func Run(ctx context.Context, cfg *config.Config) error {
 	addr := cfg.Server.Addr()
-	lis, err := net.Listen("tcp", addr)
+	lis, err := socketSet(cfg).Listen(mainListener.name, "tcp", addr)
 	if err != nil {
 		return fmt.Errorf("listen: %w", err)
 	}
@@ -39,12 +46,81 @@ func Run(ctx context.Context, cfg *config.Config) error {
 	return Serve(ctx, cfg, lis)
 }
 
+// socketSet возвращает слушающие сокеты процесса; без cfg.Sockets все сокеты создаются заново.
+func socketSet(cfg *config.Config) *listenfd.Set {
+	if cfg.Sockets != nil {
+		return cfg.Sockets
+	}
+	return &listenfd.Set{}
+}
+
+// bansBucket возвращает KV bucket блокировок; пустой, если они хранятся не в NATS.
+func bansBucket(cfg *config.Config) string {
+	if cfg.Bans.Store != config.BanStoreNATS {
+		return ""
+	}
+	return cfg.NATS.JetStream.BansBucket
+}
+
+// server — состояние запущенного роутера, общее для всех соединений.
+type server struct {
+	cfg      *config.Config
+	auths    map[byte]Authenticator
+	brk      *broker.Broker
+	presence *broker.Presence
+	policy   Policy              // nil = без ограничений
+	lists    *broker.PolicyLists // nil без JetStream
+	certs    *certReloader
+	health   *health
+	bans     *banList
+	abuse    *abuseTracker // нарушения для автоматических блокировок
+	sockets  *listenfd.Set // сокеты listener, передаются новому процессу при обновлении
+
+	// listenerCerts — сертификаты дополнительных listener с собственным tls.
+	listenerCerts []*certReloader
+
+	limits  atomic.Pointer[limitsState] // меняются при перезагрузке конфигурации
+	slots   *connSlots                  // лимит соединений и буферы аутентификации
+	sources *sourceLimiter              // лимиты на адрес и подсеть клиента
+
+	peers sync.Map       // PeerID -> *Peer
+	conns sync.WaitGroup // обработчики принятых соединений
+}
+
 // Serve запускает роутер на переданном TCP listener.
 // Аналог http.ServeTLS — принимает plain TCP listener и оборачивает в TLS.
+// После отмены ctx роутер перестаёт принимать соединения и завершает
+// работу с подключёнными клиентами (см. server.drain).
 func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
-	tlsConfig, err := buildTLSConfig(cfg.TLS)
+	certs, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
 	if err != nil {
-		return fmt.Errorf("build TLS config: %w", err)
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	tlsConfig := buildTLSConfig(cfg.TLS, certs)
+	if cfg.Auth.Enabled(config.AuthMethodMTLS) {
+		if err := configureClientAuth(tlsConfig, cfg.Auth.MTLS); err != nil {
+			return fmt.Errorf("configure client auth: %w", err)
+		}
+	}
+
+	auths, err := newAuthenticators(cfg)
+	if err != nil {
+		return fmt.Errorf("create authenticators: %w", err)
+	}
+
+	s := &server{
+		cfg:     cfg,
+		auths:   auths,
+		certs:   certs,
+		health:  &health{},
+		bans:    newBanList(),
+		abuse:   newAbuseTracker(cfg.Bans.Auto.Window),
+		sockets: socketSet(cfg),
+	}
+
+	addr := lis.Addr().String()
+	if cfg.Server.ProxyProtocol {
+		lis = s.proxyListener(lis, mainListener.name)
 	}
 
 	tlsLis := tls.NewListener(lis, tlsConfig)
@@ -54,11 +130,10 @@ func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
 		}
 	}()
 
-	addr := lis.Addr().String()
-
-	// Graceful shutdown listener
+	// Graceful shutdown: перестаём принимать соединения, drain выполнит accept loop
 	go func() {
 		<-ctx.Done()
+		s.health.draining.Store(true)
 		if err := tlsLis.Close(); err != nil {
 			slog.Error("close listener", "error", err)
 		}
@@ -69,6 +144,17 @@ func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
 		URLs:          cfg.NATS.URLs,
 		ReconnectWait: cfg.NATS.ReconnectWait,
 		MaxReconnects: cfg.NATS.MaxReconnects,
+		JetStream: broker.JetStreamConfig{
+			Enabled:              cfg.NATS.JetStream.Enabled,
+			Stream:               cfg.NATS.JetStream.Stream,
+			MaxMsgsPerRecipient:  cfg.NATS.JetStream.MaxMsgsPerRecipient,
+			MaxBytesPerRecipient: cfg.NATS.JetStream.MaxBytesPerRecipient,
+			MaxAge:               cfg.NATS.JetStream.MaxAge,
+			Replicas:             cfg.NATS.JetStream.Replicas,
+			GroupsBucket:         cfg.NATS.JetStream.GroupsBucket,
+			PolicyBucket:         cfg.NATS.JetStream.PolicyBucket,
+			BansBucket:           bansBucket(cfg),
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("create broker: %w", err)
@@ -78,31 +164,95 @@ func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
 			slog.Error("close broker", "error", err)
 		}
 	}()
+	defer observeBroker(brk)()
+	s.brk = brk
+	s.health.connected = brk.Connected
+
+	// Слоты: одна операция для лимита соединений И получения auth буфера
+	s.slots = newConnSlots(cfg.Limits.MaxConnections)
+	s.sources = newSourceLimiter(cfg.Limits)
+	s.limits.Store(newLimitsState(cfg.Limits))
+	go s.sources.watch(ctx)
+
+	s.presence = broker.NewPresence(brk, cfg.Server.ServerID)
 
-	// ServerID в байтах для записи в буферы
-	var serverID [protocol.ServerIDSize]byte
-	serverIDBytes := []byte(cfg.Server.ServerID)
-	if len(serverIDBytes) > protocol.ServerIDSize {
-		return fmt.Errorf("server_id too long: max %d bytes, got %d", protocol.ServerIDSize, len(serverIDBytes))
+	// Блок-листы и контакты получателей хранятся в JetStream KV
+	if brk.JetStreamEnabled() {
+		s.lists, err = broker.NewPolicyLists(brk)
+		if err != nil {
+			return fmt.Errorf("create policy lists: %w", err)
+		}
+		defer func() {
+			if err := s.lists.Stop(); err != nil {
+				slog.Error("stop policy lists", "error", err)
+			}
+		}()
 	}
-	copy(serverID[:], serverIDBytes)
 
-	// Семафор-с-буфером: одна операция для лимита соединений И получения auth буфера
-	authSem := make(chan []byte, cfg.Limits.MaxConnections)
-	for range cfg.Limits.MaxConnections {
-		buf := make([]byte, AuthBufSize)
-		copy(buf[offServerID:offServerID+protocol.ServerIDSize], serverID[:])
-		authSem <- buf
+	s.policy, err = newPolicy(cfg.Policy, s.lists)
+	if err != nil {
+		return fmt.Errorf("create policy: %w", err)
+	}
+
+	// Блокировки ключей и адресов
+	closeBans, err := s.openBans(cfg.Bans)
+	if err != nil {
+		return fmt.Errorf("open bans: %w", err)
+	}
+	defer closeBans()
+	go s.sweepBans(ctx)
+
+	// Служебный HTTP сервер останавливается при выходе из Serve
+	if cfg.HTTP.Addr != "" {
+		// Не от ctx: во время завершения /readyz должен сообщать о drain
+		httpCtx, stopHTTP := context.WithCancel(context.Background())
+		defer stopHTTP()
+		if err := s.serveHTTP(httpCtx, "http", cfg.HTTP.Addr, s.health.handler()); err != nil {
+			return fmt.Errorf("start HTTP server: %w", err)
+		}
+	}
+
+	// Admin API: список клиентов, отключение и блокировка ключей
+	if cfg.Admin.Addr != "" {
+		token, err := loadAdminToken(cfg.Admin.TokenFile)
+		if err != nil {
+			return fmt.Errorf("load admin token: %w", err)
+		}
+		adminCtx, stopAdmin := context.WithCancel(ctx)
+		defer stopAdmin()
+		if err := s.serveHTTP(adminCtx, "admin", cfg.Admin.Addr, s.adminHandler(token)); err != nil {
+			return fmt.Errorf("start admin server: %w", err)
+		}
+	}
+
+	// WebSocket listener для браузерных клиентов
+	if cfg.Server.WebSocket.Addr != "" {
+		if err := s.serveWebSocket(ctx, cfg.Server.WebSocket, tlsConfig); err != nil {
+			return fmt.Errorf("start websocket listener: %w", err)
+		}
 	}
 
-	// sync.Pool для буферов сообщений (хранит *[]byte для избежания аллокаций)
-	msgPool := &sync.Pool{New: func() any {
-		buf := make([]byte, cfg.Limits.MaxMessageSize)
-		return &buf
-	}}
+	// QUIC listener для мобильных клиентов
+	if cfg.Server.QUIC.Addr != "" {
+		closeQUIC, err := s.serveQUIC(ctx, cfg.Server.QUIC, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("start quic listener: %w", err)
+		}
+		// UDP сокет нужен QUIC соединениям до конца drain
+		defer closeQUIC()
+	}
+
+	// Дополнительные TCP и Unix listener
+	for _, lcfg := range cfg.Server.Listeners {
+		if err := s.serveListener(ctx, lcfg, tlsConfig); err != nil {
+			return fmt.Errorf("start listener %s: %w", lcfg.DisplayName(), err)
+		}
+	}
 
-	// sync.Map для пиров
-	var peers sync.Map
+	// Сокеты от systemd или предыдущего процесса, которым нет listener в конфигурации
+	for _, name := range s.sockets.CloseUnused() {
+		slog.Warn("router: inherited socket not used, closed", "name", name)
+	}
 
 	slog.Info("router started", "addr", addr)
 	slog.Info("router: configuration",
@@ -112,7 +262,29 @@ func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
 		"rate_limit_burst", cfg.Limits.RateLimitBurst,
 		"auth_timeout", cfg.Limits.AuthTimeout,
 		"challenge_ttl", cfg.Limits.ChallengeTTL,
+		"auth_methods", cfg.Auth.Methods,
+		"policy_allowlist", cfg.Policy.AllowlistFile,
+		"policy_blocklists", cfg.Policy.Blocklists,
+		"policy_contacts_only", cfg.Policy.ContactsOnly,
+		"jetstream", cfg.NATS.JetStream.Enabled,
+		"drain_timeout", cfg.Server.DrainTimeout,
+		"proxy_protocol", cfg.Server.ProxyProtocol,
 	)
+	if !brk.JetStreamEnabled() {
+		slog.Warn("router: JetStream disabled, groups and offline delivery unavailable; group frames are rejected",
+			"hint", "set nats.jetstream.enabled: true")
+	}
+
+	// Сертификат перечитывается при изменении файлов и по SIGHUP
+	if cfg.TLS.ReloadInterval > 0 {
+		go certs.watch(ctx, cfg.TLS.ReloadInterval)
+	}
+	if cfg.Reload != nil {
+		go s.watchReload(ctx)
+	}
+
+	s.health.listening.Store(true)
+	defer s.health.listening.Store(false)
 
 	// Сигнализируем что сервер готов
 	if cfg.Ready != nil {
@@ -123,42 +295,108 @@ func Serve(ctx context.Context, cfg *config.Config, lis net.Listener) error {
 	for {
 		conn, err := tlsLis.Accept()
 		if err != nil {
-			if ctx.Err() != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
 				slog.Info("router shutting down")
-				return nil
-			}
-			if errors.Is(err, net.ErrClosed) {
+				s.health.draining.Store(true)
+				s.health.listening.Store(false)
+				s.drain()
 				return nil
 			}
 			slog.Error("accept connection", "error", err)
 			continue
 		}
 
-		select {
-		case authBuf := <-authSem:
-			slog.Debug("router: auth buffer acquired", "remote", conn.RemoteAddr())
-			go func(c net.Conn, buf []byte) {
-				defer func() { authSem <- buf }()
-				handleConn(c, &peers, buf, msgPool, brk, cfg)
-			}(conn, authBuf)
-		default:
-			slog.Warn("router: connection limit reached", "remote", conn.RemoteAddr())
-			if err := conn.Close(); err != nil {
-				slog.Error("router: close connection on limit failed", "error", err)
-			}
+		s.serveConn(conn, mainListener)
+	}
+}
+
+// serveConn применяет к принятому соединению лимиты на источник и общий
+// лимит соединений и запускает его обработку. Общий для TCP, Unix и QUIC listener.
+func (s *server) serveConn(conn net.Conn, l *listener) {
+	// Лимиты на источник — до рукопожатия и до занятия общего слота
+	releaseSource, reason := s.sources.acquire(remoteIP(conn), time.Now())
+	if releaseSource == nil {
+		metricConnsRejected.WithLabelValues(reason).Inc()
+		// Debug: при атаке с одного источника Warn на каждое соединение засыпал бы лог
+		slog.Debug("router: source limit reached", "remote", conn.RemoteAddr(), "reason", reason)
+		if err := conn.Close(); err != nil {
+			slog.Error("router: close connection on limit failed", "error", err)
 		}
+		return
 	}
+
+	authBuf, ok := s.slots.acquire()
+	if !ok {
+		releaseSource()
+		metricAuthSlotsExhausted.Inc()
+		slog.Warn("router: connection limit reached", "remote", conn.RemoteAddr())
+		if err := conn.Close(); err != nil {
+			slog.Error("router: close connection on limit failed", "error", err)
+		}
+		return
+	}
+
+	slog.Debug("router: auth buffer acquired", "remote", conn.RemoteAddr())
+	s.conns.Add(1)
+	go func() {
+		defer s.conns.Done()
+		defer releaseSource()
+		defer s.slots.release(authBuf)
+		s.handleConn(conn, authBuf, l)
+	}()
 }
 
-// handleConn обрабатывает одно соединение.
-func handleConn(
-	conn net.Conn,
-	peers *sync.Map,
-	authBuf []byte,
-	msgPool *sync.Pool,
-	brk *broker.Broker,
-	cfg *config.Config,
-) {
+// drain завершает работу с подключёнными клиентами: отправляет им GoAway
+// после уже поставленных в очередь кадров и ждёт, пока клиенты отключатся сами.
+// По истечении server.drain_timeout оставшиеся соединения закрываются.
+func (s *server) drain() {
+	timeout := s.cfg.Server.DrainTimeout
+
+	var n int
+	s.peers.Range(func(_, v any) bool {
+		v.(*Peer).GoAway(s.cfg.Server.DrainAddr)
+		n++
+		return true
+	})
+	slog.Info("router: draining", "peers", n, "timeout", timeout, "alternate_addr", s.cfg.Server.DrainAddr)
+
+	done := make(chan struct{})
+	go func() {
+		s.conns.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		slog.Info("router: drained")
+		return
+	case <-timer.C:
+	}
+
+	var closed int
+	s.peers.Range(func(_, v any) bool {
+		v.(*Peer).Close()
+		closed++
+		return true
+	})
+	slog.Warn("router: drain timeout, connections closed", "peers", closed)
+
+	// Обработчики публикуют отключение клиентов — ждём их до закрытия брокера
+	select {
+	case <-done:
+	case <-time.After(ErrorWriteTimeout):
+	}
+}
+
+// handleConn обрабатывает одно соединение, принятое listener l.
+func (s *server) handleConn(conn net.Conn, authBuf []byte, l *listener) {
+	cfg := s.cfg
+	limits := s.limits.Load()
+	connLimits := l.apply(limits.LimitsConfig)
+	accepted := time.Now()
 	remoteAddr := conn.RemoteAddr().String()
 	defer func() {
 		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
@@ -175,51 +413,102 @@ func handleConn(
 		}
 	}
 
-	// 1. Аутентификация (буфер с serverID уже получен из семафора)
-	if err := authenticate(conn, cfg.Limits.AuthTimeout, cfg.Limits.ChallengeTTL, authBuf); err != nil {
+	// Заблокированный адрес отключаем до TLS рукопожатия
+	ip := remoteIP(conn)
+	if ban, ok := s.bans.checkAddr(ip, accepted); ok {
+		metricBannedConns.Inc()
+		slog.Debug("connection from banned address rejected", "remote", remoteAddr, "cidr", ban.CIDR)
+		return
+	}
+
+	// 1. Аутентификация способом, выбранным клиентом (буфер получен из семафора)
+	id, method, err := authenticate(conn, s.auths, limits.AuthTimeout, authBuf, s.admit)
+	observeAuth(method, accepted, err)
+	if err != nil {
 		if !errors.Is(err, io.EOF) {
-			slog.Warn("authentication failed", "error", err, "remote", remoteAddr)
+			slog.Warn("authentication failed", "error", err, "remote", remoteAddr, "method", method)
 		}
+		s.recordAuthFailure(ip, authFailureReason(err))
 		return
 	}
 
-	// PeerID уже в буфере после authenticate()
-	var id PeerID
-	copy(id[:], authBuf[offPubKey:offPubKey+protocol.PublicKeySize])
 	pubKeyHex := hex.EncodeToString(id[:])
-	slog.Info("client authenticated", "client", pubKeyHex, "remote", remoteAddr)
+	slog.Info("client authenticated", "client", pubKeyHex, "remote", remoteAddr, "method", method, "listener", l.name)
 
 	// 2. Создаём peer
 	peer, err := newPeer(
-		conn, id, brk,
+		newMeteredConn(conn), id, s.brk,
 		WriteBufferSize, WriteTimeout,
-		cfg.Limits.RateLimitPerSec, cfg.Limits.RateLimitBurst,
+		connLimits.RateLimitPerSec, connLimits.RateLimitBurst,
+		connLimits.OverflowPolicy,
 	)
 	if err != nil {
 		slog.Error("router: create peer failed", "error", err, "client", pubKeyHex)
 		return
 	}
+	peer.policy = s.policy
+	peer.lists = s.lists
+	peer.listener = l
 	slog.Debug("router: peer created", "client", pubKeyHex, "remote", remoteAddr)
 
-	// 3. Закрываем старое соединение если есть (reconnect case)
-	if old, loaded := peers.LoadAndDelete(id); loaded {
+	// 3. Запускаем write loop
+	slog.Debug("router: starting read/write loops", "client", pubKeyHex)
+	go peer.writeLoop()
+
+	// 4. Закрываем старое соединение если есть (reconnect case)
+	if old, loaded := s.peers.Swap(id, peer); loaded {
 		oldPeer := old.(*Peer)
 		slog.Info("closing old connection", "client", pubKeyHex)
-		oldPeer.Close()
+		go oldPeer.CloseWithError(protocol.ErrorCodeReplaced, "replaced by new connection")
+	}
+
+	// Лимиты могли измениться, пока пира не было в peers
+	if cur := s.limits.Load(); cur != limits {
+		connLimits := l.apply(cur.LimitsConfig)
+		peer.SetRateLimit(connLimits.RateLimitPerSec, connLimits.RateLimitBurst)
+		peer.SetOverflowPolicy(connLimits.OverflowPolicy)
 	}
-	peers.Store(id, peer)
 
+	metricPeersActive.Inc()
 	defer func() {
-		peers.Delete(id)
+		metricPeersActive.Dec()
+		// Не удаляем запись, если её уже заняло новое соединение с тем же ключом
+		replaced := !s.peers.CompareAndDelete(id, peer)
 		peer.Close()
+		peer.stopPresence(!replaced)
 		slog.Info("client disconnected", "client", pubKeyHex)
 	}()
 
-	// 4. Запускаем write loop
-	slog.Debug("router: starting read/write loops", "client", pubKeyHex)
-	go peer.writeLoop()
+	// Аутентификация завершилась во время drain: drain мог не увидеть пира
+	if s.health.draining.Load() {
+		peer.GoAway(cfg.Server.DrainAddr)
+	}
+
+	// 5. Presence: статус клиента и подписка на статусы контактов
+	if err := peer.startPresence(s.presence); err != nil {
+		slog.Error("router: start presence failed", "error", err, "client", pubKeyHex)
+		peer.CloseWithError(protocol.ErrorCodeInternal, "presence unavailable")
+		return
+	}
+
+	// 6. Read loop (блокирующий)
+	var idleDeadline bool // на соединении стоит дедлайн чтения
+
+	// admit списывает прочитанный кадр из лимита частоты (см. handleMessage)
+	admit := func() error {
+		if connLimits.RateLimitMode == config.RateLimitModeThrottle {
+			// Следующий кадр не читается, пока лимит не разрешит: клиент упрётся в TCP окно
+			if err := peer.WaitMessage(); err != nil {
+				return fmt.Errorf("wait rate limit: %w", net.ErrClosed)
+			}
+			return nil
+		}
+		if !peer.AllowMessage() {
+			return violation(protocol.ErrorCodeRateLimited, "rate limit exceeded")
+		}
+		return nil
+	}
 
-	// 5. Read loop (блокирующий)
 	for {
 		select {
 		case <-peer.closeCh:
@@ -227,16 +516,40 @@ func handleConn(
 		default:
 		}
 
-		// Rate limiting: проверяем перед чтением сообщения
-		if !peer.AllowMessage() {
-			slog.Warn("rate limit exceeded, disconnecting client", "client", pubKeyHex)
-			return
+		// Лимиты читаются на каждый кадр: перезагрузка применяется к подключённым клиентам
+		limits := s.limits.Load()
+		connLimits = l.apply(limits.LimitsConfig)
+
+		// Кадр должен прийти до дедлайна, иначе соединение считается полуоткрытым.
+		// Ожидание лимита в throttle идёт после чтения кадра и дедлайн не расходует.
+		if connLimits.IdleTimeout > 0 || idleDeadline {
+			var deadline time.Time
+			if connLimits.IdleTimeout > 0 {
+				deadline = time.Now().Add(connLimits.IdleTimeout)
+			}
+			if err := conn.SetReadDeadline(deadline); err != nil {
+				slog.Error("set read deadline", "error", err, "client", pubKeyHex)
+				return
+			}
+			idleDeadline = connLimits.IdleTimeout > 0
 		}
 
-		if err := handleMessage(peer, msgPool, cfg.Limits.MaxMessageSize); err != nil {
-			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		if err := handleMessage(peer, limits.msgPool, limits.MaxMessageSize, admit); err != nil {
+			var v *violationError
+			switch {
+			case errors.As(err, &v) && v.code == protocol.ErrorCodeRateLimited:
+				slog.Warn("rate limit exceeded, disconnecting client", "client", pubKeyHex)
+				peer.CloseWithError(v.code, v.Error())
+				s.recordRateLimited(ip, pubKeyHex)
+			case errors.As(err, &v):
+				slog.Warn("protocol violation, disconnecting client", "error", err, "client", pubKeyHex)
+				peer.CloseWithError(v.code, v.Error())
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				metricIdleTimeouts.Inc()
+				slog.Info("idle timeout, disconnecting client", "client", pubKeyHex, "timeout", connLimits.IdleTimeout)
+			case errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed):
 				slog.Debug("peer disconnected gracefully", "client", pubKeyHex)
-			} else {
+			default:
 				slog.Error("handle message", "error", err, "client", pubKeyHex)
 			}
 			return
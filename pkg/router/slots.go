@@ -0,0 +1,64 @@
+package router
+
+import "sync"
+
+// connSlots ограничивает число одновременно обслуживаемых соединений
+// и выдаёт каждому буфер аутентификации размера AuthBufSize.
+// Лимит меняется на ходу: при уменьшении принятые соединения не разрываются,
+// новые отклоняются, пока активных больше лимита.
+type connSlots struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	free   [][]byte // буферы освободившихся слотов
+}
+
+// newConnSlots создаёт connSlots с лимитом limit и заранее выделенными буферами.
+func newConnSlots(limit int) *connSlots {
+	s := &connSlots{limit: limit, free: make([][]byte, limit)}
+	for i := range s.free {
+		s.free[i] = make([]byte, AuthBufSize)
+	}
+	return s
+}
+
+// acquire занимает слот. Возвращает false, если лимит исчерпан.
+func (s *connSlots) acquire() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active >= s.limit {
+		return nil, false
+	}
+	s.active++
+
+	if n := len(s.free); n > 0 {
+		buf := s.free[n-1]
+		s.free = s.free[:n-1]
+		return buf, true
+	}
+	return make([]byte, AuthBufSize), true
+}
+
+// release освобождает слот, занятый acquire.
+func (s *connSlots) release(buf []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	// Буферы сверх уменьшенного лимита отдаём GC
+	if s.active+len(s.free) < s.limit {
+		s.free = append(s.free, buf)
+	}
+}
+
+// setLimit меняет лимит соединений.
+func (s *connSlots) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	if extra := s.active + len(s.free) - limit; extra > 0 {
+		s.free = s.free[:max(len(s.free)-extra, 0)]
+	}
+}
@@ -0,0 +1,41 @@
+package router
+
+import "testing"
+
+func TestConnSlots(t *testing.T) {
+	s := newConnSlots(2)
+
+	a, ok := s.acquire()
+	if !ok || len(a) != AuthBufSize {
+		t.Fatalf("acquire 1: ok=%v len=%d", ok, len(a))
+	}
+	b, ok := s.acquire()
+	if !ok {
+		t.Fatal("acquire 2 failed")
+	}
+	if _, ok := s.acquire(); ok {
+		t.Fatal("acquire over limit succeeded")
+	}
+
+	// Увеличение лимита сразу освобождает место
+	s.setLimit(3)
+	c, ok := s.acquire()
+	if !ok {
+		t.Fatal("acquire after raising limit failed")
+	}
+
+	// Уменьшение не разрывает занятые слоты, новые ждут освобождения
+	s.setLimit(1)
+	s.release(a)
+	s.release(b)
+	if _, ok := s.acquire(); ok {
+		t.Fatal("acquire while active >= limit succeeded")
+	}
+	s.release(c)
+	if _, ok := s.acquire(); !ok {
+		t.Fatal("acquire after release failed")
+	}
+	if n := len(s.free); n != 0 {
+		t.Errorf("free buffers = %d, want 0", n)
+	}
+}
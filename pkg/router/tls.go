@@ -2,25 +2,25 @@
 package router
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/udisondev/sprut/pkg/config"
 )
 
-// buildTLSConfig создаёт production-ready TLS конфигурацию.
-func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
-	slog.Debug("tls: loading certificates", "cert_file", cfg.CertFile, "key_file", cfg.KeyFile)
-
-	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
-	if err != nil {
-		slog.Error("tls: load certificates failed", "error", err, "cert_file", cfg.CertFile, "key_file", cfg.KeyFile)
-		return nil, fmt.Errorf("load certificates: %w", err)
-	}
-
-	slog.Info("tls: certificates loaded", "cert_file", cfg.CertFile)
+// certExpiryWarning — за сколько до истечения сертификата предупреждать в логах.
+const certExpiryWarning = 7 * 24 * time.Hour
 
+// buildTLSConfig создаёт production-ready TLS конфигурацию.
+// Сертификат сервера отдаёт certs, что позволяет менять его без перезапуска.
+func buildTLSConfig(cfg config.TLSConfig, certs *certReloader) *tls.Config {
 	minVersion := tls.VersionTLS12
 	minVersionStr := "1.2"
 	if cfg.MinVersion == "1.3" {
@@ -30,9 +30,9 @@ func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
 
 	slog.Debug("tls: configuration built", "min_version", minVersionStr, "session_tickets_disabled", true)
 
-	tlsCfg := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   uint16(minVersion),
+	return &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     uint16(minVersion),
 		// CipherSuites игнорируются для TLS 1.3 (Go выбирает автоматически)
 		// Для TLS 1.2 указываем явно безопасные cipher suites
 		CipherSuites: []uint16{
@@ -49,6 +49,154 @@ func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
 		// При компрометации ticket key без ротации нарушается PFS.
 		SessionTicketsDisabled: true,
 	}
+}
 
-	return tlsCfg, nil
+// certReloader хранит активный сертификат сервера и перечитывает его
+// с диска по запросу (SIGHUP) или при изменении файлов.
+// Новая пара проверяется до замены: при ошибке остаётся прежний сертификат.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu    sync.Mutex // сериализует перезагрузку
+	stamp certStamp  // состояние файлов при последней успешной загрузке
+}
+
+// certStamp — время изменения и размер файлов сертификата и ключа.
+type certStamp struct {
+	certMod, keyMod   time.Time
+	certSize, keySize int64
+}
+
+// newCertReloader загружает сертификат из файлов.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate возвращает активный сертификат (tls.Config.GetCertificate).
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload перечитывает сертификат и ключ и заменяет активный сертификат,
+// если новая пара валидна.
+func (r *certReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp, err := statCertFiles(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	return r.load(stamp)
+}
+
+// reloadIfChanged перечитывает сертификат, если файлы изменились
+// с последней успешной загрузки.
+func (r *certReloader) reloadIfChanged() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp, err := statCertFiles(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if stamp == r.stamp {
+		return nil
+	}
+	return r.load(stamp)
+}
+
+// load загружает и проверяет пару, затем делает её активной. Вызывается под mu.
+func (r *certReloader) load(stamp certStamp) error {
+	slog.Debug("tls: loading certificates", "cert_file", r.certFile, "key_file", r.keyFile)
+
+	cert, err := loadCertificate(r.certFile, r.keyFile, time.Now())
+	if err != nil {
+		return err
+	}
+
+	r.cert.Store(cert)
+	r.stamp = stamp
+
+	leaf := cert.Leaf
+	metricTLSCertExpiry.WithLabelValues(r.certFile).Set(float64(leaf.NotAfter.Unix()))
+	slog.Info("tls: certificate loaded",
+		"cert_file", r.certFile,
+		"subject", leaf.Subject.String(),
+		"serial", leaf.SerialNumber.String(),
+		"not_after", leaf.NotAfter,
+	)
+	if left := time.Until(leaf.NotAfter); left < certExpiryWarning {
+		slog.Warn("tls: certificate expires soon", "cert_file", r.certFile, "not_after", leaf.NotAfter, "left", left.Round(time.Minute))
+	}
+
+	return nil
+}
+
+// watch проверяет файлы сертификата каждые interval до отмены ctx.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reloadIfChanged(); err != nil {
+				// Файлы могут быть записаны не полностью — повторим на следующей проверке
+				slog.Warn("tls: certificate reload failed, keeping current", "error", err, "cert_file", r.certFile)
+			}
+		}
+	}
+}
+
+// loadCertificate загружает пару сертификат/ключ и проверяет,
+// что ключ соответствует сертификату и сертификат действителен в момент now.
+func loadCertificate(certFile, keyFile string, now time.Time) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificates: %w", err)
+	}
+
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+	}
+
+	switch {
+	case now.Before(cert.Leaf.NotBefore):
+		return nil, fmt.Errorf("certificate is not valid before %s", cert.Leaf.NotBefore)
+	case now.After(cert.Leaf.NotAfter):
+		return nil, fmt.Errorf("certificate expired at %s", cert.Leaf.NotAfter)
+	}
+
+	return &cert, nil
+}
+
+// statCertFiles возвращает состояние файлов сертификата и ключа.
+func statCertFiles(certFile, keyFile string) (certStamp, error) {
+	certInfo, err := os.Stat(certFile)
+	if err != nil {
+		return certStamp{}, fmt.Errorf("stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(keyFile)
+	if err != nil {
+		return certStamp{}, fmt.Errorf("stat key: %w", err)
+	}
+	return certStamp{
+		certMod:  certInfo.ModTime(),
+		keyMod:   keyInfo.ModTime(),
+		certSize: certInfo.Size(),
+		keySize:  keyInfo.Size(),
+	}, nil
 }
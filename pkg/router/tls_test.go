@@ -0,0 +1,133 @@
+package router
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// writeTestCert записывает самоподписанный сертификат с указанным сроком действия.
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64, notBefore, notAfter time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if keyFile != "" {
+		if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+			t.Fatalf("write key: %v", err)
+		}
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	now := time.Now()
+
+	writeTestCert(t, certFile, keyFile, 1, now.Add(-time.Hour), now.Add(30*24*time.Hour))
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+
+	serial := func() int64 {
+		cert, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+		return cert.Leaf.SerialNumber.Int64()
+	}
+
+	// Файлы не менялись — сертификат прежний
+	if err := r.reloadIfChanged(); err != nil {
+		t.Fatalf("reloadIfChanged: %v", err)
+	}
+	if got := serial(); got != 1 {
+		t.Fatalf("serial = %d, want 1", got)
+	}
+
+	// Ротация: новая пара заменяет активную
+	writeTestCert(t, certFile, keyFile, 2, now.Add(-time.Hour), now.Add(60*24*time.Hour))
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := serial(); got != 2 {
+		t.Fatalf("serial after rotation = %d, want 2", got)
+	}
+	if got, want := testutil.ToFloat64(metricTLSCertExpiry.WithLabelValues(certFile)), float64(now.Add(60*24*time.Hour).Unix()); got != want {
+		t.Errorf("expiry metric = %v, want %v", got, want)
+	}
+
+	// Сертификат записан, ключ ещё нет — пара не совпадает
+	writeTestCert(t, certFile, "", 3, now.Add(-time.Hour), now.Add(90*24*time.Hour))
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected error for mismatched key")
+	}
+	if got := serial(); got != 2 {
+		t.Fatalf("serial after mismatched key = %d, want 2", got)
+	}
+
+	// Просроченный сертификат не применяется
+	writeTestCert(t, certFile, keyFile, 4, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected error for expired certificate")
+	}
+	if got := serial(); got != 2 {
+		t.Fatalf("serial after expired certificate = %d, want 2", got)
+	}
+}
+
+func TestCertExpiryMetricPerFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	expiry := map[string]time.Time{
+		filepath.Join(dir, "main.crt"):     now.Add(30 * 24 * time.Hour),
+		filepath.Join(dir, "internal.crt"): now.Add(90 * 24 * time.Hour),
+	}
+
+	for certFile, notAfter := range expiry {
+		keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+		writeTestCert(t, certFile, keyFile, 1, now.Add(-time.Hour), notAfter)
+		if _, err := newCertReloader(certFile, keyFile); err != nil {
+			t.Fatalf("newCertReloader(%s): %v", certFile, err)
+		}
+	}
+
+	// Каждый сертификат отдаёт свой срок, а не срок последнего загруженного
+	for certFile, notAfter := range expiry {
+		if got, want := testutil.ToFloat64(metricTLSCertExpiry.WithLabelValues(certFile)), float64(notAfter.Unix()); got != want {
+			t.Errorf("expiry metric for %s = %v, want %v", filepath.Base(certFile), got, want)
+		}
+	}
+}
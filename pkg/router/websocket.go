@@ -0,0 +1,136 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/udisondev/sprut/pkg/config"
+	"github.com/udisondev/sprut/pkg/transport"
+)
+
+// serveWebSocket запускает WebSocket listener (config.WebSocketConfig).
+// Соединения проходят те же лимиты и обрабатываются тем же handleConn,
+// что и соединения TCP listener. Listener закрывается с отменой ctx;
+// установленные соединения завершаются в drain вместе с остальными.
+func (s *server) serveWebSocket(ctx context.Context, cfg config.WebSocketConfig, tlsConfig *tls.Config) error {
+	lis, err := s.sockets.Listen("websocket", "tcp", cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	// WebSocket работает поверх HTTP/1.1: без ALPN h2 соединение можно перехватить
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"http/1.1"}
+
+	path := cfg.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET "+path, s.webSocketHandler(cfg.AllowedOrigins))
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: s.limits.Load().AuthTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelDebug),
+	}
+
+	tlsLis := tls.NewListener(&sourceLimitedListener{Listener: lis, sources: s.sources}, tlsConfig)
+	go func() {
+		if err := srv.Serve(tlsLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("websocket: serve failed", "error", err, "addr", cfg.Addr)
+		}
+	}()
+
+	// Close не трогает перехваченные соединения: ими занимается drain
+	go func() {
+		<-ctx.Done()
+		if err := srv.Close(); err != nil {
+			slog.Error("websocket: close failed", "error", err)
+		}
+	}()
+
+	slog.Info("websocket: started", "addr", lis.Addr().String(), "path", path)
+	return nil
+}
+
+// webSocketHandler принимает WebSocket соединение и обслуживает его как TCP:
+// аутентификация, затем кадры протокола до отключения клиента.
+func (s *server) webSocketHandler(origins []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.health.draining.Load() {
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		authBuf, ok := s.slots.acquire()
+		if !ok {
+			metricAuthSlotsExhausted.Inc()
+			slog.Warn("websocket: connection limit reached", "remote", r.RemoteAddr)
+			http.Error(w, "connection limit reached", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.slots.release(authBuf)
+
+		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: origins})
+		if err != nil {
+			// Accept уже ответил клиенту
+			slog.Debug("websocket: upgrade failed", "error", err, "remote", r.RemoteAddr)
+			return
+		}
+
+		s.conns.Add(1)
+		defer s.conns.Done()
+		s.handleConn(transport.NewWebSocketConn(ws, *r.TLS), authBuf, webSocketListener)
+	})
+}
+
+// sourceLimitedListener применяет лимиты на источник (sourceLimiter)
+// к принятым соединениям до TLS рукопожатия, как accept loop TCP listener.
+type sourceLimitedListener struct {
+	net.Listener
+	sources *sourceLimiter
+}
+
+// Accept возвращает следующее соединение, прошедшее лимиты на источник.
+// Место источника освобождается при закрытии соединения.
+func (l *sourceLimitedListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		release, reason := l.sources.acquire(remoteIP(conn), time.Now())
+		if release == nil {
+			metricConnsRejected.WithLabelValues(reason).Inc()
+			slog.Debug("websocket: source limit reached", "remote", conn.RemoteAddr(), "reason", reason)
+			if err := conn.Close(); err != nil {
+				slog.Error("websocket: close connection on limit failed", "error", err)
+			}
+			continue
+		}
+		return &releasingConn{Conn: conn, release: sync.OnceFunc(release)}, nil
+	}
+}
+
+// releasingConn вызывает release при закрытии соединения.
+type releasingConn struct {
+	net.Conn
+	release func()
+}
+
+// Close закрывает соединение и освобождает место источника.
+func (c *releasingConn) Close() error {
+	defer c.release()
+	return c.Conn.Close()
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/config"
+)
+
+func TestSourceLimitedListener(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	lis := &sourceLimitedListener{
+		Listener: raw,
+		sources:  newSourceLimiter(config.LimitsConfig{MaxConnsPerIP: 1}),
+	}
+	defer lis.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", raw.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	dial()
+	first := <-accepted
+
+	// Второе соединение с того же адреса закрывается до TLS рукопожатия
+	second := dial()
+	_ = second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var buf [1]byte
+	if _, err := second.Read(buf[:]); err == nil {
+		t.Fatal("connection over per-IP limit was not closed")
+	}
+
+	// Закрытие принятого соединения освобождает место источника
+	if err := first.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	dial()
+	select {
+	case conn := <-accepted:
+		_ = conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not accepted after release")
+	}
+}
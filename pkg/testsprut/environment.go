@@ -21,6 +21,10 @@ type Environment struct {
 	SprutAddr string
 	// CACert CA сертификат для TLS клиентов.
 	CACert []byte
+	// WebSocketURL адрес WebSocket listener (wss://host:port/), если он включён WithWebSocket.
+	WebSocketURL string
+	// QUICAddr адрес QUIC listener (quic://host:port), если он включён WithQUIC.
+	QUICAddr string
 
 	nats      *natsContainer
 	certs     *Certs
@@ -40,6 +44,17 @@ type options struct {
 	authTimeout     time.Duration
 	challengeTTL    time.Duration
 	serverID        string
+	jetStream       bool
+	auth            config.AuthConfig
+	policy          config.PolicyConfig
+	drainTimeout    time.Duration
+	drainAddr       string
+	overflowPolicy  string
+	rateLimitMode   string
+	idleTimeout     time.Duration
+	webSocket       bool
+	quic            bool
+	listeners       []config.ListenerConfig
 }
 
 func defaultOptions() *options {
@@ -72,6 +87,36 @@ func WithRateLimit(perSec float64, burst int) Option {
 	}
 }
 
+// WithOverflowPolicy устанавливает политику переполнения очереди записи клиента (config.Overflow*).
+func WithOverflowPolicy(policy string) Option {
+	return func(o *options) { o.overflowPolicy = policy }
+}
+
+// WithRateLimitMode устанавливает реакцию на превышение rate limit (config.RateLimitMode*).
+func WithRateLimitMode(mode string) Option {
+	return func(o *options) { o.rateLimitMode = mode }
+}
+
+// WithIdleTimeout устанавливает, сколько сервер ждёт кадра от клиента (0 = без ограничения).
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) { o.idleTimeout = d }
+}
+
+// WithWebSocket включает WebSocket listener на случайном порту (см. Environment.WebSocketURL).
+func WithWebSocket() Option {
+	return func(o *options) { o.webSocket = true }
+}
+
+// WithQUIC включает QUIC listener на случайном UDP порту (см. Environment.QUICAddr).
+func WithQUIC() Option {
+	return func(o *options) { o.quic = true }
+}
+
+// WithListener добавляет дополнительный listener (config.ListenerConfig).
+func WithListener(l config.ListenerConfig) Option {
+	return func(o *options) { o.listeners = append(o.listeners, l) }
+}
+
 // WithAuthTimeout устанавливает таймаут аутентификации.
 func WithAuthTimeout(d time.Duration) Option {
 	return func(o *options) { o.authTimeout = d }
@@ -87,6 +132,31 @@ func WithServerID(id string) Option {
 	return func(o *options) { o.serverID = id }
 }
 
+// WithJetStream включает offline-доставку и группы через NATS JetStream.
+func WithJetStream() Option {
+	return func(o *options) { o.jetStream = true }
+}
+
+// WithAuth устанавливает способы аутентификации клиентов.
+func WithAuth(auth config.AuthConfig) Option {
+	return func(o *options) { o.auth = auth }
+}
+
+// WithPolicy устанавливает политику авторизации.
+// Блок-листы и режим "только контакты" требуют WithJetStream.
+func WithPolicy(policy config.PolicyConfig) Option {
+	return func(o *options) { o.policy = policy }
+}
+
+// WithDrain включает завершение работы с GoAway: сервер ждёт отключения
+// клиентов не дольше timeout и предлагает им переподключиться к addr.
+func WithDrain(timeout time.Duration, addr string) Option {
+	return func(o *options) {
+		o.drainTimeout = timeout
+		o.drainAddr = addr
+	}
+}
+
 // Start запускает тестовое окружение: NATS контейнер + Sprut сервер.
 func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 	o := defaultOptions()
@@ -118,13 +188,40 @@ func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 	addr := lis.Addr().String()
 	host, port, _ := net.SplitHostPort(addr)
 
+	var wsAddr string
+	if o.webSocket {
+		wsAddr, err = freeAddr()
+		if err != nil {
+			lis.Close()
+			certs.Cleanup()
+			nats.Terminate(ctx)
+			return nil, fmt.Errorf("pick websocket port: %w", err)
+		}
+	}
+
+	var quicAddr string
+	if o.quic {
+		quicAddr, err = freeUDPAddr()
+		if err != nil {
+			lis.Close()
+			certs.Cleanup()
+			nats.Terminate(ctx)
+			return nil, fmt.Errorf("pick quic port: %w", err)
+		}
+	}
+
 	// 4. Конфигурация Sprut
 	ready := make(chan struct{})
 	cfg := &config.Config{
 		Server: config.ServerConfig{
-			Host:     host,
-			Port:     mustAtoi(port),
-			ServerID: o.serverID,
+			Host:         host,
+			Port:         mustAtoi(port),
+			ServerID:     o.serverID,
+			DrainTimeout: o.drainTimeout,
+			DrainAddr:    o.drainAddr,
+			WebSocket:    config.WebSocketConfig{Addr: wsAddr},
+			QUIC:         config.QUICConfig{Addr: quicAddr},
+			Listeners:    o.listeners,
 		},
 		TLS: config.TLSConfig{
 			CertFile: certs.CertFile,
@@ -134,7 +231,18 @@ func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 			URLs:          []string{nats.URL()},
 			ReconnectWait: time.Second,
 			MaxReconnects: 5,
+			JetStream: config.JetStreamConfig{
+				Enabled:              o.jetStream,
+				Stream:               "SPRUT_MSG",
+				MaxMsgsPerRecipient:  1000,
+				MaxBytesPerRecipient: 10 << 20,
+				MaxAge:               time.Hour,
+				Replicas:             1,
+				GroupsBucket:         "SPRUT_GROUPS",
+			},
 		},
+		Auth:   o.auth,
+		Policy: o.policy,
 		Limits: config.LimitsConfig{
 			MaxConnections:  o.maxConnections,
 			MaxMessageSize:  o.maxMessageSize,
@@ -142,6 +250,9 @@ func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 			RateLimitBurst:  o.rateLimitBurst,
 			AuthTimeout:     o.authTimeout,
 			ChallengeTTL:    o.challengeTTL,
+			IdleTimeout:     o.idleTimeout,
+			OverflowPolicy:  o.overflowPolicy,
+			RateLimitMode:   o.rateLimitMode,
 		},
 		Ready: ready,
 	}
@@ -170,7 +281,7 @@ func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 		return nil, fmt.Errorf("server start timeout")
 	}
 
-	return &Environment{
+	env := &Environment{
 		NATSUrl:   nats.URL(),
 		SprutAddr: addr,
 		CACert:    certs.CACert,
@@ -179,7 +290,28 @@ func Start(ctx context.Context, opts ...Option) (*Environment, error) {
 		listener:  lis,
 		cancelCtx: cancelCtx,
 		serverErr: serverErr,
-	}, nil
+	}
+	if wsAddr != "" {
+		env.WebSocketURL = "wss://" + wsAddr + "/"
+	}
+	if quicAddr != "" {
+		env.QUICAddr = "quic://" + quicAddr
+	}
+	return env, nil
+}
+
+// Shutdown останавливает Sprut сервер и ждёт его завершения не дольше timeout.
+// NATS продолжает работать до Close.
+func (e *Environment) Shutdown(timeout time.Duration) error {
+	e.cancelCtx()
+
+	select {
+	case err := <-e.serverErr:
+		e.serverErr = nil
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("server shutdown timeout")
+	}
 }
 
 // Close останавливает тестовое окружение.
@@ -189,10 +321,12 @@ func (e *Environment) Close(ctx context.Context) error {
 		e.cancelCtx()
 	}
 
-	// Ждём завершения сервера
-	select {
-	case <-e.serverErr:
-	case <-time.After(5 * time.Second):
+	// Ждём завершения сервера, если его не остановил Shutdown
+	if e.serverErr != nil {
+		select {
+		case <-e.serverErr:
+		case <-time.After(5 * time.Second):
+		}
 	}
 
 	// Очищаем ресурсы
@@ -218,16 +352,41 @@ func (e *Environment) Close(ctx context.Context) error {
 
 // NewClient создаёт клиент Sprut с указанными ключами.
 // Клиент подключён и готов к использованию.
+// Дополнительные опции применяются после опций по умолчанию.
 // Вызывающий код отвечает за закрытие канала отправки.
-func (e *Environment) NewClient(ctx context.Context, keys *identity.KeyPair) (*Client, error) {
+func (e *Environment) NewClient(ctx context.Context, keys *identity.KeyPair, opts ...client.ConnectOption) (*Client, error) {
 	send := make(chan client.OutgoingMessage, 10)
+	acks := make(chan client.SendResult, 100)
+	receipts := make(chan client.Receipt, 100)
+	errs := make(chan error, 100)
 
-	recv, err := client.Connect(e.SprutAddr, send,
+	defaults := []client.ConnectOption{
 		client.WithKeys(keys),
+		client.WithOnAck(func(r client.SendResult) {
+			// Не блокируем читающую горутину клиента
+			select {
+			case acks <- r:
+			default:
+			}
+		}),
+		client.WithOnReceipt(func(r client.Receipt) {
+			select {
+			case receipts <- r:
+			default:
+			}
+		}),
+		client.WithOnError(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
 		client.WithInsecureSkipVerify(),
-		client.WithDialTimeout(10*time.Second),
-		client.WithWriteTimeout(10*time.Second),
-	)
+		client.WithDialTimeout(10 * time.Second),
+		client.WithWriteTimeout(10 * time.Second),
+	}
+
+	recv, err := client.Connect(e.SprutAddr, send, append(defaults, opts...)...)
 	if err != nil {
 		close(send)
 		return nil, fmt.Errorf("connect to Sprut: %w", err)
@@ -236,6 +395,9 @@ func (e *Environment) NewClient(ctx context.Context, keys *identity.KeyPair) (*C
 	return &Client{
 		send:      send,
 		recv:      recv,
+		acks:      acks,
+		receipts:  receipts,
+		errs:      errs,
 		pubKeyHex: keys.PublicKeyHex(),
 	}, nil
 }
@@ -244,6 +406,9 @@ func (e *Environment) NewClient(ctx context.Context, keys *identity.KeyPair) (*C
 type Client struct {
 	send      chan client.OutgoingMessage
 	recv      <-chan *message.Message
+	acks      chan client.SendResult
+	receipts  chan client.Receipt
+	errs      chan error
 	pubKeyHex string
 }
 
@@ -257,6 +422,24 @@ func (c *Client) Recv() <-chan *message.Message {
 	return c.recv
 }
 
+// Acks возвращает канал подтверждений отправленных сообщений.
+// Буфер ограничен: при переполнении подтверждения отбрасываются.
+func (c *Client) Acks() <-chan client.SendResult {
+	return c.acks
+}
+
+// Receipts возвращает канал квитанций о доставке от получателей.
+// Буфер ограничен: при переполнении квитанции отбрасываются.
+func (c *Client) Receipts() <-chan client.Receipt {
+	return c.receipts
+}
+
+// Errors возвращает канал ошибок соединения, включая ошибки от сервера.
+// Буфер ограничен: при переполнении ошибки отбрасываются.
+func (c *Client) Errors() <-chan error {
+	return c.errs
+}
+
 // PubKeyHex возвращает hex-представление публичного ключа клиента.
 func (c *Client) PubKeyHex() string {
 	return c.pubKeyHex
@@ -276,6 +459,32 @@ func (c *Client) SendMessage(to, msgID string, payload []byte) {
 	}
 }
 
+// freeAddr возвращает свободный адрес на loopback для listener, который сервер открывает сам.
+func freeAddr() (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	addr := lis.Addr().String()
+	if err := lis.Close(); err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// freeUDPAddr возвращает свободный UDP адрес на loopback.
+func freeUDPAddr() (string, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	addr := conn.LocalAddr().String()
+	if err := conn.Close(); err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
 func mustAtoi(s string) int {
 	var n int
 	for _, c := range s {
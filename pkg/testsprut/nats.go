@@ -15,12 +15,13 @@ type natsContainer struct {
 	url       string
 }
 
-// startNATS запускает NATS контейнер.
+// startNATS запускает NATS контейнер с включённым JetStream.
 func startNATS(ctx context.Context) (*natsContainer, error) {
 	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
 		ContainerRequest: testcontainers.ContainerRequest{
 			Image:        "nats:latest",
 			ExposedPorts: []string{"4222/tcp"},
+			Cmd:          []string{"-js"},
 			WaitingFor:   wait.ForListeningPort("4222/tcp").WithStartupTimeout(30 * time.Second),
 		},
 		Started: true,
@@ -0,0 +1,658 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// QUICProtocol — ALPN протокола поверх QUIC.
+const QUICProtocol = "sprut"
+
+// Параметры QUIC соединений.
+const (
+	// Пинги QUIC держат NAT binding мобильного клиента, пока приложение молчит.
+	quicMaxIdleTimeout  = time.Minute
+	quicKeepAlivePeriod = 20 * time.Second
+	// quicMaxUniStreams — одновременно открытых потоков сообщений от собеседника.
+	quicMaxUniStreams = 1000
+	// quicCloseLinger — сколько после Close ждать, пока собеседник дочитает
+	// отправленное и закроет соединение сам.
+	quicCloseLinger = time.Second
+	// quicReadBuffer — сколько байт сообщений соединение держит прочитанными
+	// из потоков, но ещё не отданными Read. Сообщение большего размера (только
+	// если max_message_size поднят выше) читается прямо из потока.
+	quicReadBuffer = 4 << 20
+)
+
+// errQUICClosed — код закрытия соединения без ошибки.
+const errQUICClosed quic.ApplicationErrorCode = 0
+
+func quicConfig() *quic.Config {
+	return &quic.Config{
+		MaxIdleTimeout:        quicMaxIdleTimeout,
+		KeepAlivePeriod:       quicKeepAlivePeriod,
+		MaxIncomingUniStreams: quicMaxUniStreams,
+	}
+}
+
+// streamMessage сообщает, передаётся ли кадр в отдельном потоке QUIC.
+// Сообщения друг друга не задерживают; рукопожатие и служебные кадры,
+// порядок которых важен, идут по управляющему потоку.
+func streamMessage(frameType byte) bool {
+	switch frameType {
+	case protocol.TypeClientMessage, protocol.TypeServerMessage, protocol.TypeClientGroupMessage:
+		return true
+	default:
+		return false
+	}
+}
+
+// QUICConn передаёт сообщения протокола поверх QUIC: рукопожатие и служебные
+// кадры — по первому двунаправленному (управляющему) потоку, каждое сообщение —
+// в отдельном однонаправленном потоке, так что потеря пакета одного сообщения
+// не задерживает остальные. Для Peer и клиента это обычный поток кадров.
+// Channel binding берётся из TLS exporter QUIC соединения.
+type QUICConn struct {
+	conn *quic.Conn
+	ctrl *quic.Stream
+
+	// segs — входящие сообщения: из потоков — в порядке получения целиком,
+	// из управляющего потока — по порядку.
+	segs chan *segment
+	cur  *segment
+	// buffered ограничивает объём полученных, но не прочитанных сообщений.
+	buffered *budget
+
+	readDeadline  *deadline
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	pending       []byte // начало исходящего сообщения, ещё не переданного целиком
+
+	errMu   sync.Mutex
+	readErr error         // причина завершения управляющего потока
+	peerEOF chan struct{} // закрыт, когда управляющий поток собеседника завершён
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// segment — одно входящее сообщение: читается ровно size байт.
+type segment struct {
+	r      io.Reader
+	stream *quic.ReceiveStream // поток, из которого читается r; nil — сообщение уже в памяти
+	ctrl   bool                // сообщение управляющего потока
+	size   int                 // байт бюджета buffered, занятых сообщением
+	done   chan struct{}       // закрыт, когда сообщение прочитано целиком
+}
+
+func newQUICConn(conn *quic.Conn, ctrl *quic.Stream) *QUICConn {
+	c := &QUICConn{
+		conn:         conn,
+		ctrl:         ctrl,
+		segs:         make(chan *segment, quicMaxUniStreams),
+		buffered:     newBudget(quicReadBuffer),
+		readDeadline: newDeadline(),
+		peerEOF:      make(chan struct{}),
+		closed:       make(chan struct{}),
+	}
+	go c.readControl()
+	go c.acceptStreams()
+	return c
+}
+
+// readControl разбивает управляющий поток на сообщения. Следующее сообщение
+// читается после того, как предыдущее дочитано через Read.
+func (c *QUICConn) readControl() {
+	defer close(c.peerEOF)
+
+	var prefix [protocol.FrameHeaderSize]byte
+	for {
+		size, n, err := readPrefix(c.ctrl, prefix[:])
+		if err != nil {
+			c.setReadErr(err)
+			break
+		}
+
+		seg := &segment{
+			r:    io.MultiReader(bytes.NewReader(prefix[:n]), exactReader(c.ctrl, size-n)),
+			ctrl: true,
+			done: make(chan struct{}),
+		}
+		if !c.push(seg) {
+			break
+		}
+		select {
+		case <-seg.done:
+		case <-c.closed:
+		}
+		if c.isClosed() {
+			break
+		}
+	}
+
+	// После Close дочитываем поток, чтобы увидеть, что собеседник закрыл свою сторону
+	if c.isClosed() {
+		_, _ = io.Copy(io.Discard, c.ctrl)
+	}
+}
+
+// acceptStreams принимает потоки сообщений собеседника.
+func (c *QUICConn) acceptStreams() {
+	for {
+		stream, err := c.conn.AcceptUniStream(context.Background())
+		if err != nil {
+			return
+		}
+		go c.readStream(stream)
+	}
+}
+
+// readStream читает сообщение из потока целиком и ставит его в очередь: сообщение,
+// пакет которого потерян, не задерживает пришедшие после него.
+func (c *QUICConn) readStream(stream *quic.ReceiveStream) {
+	var header [protocol.FrameHeaderSize]byte
+	if _, err := io.ReadFull(stream, header[:]); err != nil {
+		stream.CancelRead(0)
+		return
+	}
+	size, err := protocol.MessageSize(header[:])
+	if err != nil || !streamMessage(header[0]) {
+		// Поток сообщения несёт только кадр сообщения — остальное игнорируем
+		stream.CancelRead(0)
+		return
+	}
+
+	if size > quicReadBuffer {
+		// Получатель отвергнет такое сообщение по своему лимиту, прочитав заголовок
+		seg := &segment{
+			r:      io.MultiReader(bytes.NewReader(header[:]), exactReader(stream, size-len(header))),
+			stream: stream,
+			done:   make(chan struct{}),
+		}
+		if !c.push(seg) {
+			stream.CancelRead(0)
+		}
+		return
+	}
+
+	if !c.buffered.acquire(size, c.closed) {
+		stream.CancelRead(0)
+		return
+	}
+	msg := make([]byte, size)
+	copy(msg, header[:])
+	_, err = io.ReadFull(stream, msg[len(header):])
+	// Всё сообщение прочитано: остаток потока не нужен
+	stream.CancelRead(0)
+	if err != nil {
+		c.buffered.release(size)
+		return
+	}
+
+	seg := &segment{r: bytes.NewReader(msg), size: size, done: make(chan struct{})}
+	if !c.push(seg) {
+		c.buffered.release(size)
+	}
+}
+
+// push передаёт сообщение читателю; false — соединение закрыто.
+func (c *QUICConn) push(seg *segment) bool {
+	select {
+	case c.segs <- seg:
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// readPrefix читает начало сообщения, достаточное для определения его размера.
+func readPrefix(r io.Reader, buf []byte) (size, n int, err error) {
+	for n < len(buf) {
+		size, err = protocol.MessageSize(buf[:n])
+		if err != nil {
+			return 0, 0, err
+		}
+		if size != 0 {
+			return size, n, nil
+		}
+		if _, err := io.ReadFull(r, buf[n:n+1]); err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, 0, err
+		}
+		n++
+	}
+	size, err = protocol.MessageSize(buf)
+	return size, n, err
+}
+
+// Read читает входящие сообщения как поток байт.
+func (c *QUICConn) Read(p []byte) (int, error) {
+	for {
+		if c.cur == nil {
+			select {
+			case seg := <-c.segs:
+				c.cur = seg
+			case <-c.peerEOF:
+				// Сообщения, пришедшие до завершения управляющего потока, дочитываются
+				select {
+				case seg := <-c.segs:
+					c.cur = seg
+				default:
+					return 0, c.getReadErr()
+				}
+			case <-c.closed:
+				return 0, net.ErrClosed
+			case <-c.readDeadline.wait():
+				return 0, os.ErrDeadlineExceeded
+			}
+		}
+
+		switch {
+		case c.cur.stream != nil:
+			_ = c.cur.stream.SetReadDeadline(c.readDeadline.get())
+		case c.cur.ctrl:
+			_ = c.ctrl.SetReadDeadline(c.readDeadline.get())
+		}
+
+		n, err := c.cur.r.Read(p)
+		if errors.Is(err, io.EOF) {
+			switch {
+			case c.cur.stream != nil:
+				// Всё сообщение прочитано: остаток потока не нужен
+				c.cur.stream.CancelRead(0)
+			case c.cur.ctrl:
+				// Заголовок следующего сообщения readControl читает без дедлайна
+				_ = c.ctrl.SetReadDeadline(time.Time{})
+			default:
+				c.buffered.release(c.cur.size)
+			}
+			close(c.cur.done)
+			c.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Write накапливает байты и отправляет каждое завершённое сообщение протокола:
+// кадры сообщений — в новом потоке, остальное — по управляющему потоку.
+func (c *QUICConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.pending = append(c.pending, p...)
+	for {
+		size, err := protocol.MessageSize(c.pending)
+		if err != nil {
+			c.pending = c.pending[:0]
+			return 0, fmt.Errorf("quic write: %w", err)
+		}
+		if size == 0 || len(c.pending) < size {
+			return len(p), nil
+		}
+		if err := c.writeMessage(c.pending[:size]); err != nil {
+			return 0, err
+		}
+		c.pending = append(c.pending[:0], c.pending[size:]...)
+	}
+}
+
+func (c *QUICConn) writeMessage(msg []byte) error {
+	if !streamMessage(msg[0]) {
+		if err := c.ctrl.SetWriteDeadline(c.writeDeadline); err != nil {
+			return err
+		}
+		_, err := c.ctrl.Write(msg)
+		return err
+	}
+
+	ctx := c.conn.Context()
+	if !c.writeDeadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.writeDeadline)
+		defer cancel()
+	}
+	stream, err := c.conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("open stream: %w", os.ErrDeadlineExceeded)
+		}
+		return fmt.Errorf("open stream: %w", err)
+	}
+	if err := stream.SetWriteDeadline(c.writeDeadline); err != nil {
+		return err
+	}
+	if _, err := stream.Write(msg); err != nil {
+		return err
+	}
+	return stream.Close()
+}
+
+// Close закрывает соединение. Собеседнику даётся до quicCloseLinger,
+// чтобы дочитать отправленное и закрыть соединение со своей стороны.
+func (c *QUICConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.ctrl.Close()
+		go func() {
+			timer := time.NewTimer(quicCloseLinger)
+			defer timer.Stop()
+			select {
+			case <-c.conn.Context().Done():
+			case <-c.peerEOF:
+			case <-timer.C:
+			}
+			_ = c.conn.CloseWithError(errQUICClosed, "")
+		}()
+	})
+	return nil
+}
+
+func (c *QUICConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *QUICConn) setReadErr(err error) {
+	// Штатное закрытие собеседником — конец потока, как у TCP
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.ErrorCode == errQUICClosed {
+		err = io.EOF
+	}
+	c.errMu.Lock()
+	c.readErr = err
+	c.errMu.Unlock()
+}
+
+func (c *QUICConn) getReadErr() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	return c.readErr
+}
+
+// ConnectionState возвращает состояние TLS рукопожатия QUIC соединения.
+func (c *QUICConn) ConnectionState() tls.ConnectionState {
+	return c.conn.ConnectionState().TLS
+}
+
+// LocalAddr возвращает локальный UDP адрес.
+func (c *QUICConn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
+
+// RemoteAddr возвращает текущий UDP адрес собеседника (меняется при миграции).
+func (c *QUICConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetDeadline устанавливает дедлайны чтения и записи.
+func (c *QUICConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline устанавливает дедлайн чтения.
+func (c *QUICConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline устанавливает дедлайн записи; действует на следующие Write.
+func (c *QUICConn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	c.writeDeadline = t
+	c.writeMu.Unlock()
+	return nil
+}
+
+// budget — счётчик байт с ожиданием освобождения (взвешенный семафор).
+type budget struct {
+	mu    sync.Mutex
+	free  int
+	freed chan struct{} // закрывается и заменяется при каждом release
+}
+
+func newBudget(size int) *budget {
+	return &budget{free: size, freed: make(chan struct{})}
+}
+
+// acquire занимает n байт, дожидаясь освобождения; false — закрыт done.
+func (b *budget) acquire(n int, done <-chan struct{}) bool {
+	for {
+		b.mu.Lock()
+		if b.free >= n {
+			b.free -= n
+			b.mu.Unlock()
+			return true
+		}
+		freed := b.freed
+		b.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// release возвращает n байт.
+func (b *budget) release(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.free += n
+	close(b.freed)
+	b.freed = make(chan struct{})
+}
+
+// QUICListener принимает QUIC соединения, открывшие управляющий поток.
+type QUICListener struct {
+	ln    *quic.Listener
+	conns chan *QUICConn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// ListenQUIC запускает QUIC listener на UDP сокете conn. Сокет не закрывается
+// вместе с listener: он нужен принятым соединениям, пока они не завершатся.
+// Соединение, не открывшее управляющий поток за handshakeTimeout, закрывается.
+func ListenQUIC(conn net.PacketConn, tlsConfig *tls.Config, handshakeTimeout time.Duration) (*QUICListener, error) {
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{QUICProtocol}
+
+	ln, err := quic.Listen(conn, tlsConfig, quicConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	l := &QUICListener{
+		ln:     ln,
+		conns:  make(chan *QUICConn),
+		closed: make(chan struct{}),
+	}
+	go l.acceptLoop(handshakeTimeout)
+	return l, nil
+}
+
+func (l *QUICListener) acceptLoop(handshakeTimeout time.Duration) {
+	for {
+		conn, err := l.ln.Accept(context.Background())
+		if err != nil {
+			_ = l.Close()
+			return
+		}
+		go l.acceptControl(conn, handshakeTimeout)
+	}
+}
+
+// acceptControl ждёт управляющий поток: клиент открывает его первым сообщением рукопожатия.
+func (l *QUICListener) acceptControl(conn *quic.Conn, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(conn.Context(), timeout)
+	defer cancel()
+
+	ctrl, err := conn.AcceptStream(ctx)
+	if err != nil {
+		_ = conn.CloseWithError(errQUICClosed, "no control stream")
+		return
+	}
+
+	select {
+	case l.conns <- newQUICConn(conn, ctrl):
+	case <-l.closed:
+		_ = conn.CloseWithError(errQUICClosed, "server closed")
+	}
+}
+
+// Accept возвращает следующее соединение (*QUICConn).
+func (l *QUICListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close перестаёт принимать соединения. Принятые соединения продолжают работать.
+func (l *QUICListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		err = l.ln.Close()
+	})
+	return err
+}
+
+// Addr возвращает UDP адрес listener.
+func (l *QUICListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// DialQUIC подключается к QUIC listener сервера и открывает управляющий поток.
+func DialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config) (*QUICConn, error) {
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{QUICProtocol}
+
+	conn, err := quic.DialAddr(ctx, addr, tlsConfig, quicConfig())
+	if err != nil {
+		return nil, fmt.Errorf("quic dial: %w", err)
+	}
+	ctrl, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		_ = conn.CloseWithError(errQUICClosed, "")
+		return nil, fmt.Errorf("open control stream: %w", err)
+	}
+	return newQUICConn(conn, ctrl), nil
+}
+
+// exactReader читает ровно n байт из r: поток, оборвавшийся раньше,
+// — io.ErrUnexpectedEOF, а не конец сообщения.
+func exactReader(r io.Reader, n int) io.Reader {
+	return &exact{r: r, n: n}
+}
+
+type exact struct {
+	r io.Reader
+	n int
+}
+
+func (e *exact) Read(p []byte) (int, error) {
+	if e.n <= 0 {
+		return 0, io.EOF
+	}
+	if len(p) > e.n {
+		p = p[:e.n]
+	}
+	n, err := e.r.Read(p)
+	e.n -= n
+	if errors.Is(err, io.EOF) {
+		if e.n > 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// deadline — дедлайн, прерывающий ожидание (как у net.Pipe).
+type deadline struct {
+	mu     sync.Mutex
+	t      time.Time
+	timer  *time.Timer
+	cancel chan struct{} // закрыт, когда дедлайн истёк
+}
+
+func newDeadline() *deadline {
+	return &deadline{cancel: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel // таймер уже сработал: ждём закрытия cancel
+	}
+	d.timer = nil
+	d.t = t
+
+	expired := isClosed(d.cancel)
+	if t.IsZero() {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+	if dur := time.Until(t); dur > 0 {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+		return
+	}
+	if !expired {
+		close(d.cancel)
+	}
+}
+
+func (d *deadline) get() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.t
+}
+
+func (d *deadline) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,207 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// startQUIC запускает QUIC listener на loopback и подключается к нему.
+func startQUIC(t *testing.T) (client, server *QUICConn) {
+	t.Helper()
+
+	// Сертификат тестового HTTPS сервера подходит и для QUIC
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	cert := srv.TLS.Certificates[0]
+	srv.Close()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+
+	ln, err := ListenQUIC(pc, &tls.Config{Certificates: []tls.Certificate{cert}}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err = DialQUIC(ctx, ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	// Управляющий поток появляется у сервера с первыми байтами клиента
+	go func() {
+		_ = (&protocol.ClientHello{}).Encode(client)
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	server = conn.(*QUICConn)
+	t.Cleanup(func() { _ = server.Close() })
+
+	if typ, err := protocol.ReadMessageType(server); err != nil || typ != protocol.TypeClientHello {
+		t.Fatalf("read hello: got %d, %v", typ, err)
+	}
+	if _, err := protocol.DecodeClientHello(server); err != nil {
+		t.Fatalf("decode hello: %v", err)
+	}
+	return client, server
+}
+
+func TestQUICChannelBinding(t *testing.T) {
+	client, server := startQUIC(t)
+
+	clientBinding, err := protocol.GetChannelBinding(client.ConnectionState())
+	if err != nil {
+		t.Fatalf("client binding: %v", err)
+	}
+	serverBinding, err := protocol.GetChannelBinding(server.ConnectionState())
+	if err != nil {
+		t.Fatalf("server binding: %v", err)
+	}
+	if clientBinding != serverBinding {
+		t.Error("channel binding differs between client and server")
+	}
+	if proto := server.ConnectionState().NegotiatedProtocol; proto != QUICProtocol {
+		t.Errorf("ALPN: got %q, want %q", proto, QUICProtocol)
+	}
+}
+
+func TestQUICMessages(t *testing.T) {
+	client, server := startQUIC(t)
+
+	// Сообщения идут в отдельных потоках, ping — по управляющему
+	const count = 50
+	go func() {
+		for i := range count {
+			_ = (&protocol.ServerMessage{Data: []byte{byte(i)}}).Encode(server)
+		}
+		_ = (&protocol.ServerPong{Seq: 7}).Encode(server)
+	}()
+
+	seen := make(map[byte]bool)
+	var pong bool
+	for len(seen) < count || !pong {
+		typ, err := protocol.ReadMessageType(client)
+		if err != nil {
+			t.Fatalf("read type: %v", err)
+		}
+		switch typ {
+		case protocol.TypeServerMessage:
+			msg, err := protocol.DecodeServerMessage(client)
+			if err != nil {
+				t.Fatalf("decode message: %v", err)
+			}
+			seen[msg.Data[0]] = true
+		case protocol.TypeServerPong:
+			p, err := protocol.DecodeServerPong(client)
+			if err != nil {
+				t.Fatalf("decode pong: %v", err)
+			}
+			if p.Seq != 7 {
+				t.Errorf("pong seq: got %d, want 7", p.Seq)
+			}
+			pong = true
+		default:
+			t.Fatalf("unexpected type %#x", typ)
+		}
+	}
+
+	// Закрытие сервером — конец потока у клиента
+	if err := server.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var buf [1]byte
+	if _, err := client.Read(buf[:]); !errors.Is(err, io.EOF) {
+		t.Errorf("read after close: got %v, want io.EOF", err)
+	}
+}
+
+func TestQUICReadDeadline(t *testing.T) {
+	_, server := startQUIC(t)
+
+	if err := server.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+	var buf [1]byte
+	if _, err := server.Read(buf[:]); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("read after deadline: got %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	// Снятый дедлайн снова позволяет читать
+	if err := server.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("reset deadline: %v", err)
+	}
+}
+
+func TestQUICDelayedMessageDoesNotBlock(t *testing.T) {
+	client, server := startQUIC(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	recipient := strings.Repeat("ab", protocol.PublicKeySize)
+
+	// Первое сообщение приходит без конца тела — как при потере его пакета
+	var first bytes.Buffer
+	if err := (&protocol.ClientMessage{To: recipient, MsgID: "first", Payload: []byte("delayed")}).Encode(&first); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	delayed, err := client.conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	if _, err := delayed.Write(first.Bytes()[:first.Len()-3]); err != nil {
+		t.Fatalf("write head: %v", err)
+	}
+
+	// Следующее сообщение доходит целиком и читается первым
+	time.Sleep(50 * time.Millisecond)
+	if err := (&protocol.ClientMessage{To: recipient, MsgID: "second", Payload: []byte("on time")}).Encode(client); err != nil {
+		t.Fatalf("write second: %v", err)
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	readTo := func() string {
+		t.Helper()
+		typ, err := protocol.ReadMessageType(server)
+		if err != nil || typ != protocol.TypeClientMessage {
+			t.Fatalf("read type: got %d, %v", typ, err)
+		}
+		msg, err := protocol.DecodeClientMessage(server)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return msg.MsgID
+	}
+	if to := readTo(); to != "second" {
+		t.Fatalf("first read message %q, want %q", to, "second")
+	}
+
+	if _, err := delayed.Write(first.Bytes()[first.Len()-3:]); err != nil {
+		t.Fatalf("write tail: %v", err)
+	}
+	_ = delayed.Close()
+	if to := readTo(); to != "first" {
+		t.Errorf("second read message %q, want %q", to, "first")
+	}
+}
@@ -0,0 +1,158 @@
+// Package transport приводит альтернативные транспорты к net.Conn,
+// с которым работают роутер и клиент: то же рукопожатие и те же кадры
+// pkg/protocol, что и поверх TLS.
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// Conn — соединение поверх TLS. ConnectionState нужен для channel binding
+// и аутентификации по клиентскому сертификату.
+type Conn interface {
+	net.Conn
+	ConnectionState() tls.ConnectionState
+}
+
+// WebSocketConn передаёт сообщения протокола бинарными сообщениями WebSocket:
+// каждое сообщение рукопожатия и каждый кадр — отдельное сообщение WebSocket,
+// как бы Encode ни делил его на вызовы Write. Входящие сообщения читаются
+// как поток, поэтому границы сообщений у собеседника не проверяются.
+type WebSocketConn struct {
+	net.Conn
+	state tls.ConnectionState
+
+	mu      sync.Mutex
+	pending []byte // начало сообщения, ещё не переданного целиком
+
+	deadlineMu   sync.Mutex
+	readDeadline time.Time
+}
+
+// NewWebSocketConn оборачивает установленное WebSocket соединение.
+// state — состояние TLS соединения, поверх которого работает WebSocket.
+func NewWebSocketConn(ws *websocket.Conn, state tls.ConnectionState) *WebSocketConn {
+	return &WebSocketConn{
+		Conn:  websocket.NetConn(context.Background(), ws, websocket.MessageBinary),
+		state: state,
+	}
+}
+
+// ConnectionState возвращает состояние TLS соединения под WebSocket.
+func (c *WebSocketConn) ConnectionState() tls.ConnectionState {
+	return c.state
+}
+
+// Write накапливает байты и отправляет каждое завершённое сообщение протокола
+// отдельным сообщением WebSocket.
+func (c *WebSocketConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(c.pending, p...)
+	for {
+		size, err := protocol.MessageSize(c.pending)
+		if err != nil {
+			c.pending = c.pending[:0]
+			return 0, fmt.Errorf("websocket write: %w", err)
+		}
+		if size == 0 || len(c.pending) < size {
+			return len(p), nil
+		}
+		if _, err := c.Conn.Write(c.pending[:size]); err != nil {
+			return 0, err
+		}
+		c.pending = append(c.pending[:0], c.pending[size:]...)
+	}
+}
+
+// Read читает входящие сообщения как поток байт.
+// Истёкший дедлайн чтения сообщается как os.ErrDeadlineExceeded,
+// как у TCP соединения.
+func (c *WebSocketConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err != nil && c.deadlineExceeded(err) {
+		return n, fmt.Errorf("%w: %w", os.ErrDeadlineExceeded, err)
+	}
+	return n, err
+}
+
+// SetReadDeadline устанавливает дедлайн чтения.
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	c.readDeadline = t
+	c.deadlineMu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetDeadline устанавливает дедлайны чтения и записи.
+func (c *WebSocketConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// deadlineExceeded сообщает, вызвана ли ошибка чтения истёкшим дедлайном:
+// WebSocket прерывает чтение отменой контекста.
+func (c *WebSocketConn) deadlineExceeded(err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return false
+	}
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+	return !c.readDeadline.IsZero() && !time.Now().Before(c.readDeadline)
+}
+
+// DialWebSocket подключается к серверу по URL вида wss://host:port/path.
+// tlsConfig и dialer используются для TLS соединения под WebSocket.
+func DialWebSocket(ctx context.Context, url string, dialer *net.Dialer, tlsConfig *tls.Config) (*WebSocketConn, error) {
+	// WebSocket работает поверх HTTP/1.1
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"http/1.1"}
+
+	var (
+		tlsMu   sync.Mutex
+		tlsConn *tls.Conn
+	)
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				d := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+				conn, err := d.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				tlsMu.Lock()
+				tlsConn = conn.(*tls.Conn)
+				tlsMu.Unlock()
+				return conn, nil
+			},
+		},
+	}
+
+	ws, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPClient: httpClient})
+	if err != nil {
+		return nil, fmt.Errorf("websocket dial: %w", err)
+	}
+
+	tlsMu.Lock()
+	defer tlsMu.Unlock()
+	if tlsConn == nil {
+		_ = ws.CloseNow()
+		return nil, fmt.Errorf("websocket dial: %s is not a wss:// URL", url)
+	}
+	return NewWebSocketConn(ws, tlsConn.ConnectionState()), nil
+}
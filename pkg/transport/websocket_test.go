@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/udisondev/sprut/pkg/protocol"
+)
+
+// startServer запускает HTTPS сервер, передающий принятые WebSocket соединения в conns.
+func startServer(t *testing.T) (string, <-chan *WebSocketConn) {
+	t.Helper()
+
+	conns := make(chan *WebSocketConn, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("accept: %v", err)
+			return
+		}
+		conns <- NewWebSocketConn(ws, *r.TLS)
+	}))
+	t.Cleanup(srv.Close)
+
+	return "wss://" + strings.TrimPrefix(srv.URL, "https://"), conns
+}
+
+func TestWebSocketMessagePerFrame(t *testing.T) {
+	url, conns := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	ws, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPClient: httpClient})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	server := <-conns
+	defer server.Close()
+	defer ws.CloseNow()
+
+	// ServerChallenge пишется несколькими Write, ServerMessage — двумя
+	challenge := &protocol.ServerChallenge{Timestamp: 42}
+	msg := &protocol.ServerMessage{Data: []byte("hello")}
+	go func() {
+		_ = challenge.Encode(server)
+		_ = msg.Encode(server)
+	}()
+
+	for _, want := range []int{73, protocol.FrameHeaderSize + 5} {
+		typ, data, err := ws.Read(ctx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if typ != websocket.MessageBinary {
+			t.Errorf("message type: got %v, want binary", typ)
+		}
+		if len(data) != want {
+			t.Errorf("message size: got %d, want %d", len(data), want)
+		}
+	}
+}
+
+func TestDialWebSocket(t *testing.T) {
+	url, conns := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialWebSocket(ctx, url, &net.Dialer{}, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	server := <-conns
+	defer server.Close()
+	defer client.Close()
+
+	// Channel binding одинаков по обе стороны WebSocket
+	clientBinding, err := protocol.GetChannelBinding(client.ConnectionState())
+	if err != nil {
+		t.Fatalf("client binding: %v", err)
+	}
+	serverBinding, err := protocol.GetChannelBinding(server.ConnectionState())
+	if err != nil {
+		t.Fatalf("server binding: %v", err)
+	}
+	if clientBinding != serverBinding {
+		t.Error("channel binding differs between client and server")
+	}
+
+	go func() {
+		_ = (&protocol.ClientPing{Seq: 1}).Encode(client)
+	}()
+	if typ, err := protocol.ReadMessageType(server); err != nil || typ != protocol.TypeClientPing {
+		t.Fatalf("read type: got %d, %v", typ, err)
+	}
+	ping, err := protocol.DecodeClientPing(server)
+	if err != nil {
+		t.Fatalf("decode ping: %v", err)
+	}
+	if ping.Seq != 1 {
+		t.Errorf("ping seq: got %d, want 1", ping.Seq)
+	}
+
+	// Истёкший дедлайн чтения — os.ErrDeadlineExceeded, как у TCP
+	if err := server.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+	var buf [1]byte
+	if _, err := server.Read(buf[:]); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("read after deadline: got %v, want os.ErrDeadlineExceeded", err)
+	}
+}
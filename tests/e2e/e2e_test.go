@@ -3,11 +3,21 @@ package e2e
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/udisondev/sprut/pkg/client"
+	"github.com/udisondev/sprut/pkg/config"
 	"github.com/udisondev/sprut/pkg/identity"
 	"github.com/udisondev/sprut/pkg/message"
 	"github.com/udisondev/sprut/pkg/testsprut"
@@ -100,6 +110,1198 @@ func TestMultipleClients(t *testing.T) {
 	}
 }
 
+// TestSendAck проверяет подтверждения сервера на исходящие сообщения.
+func TestSendAck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	// Валидный получатель — сообщение принято
+	alice.SendMessage(bobKeys.PublicKeyHex(), "msg-ok", []byte("hi"))
+	ack := waitAck(t, alice.Acks(), 10*time.Second)
+	require.Equal(t, "msg-ok", ack.MsgID)
+	require.True(t, ack.Accepted())
+
+	// Невалидный hex — получатель неизвестен, соединение не разрывается
+	alice.SendMessage(strings.Repeat("z", 64), "msg-bad", []byte("hi"))
+	ack = waitAck(t, alice.Acks(), 10*time.Second)
+	require.Equal(t, "msg-bad", ack.MsgID)
+	require.ErrorIs(t, ack.Err(), client.ErrRecipientUnknown)
+
+	alice.SendMessage(bobKeys.PublicKeyHex(), "msg-ok-2", []byte("still here"))
+	ack = waitAck(t, alice.Acks(), 10*time.Second)
+	require.Equal(t, "msg-ok-2", ack.MsgID)
+	require.True(t, ack.Accepted())
+}
+
+// TestDeliveryReceipt проверяет квитанцию о доставке от клиента получателя.
+func TestDeliveryReceipt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	alice.SendMessage(bob.PubKeyHex(), "msg-1", []byte("Hello Bob!"))
+	waitMsg(t, bob.Recv(), 10*time.Second)
+
+	select {
+	case r := <-alice.Receipts():
+		require.Equal(t, "msg-1", r.MsgID)
+		require.Equal(t, bob.PubKeyHex(), r.From)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for receipt")
+	}
+
+	// Квитанция не попадает в канал входящих сообщений
+	select {
+	case msg := <-alice.Recv():
+		t.Fatalf("unexpected message: %v", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+// TestOfflineDelivery проверяет доставку сообщений, отправленных пока получатель был offline.
+func TestOfflineDelivery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx, testsprut.WithJetStream())
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	// Bob ещё не подключался
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	for i := range 3 {
+		alice.SendMessage(bobKeys.PublicKeyHex(), fmt.Sprintf("msg-%d", i), []byte("offline"))
+	}
+
+	// Даём серверу сохранить сообщения
+	time.Sleep(500 * time.Millisecond)
+
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	for i := range 3 {
+		msg := waitMsg(t, bob.Recv(), 10*time.Second)
+		require.Equal(t, alice.PubKeyHex(), msg.From)
+		require.Equal(t, fmt.Sprintf("msg-%d", i), msg.Id)
+	}
+}
+
+// TestBacklogReceiptsWithinRateLimit проверяет, что квитанции на backlog,
+// который больше burst, не отключают получателя за превышение лимита.
+func TestBacklogReceiptsWithinRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx,
+		testsprut.WithJetStream(),
+		testsprut.WithRateLimit(5, 2),
+		testsprut.WithRateLimitMode(config.RateLimitModeDisconnect),
+	)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	// Bob ещё не подключался: сообщения копятся в JetStream.
+	// Alice отправляет их медленнее своего лимита.
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	const total = 10
+	for i := range total {
+		alice.SendMessage(bobKeys.PublicKeyHex(), fmt.Sprintf("msg-%d", i), []byte("backlog"))
+		require.NoError(t, waitAck(t, alice.Acks(), 10*time.Second).Err())
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	for i := range total {
+		msg := waitMsg(t, bob.Recv(), 10*time.Second)
+		require.Equal(t, fmt.Sprintf("msg-%d", i), msg.Id)
+	}
+	for range total {
+		select {
+		case <-alice.Receipts():
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for receipt")
+		}
+	}
+
+	select {
+	case err := <-bob.Errors():
+		t.Fatalf("recipient got error while draining backlog: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestSessionReplaced(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	keys, err := identity.Generate()
+	require.NoError(t, err)
+
+	// Повторное подключение с тем же ключом вытесняет первую сессию
+	first, err := env.NewClient(ctx, keys)
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := env.NewClient(ctx, keys)
+	require.NoError(t, err)
+	defer second.Close()
+
+	err = waitErr(t, first.Errors(), 10*time.Second)
+	require.ErrorIs(t, err, client.ErrSessionReplaced)
+	require.False(t, client.IsRetryable(err))
+}
+
+func TestReconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Жёсткий лимит: второе сообщение подряд разрывает соединение
+	env, err := testsprut.Start(ctx, testsprut.WithRateLimit(1, 1))
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	states := make(chan client.ConnState, 10)
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	alice, err := env.NewClient(ctx, aliceKeys,
+		client.WithReconnect(),
+		client.WithReconnectBackoff(50*time.Millisecond, time.Second),
+		client.WithOnStateChange(func(s client.ConnState) {
+			select {
+			case states <- s:
+			default:
+			}
+		}),
+	)
+	require.NoError(t, err)
+	defer alice.Close()
+	require.Equal(t, client.StateConnected, waitState(t, states, 10*time.Second))
+
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	for i := range 3 {
+		alice.SendMessage(bobKeys.PublicKeyHex(), fmt.Sprintf("burst-%d", i), []byte("burst"))
+	}
+
+	require.Equal(t, client.StateReconnecting, waitState(t, states, 10*time.Second))
+	require.Equal(t, client.StateConnected, waitState(t, states, 10*time.Second))
+
+	// После переподключения каналы клиента продолжают работать
+	alice.SendMessage(bobKeys.PublicKeyHex(), "after-reconnect", []byte("hello again"))
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case msg := <-bob.Recv():
+			if msg.Id == "after-reconnect" {
+				require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+				return
+			}
+		case <-deadline:
+			t.Fatal("timeout waiting for message after reconnect")
+		}
+	}
+}
+
+func TestClientAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	dial := func(keys *identity.KeyPair) *client.Client {
+		c, err := client.Dial(ctx, env.SprutAddr,
+			client.WithKeys(keys),
+			client.WithInsecureSkipVerify(),
+		)
+		require.NoError(t, err)
+		return c
+	}
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alice := dial(aliceKeys)
+	defer alice.Close()
+	bob := dial(bobKeys)
+
+	require.Equal(t, client.StateConnected, alice.State())
+	require.Equal(t, bobKeys.PublicKey, bob.PublicKey())
+
+	require.NoError(t, alice.Send(ctx, client.OutgoingMessage{
+		To:      bobKeys.PublicKeyHex(),
+		MsgID:   "msg-1",
+		Payload: []byte("hello"),
+	}))
+
+	recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer recvCancel()
+	msg, err := bob.Receive(recvCtx)
+	require.NoError(t, err)
+	require.Equal(t, "msg-1", msg.Id)
+	require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+
+	// Receive уважает контекст
+	shortCtx, shortCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer shortCancel()
+	_, err = bob.Receive(shortCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// После Close методы возвращают ErrClosed
+	require.NoError(t, bob.Close())
+	require.NoError(t, bob.Close())
+	require.Equal(t, client.StateClosed, bob.State())
+	require.NoError(t, bob.Err())
+	require.ErrorIs(t, bob.Send(ctx, client.OutgoingMessage{To: aliceKeys.PublicKeyHex()}), client.ErrClosed)
+	_, err = bob.Receive(ctx)
+	require.ErrorIs(t, err, client.ErrClosed)
+}
+
+func TestPresence(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	dial := func(keys *identity.KeyPair, presence chan client.Presence) *client.Client {
+		c, err := client.Dial(ctx, env.SprutAddr,
+			client.WithKeys(keys),
+			client.WithInsecureSkipVerify(),
+			client.WithOnPresence(func(p client.Presence) {
+				select {
+				case presence <- p:
+				default:
+				}
+			}),
+		)
+		require.NoError(t, err)
+		return c
+	}
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	carolKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alicePresence := make(chan client.Presence, 10)
+	carolPresence := make(chan client.Presence, 10)
+
+	alice := dial(aliceKeys, alicePresence)
+	defer alice.Close()
+	bob := dial(bobKeys, make(chan client.Presence, 10))
+	carol := dial(carolKeys, carolPresence)
+	defer carol.Close()
+
+	// Bob разрешает видеть свой статус только Alice
+	require.NoError(t, bob.AllowPresence(ctx, []string{aliceKeys.PublicKeyHex()}))
+	// Список обрабатывается сервером по порядку с сообщениями — дожидаемся его применения
+	time.Sleep(200 * time.Millisecond)
+
+	require.NoError(t, alice.WatchPresence(ctx, []string{bobKeys.PublicKeyHex()}))
+	p := waitPresence(t, alicePresence, 10*time.Second)
+	require.Equal(t, bobKeys.PublicKeyHex(), p.Key)
+	require.True(t, p.Online)
+
+	// Carol не разрешено — Bob для неё offline
+	require.NoError(t, carol.WatchPresence(ctx, []string{bobKeys.PublicKeyHex()}))
+	p = waitPresence(t, carolPresence, 10*time.Second)
+	require.Equal(t, bobKeys.PublicKeyHex(), p.Key)
+	require.False(t, p.Online)
+
+	// Отключение Bob видит только Alice
+	require.NoError(t, bob.Close())
+	p = waitPresence(t, alicePresence, 10*time.Second)
+	require.Equal(t, bobKeys.PublicKeyHex(), p.Key)
+	require.False(t, p.Online)
+
+	select {
+	case p := <-carolPresence:
+		t.Fatalf("unexpected presence for carol: %+v", p)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx, testsprut.WithJetStream())
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	dial := func(keys *identity.KeyPair, acks chan client.SendResult) *client.Client {
+		c, err := client.Dial(ctx, env.SprutAddr,
+			client.WithKeys(keys),
+			client.WithInsecureSkipVerify(),
+			client.WithOnAck(func(r client.SendResult) {
+				select {
+				case acks <- r:
+				default:
+				}
+			}),
+		)
+		require.NoError(t, err)
+		return c
+	}
+
+	receive := func(c *client.Client) *message.Message {
+		recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer recvCancel()
+		msg, err := c.Receive(recvCtx)
+		require.NoError(t, err)
+		return msg
+	}
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	carolKeys, err := identity.Generate()
+	require.NoError(t, err)
+	daveKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	aliceAcks := make(chan client.SendResult, 10)
+	daveAcks := make(chan client.SendResult, 10)
+
+	alice := dial(aliceKeys, aliceAcks)
+	defer alice.Close()
+	bob := dial(bobKeys, make(chan client.SendResult, 10))
+	defer bob.Close()
+	carol := dial(carolKeys, make(chan client.SendResult, 10))
+	defer carol.Close()
+	dave := dial(daveKeys, daveAcks)
+	defer dave.Close()
+
+	groupID, err := client.NewGroupID()
+	require.NoError(t, err)
+
+	require.NoError(t, alice.ChangeGroup(ctx, "create-1", client.GroupChange{
+		GroupID: groupID,
+		Op:      client.GroupCreate,
+		Members: []string{bobKeys.PublicKeyHex(), carolKeys.PublicKeyHex()},
+	}))
+	ack := waitAck(t, aliceAcks, 10*time.Second)
+	require.Equal(t, "create-1", ack.MsgID)
+	require.NoError(t, ack.Err())
+
+	// Участники получают подписанное изменение состава
+	for _, c := range []*client.Client{bob, carol} {
+		msg := receive(c)
+		require.Equal(t, message.Kind_KIND_GROUP_CONTROL, msg.Kind)
+		require.Equal(t, groupID, msg.GroupId)
+	}
+
+	require.NoError(t, alice.Send(ctx, client.OutgoingMessage{
+		GroupID: groupID,
+		MsgID:   "group-msg-1",
+		Payload: []byte("hello group"),
+	}))
+	ack = waitAck(t, aliceAcks, 10*time.Second)
+	require.Equal(t, "group-msg-1", ack.MsgID)
+	require.NoError(t, ack.Err())
+
+	for _, c := range []*client.Client{bob, carol} {
+		msg := receive(c)
+		require.Equal(t, message.Kind_KIND_MESSAGE, msg.Kind)
+		require.Equal(t, groupID, msg.GroupId)
+		require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+		require.Equal(t, []byte("hello group"), msg.Payload)
+	}
+
+	// Не участник не может писать в группу
+	require.NoError(t, dave.Send(ctx, client.OutgoingMessage{
+		GroupID: groupID,
+		MsgID:   "dave-1",
+		Payload: []byte("intruder"),
+	}))
+	ack = waitAck(t, daveAcks, 10*time.Second)
+	require.Equal(t, "dave-1", ack.MsgID)
+	require.ErrorIs(t, ack.Err(), client.ErrRejected)
+}
+
+func TestTokenAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	issuer, err := identity.Generate()
+	require.NoError(t, err)
+
+	env, err := testsprut.Start(ctx, testsprut.WithAuth(config.AuthConfig{
+		Methods: []string{config.AuthMethodEd25519, config.AuthMethodJWT},
+		JWT: config.JWTAuthConfig{
+			PublicKeys: []string{issuer.PublicKeyHex()},
+			Audience:   "sprut",
+		},
+	}))
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	botKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	token := signJWT(t, issuer, map[string]any{
+		"sub": botKeys.PublicKeyHex(),
+		"aud": "sprut",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	bot, err := client.Dial(ctx, env.SprutAddr,
+		client.WithAuthToken(token),
+		client.WithInsecureSkipVerify(),
+	)
+	require.NoError(t, err)
+	defer bot.Close()
+
+	alice.SendMessage(botKeys.PublicKeyHex(), "msg-1", []byte("hello bot"))
+
+	recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer recvCancel()
+	msg, err := bot.Receive(recvCtx)
+	require.NoError(t, err)
+	require.Equal(t, "msg-1", msg.Id)
+	require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+
+	// Токен чужого издателя отклоняется
+	stranger, err := identity.Generate()
+	require.NoError(t, err)
+	_, err = client.Dial(ctx, env.SprutAddr,
+		client.WithAuthToken(signJWT(t, stranger, map[string]any{
+			"sub": botKeys.PublicKeyHex(),
+			"aud": "sprut",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})),
+		client.WithInsecureSkipVerify(),
+	)
+	require.ErrorIs(t, err, client.ErrAuthFailed)
+}
+
+func TestBlockList(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx,
+		testsprut.WithJetStream(),
+		testsprut.WithPolicy(config.PolicyConfig{Blocklists: true}),
+	)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+	carolKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+	carol, err := env.NewClient(ctx, carolKeys)
+	require.NoError(t, err)
+	defer carol.Close()
+
+	bob, err := client.Dial(ctx, env.SprutAddr,
+		client.WithKeys(bobKeys),
+		client.WithInsecureSkipVerify(),
+	)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	require.NoError(t, bob.SetBlockList(ctx, []string{aliceKeys.PublicKeyHex()}))
+	// Список сохраняется сервером асинхронно относительно отправителей
+	time.Sleep(500 * time.Millisecond)
+
+	alice.SendMessage(bobKeys.PublicKeyHex(), "alice-1", []byte("spam"))
+	ack := waitAck(t, alice.Acks(), 10*time.Second)
+	require.Equal(t, "alice-1", ack.MsgID)
+	require.Equal(t, client.AckForbidden, ack.Status)
+	require.ErrorIs(t, ack.Err(), client.ErrForbidden)
+
+	carol.SendMessage(bobKeys.PublicKeyHex(), "carol-1", []byte("hello"))
+	ack = waitAck(t, carol.Acks(), 10*time.Second)
+	require.NoError(t, ack.Err())
+
+	recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer recvCancel()
+	msg, err := bob.Receive(recvCtx)
+	require.NoError(t, err)
+	require.Equal(t, "carol-1", msg.Id)
+
+	// После снятия блокировки сообщения Alice доставляются
+	require.NoError(t, bob.SetBlockList(ctx, nil))
+	time.Sleep(500 * time.Millisecond)
+
+	alice.SendMessage(bobKeys.PublicKeyHex(), "alice-2", []byte("sorry"))
+	ack = waitAck(t, alice.Acks(), 10*time.Second)
+	require.Equal(t, "alice-2", ack.MsgID)
+	require.NoError(t, ack.Err())
+}
+
+func TestGracefulDrain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	const drainTimeout = 20 * time.Second
+	env, err := testsprut.Start(ctx, testsprut.WithDrain(drainTimeout, "sprut-2.example.com:8443"))
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	alice.SendMessage(bobKeys.PublicKeyHex(), "before-drain", []byte("hello"))
+	msg := waitMsg(t, bob.Recv(), 10*time.Second)
+	require.Equal(t, "before-drain", msg.Id)
+
+	// Клиенты получают GoAway и отключаются сами, не дожидаясь drain timeout
+	start := time.Now()
+	require.NoError(t, env.Shutdown(drainTimeout+5*time.Second))
+	require.Less(t, time.Since(start), drainTimeout)
+
+	for _, c := range []*testsprut.Client{alice, bob} {
+		err := waitErr(t, c.Errors(), 5*time.Second)
+		require.ErrorIs(t, err, client.ErrGoAway)
+
+		var goAway *client.GoAwayError
+		require.ErrorAs(t, err, &goAway)
+		require.Equal(t, "sprut-2.example.com:8443", goAway.Addr)
+	}
+}
+
+// TestRateLimitThrottle проверяет, что в режиме throttle клиент, превысивший
+// rate limit, не отключается, а его сообщения доставляются с задержкой.
+func TestRateLimitThrottle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx,
+		testsprut.WithRateLimit(5, 2),
+		testsprut.WithRateLimitMode(config.RateLimitModeThrottle),
+	)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	alice, err := env.NewClient(ctx, aliceKeys)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := env.NewClient(ctx, bobKeys, client.WithoutDeliveryReceipts())
+	require.NoError(t, err)
+	defer bob.Close()
+
+	const total = 10
+	start := time.Now()
+	for i := range total {
+		alice.SendMessage(bobKeys.PublicKeyHex(), fmt.Sprintf("msg-%d", i), []byte("burst"))
+	}
+	for i := range total {
+		msg := waitMsg(t, bob.Recv(), 10*time.Second)
+		require.Equal(t, fmt.Sprintf("msg-%d", i), msg.Id)
+	}
+	// 2 сообщения в burst, остальные 8 — по 5 в секунду
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	select {
+	case err := <-alice.Errors():
+		t.Fatalf("throttled client got error: %v", err)
+	default:
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx, testsprut.WithIdleTimeout(500*time.Millisecond))
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	dial := func(opts ...client.ConnectOption) *client.Client {
+		keys, err := identity.Generate()
+		require.NoError(t, err)
+		c, err := client.Dial(ctx, env.SprutAddr,
+			append([]client.ConnectOption{client.WithKeys(keys), client.WithInsecureSkipVerify()}, opts...)...,
+		)
+		require.NoError(t, err)
+		return c
+	}
+
+	// Молчащий клиент без keepalive сервер отключает
+	silent := dial(client.WithoutKeepalive())
+	defer silent.Close()
+	// Клиент с keepalive остаётся подключённым
+	alive := dial(client.WithKeepalive(100*time.Millisecond, 2*time.Second))
+	defer alive.Close()
+
+	select {
+	case <-silent.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not close idle connection")
+	}
+
+	select {
+	case <-alive.Done():
+		t.Fatalf("client with keepalive disconnected: %v", alive.Err())
+	case <-time.After(time.Second):
+	}
+	require.Equal(t, client.StateConnected, alive.State())
+}
+
+func TestKeepaliveTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	proxy := startFreezeProxy(t, env.SprutAddr)
+
+	keys, err := identity.Generate()
+	require.NoError(t, err)
+	c, err := client.Dial(ctx, proxy.addr,
+		client.WithKeys(keys),
+		client.WithInsecureSkipVerify(),
+		client.WithKeepalive(100*time.Millisecond, 500*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Pong приходит вовремя — соединение живо
+	select {
+	case <-c.Done():
+		t.Fatalf("client disconnected before freeze: %v", c.Err())
+	case <-time.After(time.Second):
+	}
+
+	// Полуоткрытое соединение: TCP не рвётся, но кадры не доходят
+	proxy.frozen.Store(true)
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not detect dead connection")
+	}
+	require.ErrorIs(t, c.Err(), client.ErrKeepaliveTimeout)
+}
+
+func TestWebSocket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx, testsprut.WithWebSocket())
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	// Alice подключается через WebSocket, Bob — через TCP
+	acks := make(chan client.SendResult, 10)
+	alice, err := client.Dial(ctx, env.WebSocketURL,
+		client.WithKeys(aliceKeys),
+		client.WithInsecureSkipVerify(),
+		client.WithOnAck(func(r client.SendResult) { acks <- r }),
+	)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	require.NoError(t, alice.Send(ctx, client.OutgoingMessage{
+		To:      bobKeys.PublicKeyHex(),
+		MsgID:   "ws-1",
+		Payload: []byte("from websocket"),
+	}))
+	msg := waitMsg(t, bob.Recv(), 10*time.Second)
+	require.Equal(t, "ws-1", msg.Id)
+	require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+
+	select {
+	case ack := <-acks:
+		require.Equal(t, "ws-1", ack.MsgID)
+		require.Equal(t, client.AckAccepted, ack.Status)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for ack over websocket")
+	}
+
+	bob.SendMessage(aliceKeys.PublicKeyHex(), "tcp-1", []byte("from tcp"))
+	recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer recvCancel()
+	reply, err := alice.Receive(recvCtx)
+	require.NoError(t, err)
+	require.Equal(t, "tcp-1", reply.Id)
+	require.Equal(t, bobKeys.PublicKeyHex(), reply.From)
+}
+
+func TestQUIC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env, err := testsprut.Start(ctx, testsprut.WithQUIC())
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	// Alice подключается через QUIC, Bob — через TCP
+	acks := make(chan client.SendResult, 10)
+	alice, err := client.Dial(ctx, env.QUICAddr,
+		client.WithKeys(aliceKeys),
+		client.WithInsecureSkipVerify(),
+		client.WithOnAck(func(r client.SendResult) { acks <- r }),
+	)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	// Каждое сообщение идёт в своём потоке: порядок доставки не гарантирован
+	const count = 5
+	for i := range count {
+		require.NoError(t, alice.Send(ctx, client.OutgoingMessage{
+			To:      bobKeys.PublicKeyHex(),
+			MsgID:   fmt.Sprintf("quic-%d", i),
+			Payload: []byte("from quic"),
+		}))
+	}
+	received := make(map[string]bool)
+	for range count {
+		msg := waitMsg(t, bob.Recv(), 10*time.Second)
+		require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+		received[msg.Id] = true
+	}
+	require.Len(t, received, count)
+
+	for range count {
+		select {
+		case ack := <-acks:
+			require.Equal(t, client.AckAccepted, ack.Status)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for ack over quic")
+		}
+	}
+
+	bob.SendMessage(aliceKeys.PublicKeyHex(), "tcp-1", []byte("from tcp"))
+	recvCtx, recvCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer recvCancel()
+	reply, err := alice.Receive(recvCtx)
+	require.NoError(t, err)
+	require.Equal(t, "tcp-1", reply.Id)
+	require.Equal(t, bobKeys.PublicKeyHex(), reply.From)
+}
+
+func TestUnixListener(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Общий лимит разрывает соединение на втором сообщении подряд;
+	// у внутреннего listener для ботов лимит выше
+	socket := filepath.Join(t.TempDir(), "sprut.sock")
+	env, err := testsprut.Start(ctx,
+		testsprut.WithRateLimit(1, 1),
+		testsprut.WithListener(config.ListenerConfig{
+			Name:    "bots",
+			Network: config.NetworkUnix,
+			Addr:    socket,
+			Limits:  config.ListenerLimits{RateLimitPerSec: 1000, RateLimitBurst: 100},
+		}),
+	)
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	botKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	bot, err := client.Dial(ctx, "unix://"+socket,
+		client.WithKeys(botKeys),
+		client.WithInsecureSkipVerify(),
+	)
+	require.NoError(t, err)
+	defer bot.Close()
+	bob, err := env.NewClient(ctx, bobKeys, client.WithoutDeliveryReceipts())
+	require.NoError(t, err)
+	defer bob.Close()
+
+	const total = 10
+	for i := range total {
+		require.NoError(t, bot.Send(ctx, client.OutgoingMessage{
+			To:      bobKeys.PublicKeyHex(),
+			MsgID:   fmt.Sprintf("bot-%d", i),
+			Payload: []byte("from bot"),
+		}))
+	}
+	for i := range total {
+		msg := waitMsg(t, bob.Recv(), 10*time.Second)
+		require.Equal(t, fmt.Sprintf("bot-%d", i), msg.Id)
+		require.Equal(t, botKeys.PublicKeyHex(), msg.From)
+	}
+}
+
+func TestProxyProtocol(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Адрес listener за балансировщиком
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lbAddr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	env, err := testsprut.Start(ctx, testsprut.WithListener(config.ListenerConfig{
+		Name:          "lb",
+		Addr:          lbAddr,
+		ProxyProtocol: true,
+	}))
+	require.NoError(t, err)
+	defer env.Close(ctx)
+
+	aliceKeys, err := identity.Generate()
+	require.NoError(t, err)
+	bobKeys, err := identity.Generate()
+	require.NoError(t, err)
+
+	// Без заголовка PROXY соединение отклоняется
+	_, err = client.Dial(ctx, lbAddr,
+		client.WithKeys(aliceKeys),
+		client.WithInsecureSkipVerify(),
+		client.WithDialTimeout(5*time.Second),
+	)
+	require.Error(t, err)
+
+	alice, err := client.Dial(ctx, startProxyProtocolLB(t, lbAddr),
+		client.WithKeys(aliceKeys),
+		client.WithInsecureSkipVerify(),
+	)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := env.NewClient(ctx, bobKeys)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	require.NoError(t, alice.Send(ctx, client.OutgoingMessage{
+		To:      bobKeys.PublicKeyHex(),
+		MsgID:   "lb-1",
+		Payload: []byte("via balancer"),
+	}))
+	msg := waitMsg(t, bob.Recv(), 10*time.Second)
+	require.Equal(t, "lb-1", msg.Id)
+	require.Equal(t, aliceKeys.PublicKeyHex(), msg.From)
+}
+
+// startProxyProtocolLB имитирует TCP балансировщик: пересылает соединения
+// на target, предваряя их заголовком PROXY protocol v1 с адресом клиента.
+func startProxyProtocolLB(t *testing.T, target string) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	go func() {
+		for {
+			src, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			dst, err := net.Dial("tcp", target)
+			if err != nil {
+				_ = src.Close()
+				continue
+			}
+			t.Cleanup(func() {
+				_ = src.Close()
+				_ = dst.Close()
+			})
+
+			from := src.RemoteAddr().(*net.TCPAddr)
+			to := src.LocalAddr().(*net.TCPAddr)
+			header := fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", from.IP, to.IP, from.Port, to.Port)
+			if _, err := dst.Write([]byte(header)); err != nil {
+				_ = src.Close()
+				_ = dst.Close()
+				continue
+			}
+			go func() { _, _ = io.Copy(dst, src) }()
+			go func() { _, _ = io.Copy(src, dst) }()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+// freezeProxy пересылает одно TCP соединение; после frozen данные
+// молча отбрасываются в обе стороны, имитируя полуоткрытое соединение.
+type freezeProxy struct {
+	addr   string
+	frozen atomic.Bool
+}
+
+func startFreezeProxy(t *testing.T, target string) *freezeProxy {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	p := &freezeProxy{addr: lis.Addr().String()}
+	go func() {
+		src, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		dst, err := net.Dial("tcp", target)
+		if err != nil {
+			_ = src.Close()
+			return
+		}
+		t.Cleanup(func() {
+			_ = src.Close()
+			_ = dst.Close()
+		})
+		go p.pipe(dst, src)
+		go p.pipe(src, dst)
+	}()
+	return p
+}
+
+func (p *freezeProxy) pipe(dst, src net.Conn) {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			return
+		}
+		if p.frozen.Load() {
+			continue
+		}
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+// signJWT выпускает JWT с alg EdDSA.
+func signJWT(t *testing.T, issuer *identity.KeyPair, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(issuer.Sign([]byte(signed)))
+}
+
+func waitPresence(t *testing.T, ch <-chan client.Presence, timeout time.Duration) client.Presence {
+	t.Helper()
+	select {
+	case p := <-ch:
+		return p
+	case <-time.After(timeout):
+		t.Fatal("timeout waiting for presence")
+		return client.Presence{}
+	}
+}
+
+func waitState(t *testing.T, ch <-chan client.ConnState, timeout time.Duration) client.ConnState {
+	t.Helper()
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(timeout):
+		t.Fatal("timeout waiting for state change")
+		return client.StateClosed
+	}
+}
+
+func waitErr(t *testing.T, ch <-chan error, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(timeout):
+		t.Fatal("timeout waiting for error")
+		return nil
+	}
+}
+
+func waitAck(t *testing.T, ch <-chan client.SendResult, timeout time.Duration) client.SendResult {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(timeout):
+		t.Fatal("timeout waiting for ack")
+		return client.SendResult{}
+	}
+}
+
 func waitMsg(t *testing.T, ch <-chan *message.Message, timeout time.Duration) *message.Message {
 	t.Helper()
 	select {